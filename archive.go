@@ -0,0 +1,73 @@
+package zippy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// tarExtensions maps the file name extensions of tar archives to their
+// compression.
+var tarExtensions = []struct {
+	ext         string
+	compression TarCompression
+}{
+	{".tar", TarUncompressed},
+	{".tar.gz", TarGzip},
+	{".tgz", TarGzip},
+	{".tar.zst", TarZstd},
+	{".tzst", TarZstd},
+	{".tar.xz", TarXz},
+	{".txz", TarXz},
+}
+
+// tarCompressionFor returns the compression of the tar archive named by path,
+// and whether path has a tar archive extension.
+func tarCompressionFor(path string) (TarCompression, bool) {
+	lower := strings.ToLower(path)
+	for _, e := range tarExtensions {
+		if strings.HasSuffix(lower, e.ext) {
+			return e.compression, true
+		}
+	}
+
+	return 0, false
+}
+
+// NewArchiver returns an archiver for the format named by the file extension
+// of path: a [Zippy] for ".zip", or a [Tarry] for ".tar", ".tar.gz", ".tgz",
+// ".tar.zst", ".tzst", ".tar.xz" and ".txz". Other extensions fail with
+// [ErrUnsupportedFormat].
+func NewArchiver(path string) (ZippyInterface, error) {
+	if path == "" {
+		return nil, ErrEmptyPath
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return NewZippy(path), nil
+	}
+
+	if compression, ok := tarCompressionFor(path); ok {
+		return NewTarry(path, compression), nil
+	}
+
+	return nil, fmt.Errorf("failed to open '%s': %w", path, ErrUnsupportedFormat)
+}
+
+// NewExtractor returns an extractor for the format named by the file extension
+// of path, an [Unzippy] or an [Untarry], like [NewArchiver] does.
+func NewExtractor(path string, options *UnzippyOptions) (UnzippyInterface, error) {
+	if path == "" {
+		return nil, ErrEmptyPath
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return NewUnzippy(path, options)
+	}
+
+	if compression, ok := tarCompressionFor(path); ok {
+		return NewUntarry(path, compression, options)
+	}
+
+	return nil, fmt.Errorf("failed to open '%s': %w", path, ErrUnsupportedFormat)
+}
@@ -0,0 +1,61 @@
+package zippy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [NewArchiver] and [NewExtractor] functions.
+func Test_NewArchiver_NewExtractor(t *testing.T) {
+	tests := []struct {
+		path        string
+		tar         bool
+		compression TarCompression
+	}{
+		{path: "archive.zip"},
+		{path: "ARCHIVE.ZIP"},
+		{path: "archive.tar", tar: true, compression: TarUncompressed},
+		{path: "archive.tar.gz", tar: true, compression: TarGzip},
+		{path: "archive.tgz", tar: true, compression: TarGzip},
+		{path: "archive.tar.zst", tar: true, compression: TarZstd},
+		{path: "archive.tzst", tar: true, compression: TarZstd},
+		{path: "archive.tar.xz", tar: true, compression: TarXz},
+		{path: "archive.TXZ", tar: true, compression: TarXz},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			archiver, err := NewArchiver(tt.path)
+			assert.NoError(t, err)
+
+			extractor, err := NewExtractor(tt.path, nil)
+			assert.NoError(t, err)
+
+			if !tt.tar {
+				assert.IsType(t, &Zippy{}, archiver)
+				assert.IsType(t, &Unzippy{}, extractor)
+				return
+			}
+
+			assert.Equal(t, tt.compression, archiver.(*Tarry).Compression)
+			assert.Equal(t, tt.compression, extractor.(*Untarry).Compression)
+		})
+	}
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		_, err := NewArchiver("archive.rar")
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+		_, err = NewExtractor("archive.gz", nil)
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+
+	t.Run("empty path", func(t *testing.T) {
+		_, err := NewArchiver("")
+		assert.ErrorIs(t, err, ErrEmptyPath)
+
+		_, err = NewExtractor("", nil)
+		assert.ErrorIs(t, err, ErrEmptyPath)
+	})
+}
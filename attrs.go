@@ -0,0 +1,233 @@
+package zippy
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// unixExtraID is the Info-ZIP Unix UID/GID extra field header ID.
+const unixExtraID = 0x7875
+
+// Flags of the extended timestamp extra field.
+const (
+	extTimeMod    = 1 << iota // Modification time is present
+	extTimeAccess             // Access time is present
+)
+
+// unixAttrs holds the file attributes stored in the Info-ZIP extra fields.
+type unixAttrs struct {
+	Atime    time.Time // Access time. Zero if unknown.
+	UID      int       // Owner user ID.
+	GID      int       // Owner group ID.
+	HasOwner bool      // Whether UID and GID are known.
+}
+
+// setUnixExtra adds the extended timestamp and Unix UID/GID extra fields to a
+// header created from a file on disk.
+//
+// The modified time is moved into the MS-DOS fields so that
+// [zip.Writer.CreateHeader] does not add a second extended timestamp.
+func setUnixExtra(header *zip.FileHeader, attrs unixAttrs) {
+	flags := byte(extTimeMod)
+	if !attrs.Atime.IsZero() {
+		flags |= extTimeAccess
+	}
+
+	data := []byte{flags}
+	data = binary.LittleEndian.AppendUint32(data, uint32(header.Modified.Unix()))
+	if !attrs.Atime.IsZero() {
+		data = binary.LittleEndian.AppendUint32(data, uint32(attrs.Atime.Unix()))
+	}
+
+	header.Extra = stripExtra(header.Extra, extTimeExtraID, unixExtraID)
+	header.Extra = appendExtra(header.Extra, extTimeExtraID, data)
+
+	if attrs.HasOwner {
+		data = []byte{1, 4} // Version 1, 4 byte UID
+		data = binary.LittleEndian.AppendUint32(data, uint32(attrs.UID))
+		data = append(data, 4) // 4 byte GID
+		data = binary.LittleEndian.AppendUint32(data, uint32(attrs.GID))
+		header.Extra = appendExtra(header.Extra, unixExtraID, data)
+	}
+
+	header.ModifiedDate, header.ModifiedTime = timeToMsDosTime(header.Modified)
+	header.Modified = time.Time{}
+}
+
+// setCentralExtra trims the extended timestamp to the modification time, as
+// Info-ZIP stores it in the central directory. It must be called after the
+// local header is written, since [zip.Writer] keeps the header to write the
+// central directory on close.
+func setCentralExtra(header *zip.FileHeader) {
+	data, ok := findExtra(header.Extra, extTimeExtraID)
+	if !ok || len(data) <= 5 || data[0]&extTimeMod == 0 {
+		return
+	}
+
+	var extra []byte
+	for _, field := range parseExtra(header.Extra) {
+		if field.ID == extTimeExtraID {
+			field.Data = data[:5]
+		}
+
+		extra = appendExtra(extra, field.ID, field.Data)
+	}
+
+	header.Extra = extra
+}
+
+// readUnixExtra reads the access time and ownership from the extended
+// timestamp and Unix UID/GID extra fields.
+func readUnixExtra(extra []byte) unixAttrs {
+	var attrs unixAttrs
+
+	if data, ok := findExtra(extra, extTimeExtraID); ok && len(data) >= 1 {
+		flags := data[0]
+		data = data[1:]
+
+		if flags&extTimeMod != 0 && len(data) >= 4 {
+			data = data[4:]
+		}
+
+		if flags&extTimeAccess != 0 && len(data) >= 4 {
+			attrs.Atime = time.Unix(int64(int32(binary.LittleEndian.Uint32(data))), 0)
+		}
+	}
+
+	if data, ok := findExtra(extra, unixExtraID); ok && len(data) >= 2 && data[0] == 1 {
+		uid, data, uidOK := readUnixID(data[1:])
+		gid, _, gidOK := readUnixID(data)
+
+		if uidOK && gidOK {
+			attrs.UID, attrs.GID, attrs.HasOwner = uid, gid, true
+		}
+	}
+
+	return attrs
+}
+
+// readUnixID reads a size-prefixed little-endian ID from the Unix UID/GID
+// extra field and returns the remaining data.
+func readUnixID(data []byte) (int, []byte, bool) {
+	if len(data) < 1 {
+		return 0, nil, false
+	}
+
+	size := int(data[0])
+	if size > 8 || len(data) < 1+size {
+		return 0, nil, false
+	}
+
+	var id uint64
+	for i := size - 1; i >= 0; i-- {
+		id = id<<8 | uint64(data[1+i])
+	}
+
+	return int(id), data[1+size:], true
+}
+
+// localExtras returns the local header extra fields of the given files, which
+// hold the access time that the central directory leaves out.
+func localExtras(archive *zipArchive, files []*zip.File) (map[*zip.File][]byte, error) {
+	eocd, err := readEOCD(archive.r, archive.size)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := readCentralDirectory(archive.r, eocd)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[string]int64, len(headers))
+	for _, h := range headers {
+		offsets[h.Name] = h.Offset
+	}
+
+	extras := make(map[*zip.File][]byte, len(files))
+	for _, file := range files {
+		offset, ok := offsets[file.Name]
+		if !ok {
+			continue
+		}
+
+		local, err := readLocalHeader(archive.r, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		extras[file] = local.Extra
+	}
+
+	return extras, nil
+}
+
+// restoreMode returns the permission bits to restore for an entry, including
+// the setuid, setgid and sticky bits only when asked to.
+func (u *Unzippy) restoreMode(file *zip.File) fs.FileMode {
+	mode := file.Mode()
+	perm := mode & fs.ModePerm
+
+	if u.Options.SpecialBits {
+		perm |= mode & (fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+	}
+
+	return perm
+}
+
+// restoreAttrs restores the ownership, permissions and times of an extracted
+// file or directory, as requested by the options.
+func (u *Unzippy) restoreAttrs(file *zip.File, path string, localExtra []byte) error {
+	attrs := readUnixExtra(file.Extra)
+	if local := readUnixExtra(localExtra); !local.Atime.IsZero() {
+		attrs.Atime = local.Atime
+	}
+
+	// Ownership is restored first, as changing it clears the setuid and
+	// setgid bits
+	if u.Options.Ownership && attrs.HasOwner && os.Geteuid() == 0 {
+		if err := os.Lchown(path, attrs.UID, attrs.GID); err != nil {
+			return err
+		}
+	}
+
+	if u.Options.Permissions {
+		if err := os.Chmod(path, u.restoreMode(file)); err != nil {
+			return err
+		}
+	}
+
+	atime := file.Modified
+	if u.Options.AccessTimes && !attrs.Atime.IsZero() {
+		atime = attrs.Atime
+	}
+
+	return os.Chtimes(path, atime, file.Modified)
+}
+
+// restoreDirAttrs restores the attributes of extracted directories once their
+// contents have been written, deepest directories first.
+func (u *Unzippy) restoreDirAttrs(dirs map[string]*zip.File, extras map[*zip.File][]byte) error {
+	paths := make([]string, 0, len(dirs))
+	for path := range dirs {
+		paths = append(paths, path)
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return strings.Count(paths[i], string(os.PathSeparator)) > strings.Count(paths[j], string(os.PathSeparator))
+	})
+
+	for _, path := range paths {
+		file := dirs[path]
+		if err := u.restoreAttrs(file, path, extras[file]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package zippy
+
+import (
+	"archive/zip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [setUnixExtra] and [readUnixExtra] functions.
+func Test_unixExtra(t *testing.T) {
+	modified := time.Date(2024, 5, 6, 7, 8, 10, 0, time.Local)
+	atime := time.Date(2024, 6, 7, 8, 9, 10, 0, time.Local)
+
+	t.Run("round trip", func(t *testing.T) {
+		header := &zip.FileHeader{Name: "file.txt", Modified: modified}
+		setUnixExtra(header, unixAttrs{Atime: atime, UID: 1000, GID: 100, HasOwner: true})
+
+		assert.True(t, header.Modified.IsZero())
+		assert.Equal(t, modified, header.ModTime().Local())
+
+		attrs := readUnixExtra(header.Extra)
+		assert.True(t, atime.Equal(attrs.Atime))
+		assert.True(t, attrs.HasOwner)
+		assert.Equal(t, 1000, attrs.UID)
+		assert.Equal(t, 100, attrs.GID)
+	})
+
+	t.Run("without access time or owner", func(t *testing.T) {
+		header := &zip.FileHeader{Name: "file.txt", Modified: modified}
+		setUnixExtra(header, unixAttrs{})
+
+		data, ok := findExtra(header.Extra, extTimeExtraID)
+		assert.True(t, ok)
+		assert.Len(t, data, 5)
+
+		_, ok = findExtra(header.Extra, unixExtraID)
+		assert.False(t, ok)
+
+		attrs := readUnixExtra(header.Extra)
+		assert.True(t, attrs.Atime.IsZero())
+		assert.False(t, attrs.HasOwner)
+	})
+
+	t.Run("replaces existing fields", func(t *testing.T) {
+		header := &zip.FileHeader{Name: "file.txt", Modified: modified}
+		header.Extra = appendExtra(nil, extTimeExtraID, []byte{1, 0, 0, 0, 0})
+		setUnixExtra(header, unixAttrs{})
+
+		assert.Len(t, parseExtra(header.Extra), 1)
+	})
+
+	t.Run("variable size IDs", func(t *testing.T) {
+		extra := appendExtra(nil, unixExtraID, []byte{1, 2, 0xe8, 0x03, 1, 0x64})
+
+		attrs := readUnixExtra(extra)
+		assert.True(t, attrs.HasOwner)
+		assert.Equal(t, 1000, attrs.UID)
+		assert.Equal(t, 100, attrs.GID)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		extra := appendExtra(nil, unixExtraID, []byte{1, 4, 0xe8, 0x03})
+		extra = appendExtra(extra, extTimeExtraID, []byte{3, 0, 0, 0, 0})
+
+		attrs := readUnixExtra(extra)
+		assert.False(t, attrs.HasOwner)
+		assert.True(t, attrs.Atime.IsZero())
+	})
+}
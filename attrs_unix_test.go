@@ -0,0 +1,126 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package zippy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+// extractAttrsTestFile archives a directory holding a single file and
+// extracts it with the given options, returning the extracted directory and
+// file paths.
+func extractAttrsTestFile(t *testing.T, setup func(dir, file string), options *UnzippyOptions) (string, string) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	srcDir := filepath.Join(tempDir, "src")
+	srcFile := filepath.Join(srcDir, "file.txt")
+	assert.NoError(t, os.MkdirAll(srcDir, os.ModePerm))
+	assert.NoError(t, os.WriteFile(srcFile, []byte("content"), 0644))
+
+	setup(srcDir, srcFile)
+
+	zipFilePath := filepath.Join(tempDir, testZipFileName)
+	assert.NoError(t, NewZippy(zipFilePath).Add(srcDir))
+
+	u, err := NewUnzippy(zipFilePath, options)
+	assert.NoError(t, err)
+
+	dest := filepath.Join(tempDir, "output")
+	_, err = u.ExtractTo(dest)
+	assert.NoError(t, err)
+
+	return filepath.Join(dest, toZipPath(srcDir)), filepath.Join(dest, toZipPath(srcFile))
+}
+
+// Tests for [UnzippyOptions] attribute restoring.
+func Test_Unzippy_ExtractTo_Attrs(t *testing.T) {
+	modified := time.Date(2020, 1, 2, 3, 4, 6, 0, time.Local)
+	atime := time.Date(2021, 2, 3, 4, 5, 6, 0, time.Local)
+
+	t.Run("permissions ignore umask", func(t *testing.T) {
+		oldMask := unix.Umask(0077)
+		defer unix.Umask(oldMask)
+
+		chmod := func(dir, file string) {
+			assert.NoError(t, os.Chmod(file, 0754))
+		}
+
+		_, file := extractAttrsTestFile(t, chmod, nil)
+		info, err := os.Stat(file)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+		_, file = extractAttrsTestFile(t, chmod, &UnzippyOptions{Permissions: true})
+		info, err = os.Stat(file)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0754), info.Mode().Perm())
+	})
+
+	t.Run("special bits only if asked", func(t *testing.T) {
+		setuid := func(dir, file string) {
+			assert.NoError(t, os.Chmod(file, 0755|os.ModeSetuid))
+		}
+
+		_, file := extractAttrsTestFile(t, setuid, &UnzippyOptions{Permissions: true})
+		info, err := os.Stat(file)
+		assert.NoError(t, err)
+		assert.Zero(t, info.Mode()&os.ModeSetuid)
+
+		_, file = extractAttrsTestFile(t, setuid, &UnzippyOptions{Permissions: true, SpecialBits: true})
+		info, err = os.Stat(file)
+		assert.NoError(t, err)
+		assert.NotZero(t, info.Mode()&os.ModeSetuid)
+	})
+
+	t.Run("access times", func(t *testing.T) {
+		chtimes := func(dir, file string) {
+			assert.NoError(t, os.Chtimes(file, atime, modified))
+		}
+
+		_, file := extractAttrsTestFile(t, chtimes, &UnzippyOptions{AccessTimes: true})
+
+		var stat unix.Stat_t
+		assert.NoError(t, unix.Stat(file, &stat))
+		assert.Equal(t, atime.Unix(), stat.Atim.Sec)
+		assert.Equal(t, modified.Unix(), stat.Mtim.Sec)
+	})
+
+	t.Run("directory times", func(t *testing.T) {
+		chtimes := func(dir, file string) {
+			assert.NoError(t, os.Chtimes(dir, modified, modified))
+		}
+
+		dir, _ := extractAttrsTestFile(t, chtimes, nil)
+		info, err := os.Stat(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, modified.Unix(), info.ModTime().Unix())
+	})
+
+	t.Run("ownership", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			t.Skip("Skipping ownership test when not running as root")
+		}
+
+		chown := func(dir, file string) {
+			assert.NoError(t, os.Chown(file, 1234, 5678))
+		}
+
+		_, file := extractAttrsTestFile(t, chown, nil)
+		var stat unix.Stat_t
+		assert.NoError(t, unix.Stat(file, &stat))
+		assert.Equal(t, uint32(0), stat.Uid)
+
+		_, file = extractAttrsTestFile(t, chown, &UnzippyOptions{Ownership: true})
+		assert.NoError(t, unix.Stat(file, &stat))
+		assert.Equal(t, uint32(1234), stat.Uid)
+		assert.Equal(t, uint32(5678), stat.Gid)
+	})
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/engmtcdrm/go-zippy"
+)
+
+// requireArgs fails with a usage error if fewer than min arguments are given.
+func requireArgs(args []string, min int, names string) error {
+	if len(args) < min {
+		return fmt.Errorf("%w: expected %s", errUsage, names)
+	}
+
+	return nil
+}
+
+// newZippy returns a Zippy for the archive configured by the options.
+func newZippy(path string, opts *options) *zippy.Zippy {
+	z := zippy.NewZippy(path)
+	z.Junk = opts.junk
+	z.Exclude = opts.exclude
+	z.ForceZip64 = opts.zip64
+
+	if opts.password != "" {
+		z.Encryption = &zippy.Encryption{Method: zippy.AES256, Password: opts.password}
+	}
+
+	return z
+}
+
+// writeListing prints the entries of an archive changed by a command, if JSON
+// output was asked for.
+func writeListing(path string, opts *options, stdout io.Writer) error {
+	if !opts.json {
+		return nil
+	}
+
+	listing, err := zippy.List(path)
+	if err != nil {
+		return err
+	}
+
+	return listing.WriteJSON(stdout)
+}
+
+func runAdd(opts *options, args []string, stdout io.Writer) error {
+	if err := requireArgs(args, 2, "archive and files"); err != nil {
+		return err
+	}
+
+	if err := newZippy(args[0], opts).Add(args[1:]...); err != nil {
+		return err
+	}
+
+	return writeListing(args[0], opts, stdout)
+}
+
+func runDelete(opts *options, args []string, stdout io.Writer) error {
+	if err := requireArgs(args, 2, "archive and patterns"); err != nil {
+		return err
+	}
+
+	if err := zippy.NewZippy(args[0]).Delete(args[1:]...); err != nil {
+		return err
+	}
+
+	return writeListing(args[0], opts, stdout)
+}
+
+func runUpdate(opts *options, args []string, stdout io.Writer) error {
+	if err := requireArgs(args, 2, "archive and files"); err != nil {
+		return err
+	}
+
+	if err := newZippy(args[0], opts).Update(args[1:]...); err != nil {
+		return err
+	}
+
+	return writeListing(args[0], opts, stdout)
+}
+
+func runFreshen(opts *options, args []string, stdout io.Writer) error {
+	if err := requireArgs(args, 1, "archive"); err != nil {
+		return err
+	}
+
+	files := args[1:]
+
+	// Like zip -f, the entries are freshened from the current directory
+	if len(files) == 0 {
+		entries, err := zippy.Contents(args[0])
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if !entry.FileInfo().IsDir() {
+				files = append(files, entry.Name)
+			}
+		}
+	}
+
+	if err := newZippy(args[0], opts).Freshen(files...); err != nil {
+		return err
+	}
+
+	return writeListing(args[0], opts, stdout)
+}
+
+func runCopy(opts *options, args []string, stdout io.Writer) error {
+	if err := requireArgs(args, 2, "archive and destination"); err != nil {
+		return err
+	}
+
+	if err := zippy.NewZippy(args[0]).Copy(args[1], args[2:]...); err != nil {
+		return err
+	}
+
+	return writeListing(args[1], opts, stdout)
+}
+
+func runExtract(opts *options, args []string, stdout io.Writer) error {
+	if err := requireArgs(args, 1, "archive"); err != nil {
+		return err
+	}
+
+	u, err := zippy.Open(args[0], &zippy.UnzippyOptions{
+		Junk:         opts.junk,
+		FailExisting: !opts.overwrite,
+		Password:     opts.password,
+		Exclude:      opts.exclude,
+	})
+	if err != nil {
+		return err
+	}
+
+	files, err := u.ExtractFilesTo(opts.dest, args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+
+	if opts.json {
+		return writeJSON(stdout, map[string]any{"archive": args[0], "dest": opts.dest, "extracted": names})
+	}
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(stdout, "  extracting: %s\n", name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runList(opts *options, args []string, stdout io.Writer) error {
+	if err := requireArgs(args, 1, "archive"); err != nil {
+		return err
+	}
+
+	listing, err := zippy.List(args[0])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case opts.json:
+		return listing.WriteJSON(stdout)
+	case opts.verbose:
+		return listing.WriteVerbose(stdout)
+	default:
+		return listing.WriteTable(stdout)
+	}
+}
+
+// testedEntry is the JSON result of testing an entry.
+type testedEntry struct {
+	Name     string   `json:"name"`
+	OK       bool     `json:"ok"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+func runTest(opts *options, args []string, stdout io.Writer) error {
+	if err := requireArgs(args, 1, "archive"); err != nil {
+		return err
+	}
+
+	u, err := zippy.NewUnzippy(args[0], &zippy.UnzippyOptions{Password: opts.password})
+	if err != nil {
+		return err
+	}
+
+	report, verifyErr := u.Verify(args[1:]...)
+	if report == nil {
+		return verifyErr
+	}
+
+	entries := make([]testedEntry, 0, len(report.Entries))
+	for _, entry := range report.Entries {
+		tested := testedEntry{Name: entry.Name, OK: entry.OK()}
+		for _, problem := range entry.Problems {
+			tested.Problems = append(tested.Problems, problem.Error())
+		}
+
+		entries = append(entries, tested)
+	}
+
+	if opts.json {
+		err = writeJSON(stdout, map[string]any{
+			"archive":        args[0],
+			"ok":             report.OK(),
+			"trailing_bytes": report.TrailingBytes,
+			"entries":        entries,
+		})
+	} else {
+		err = writeTestReport(stdout, args[0], report, entries)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return testError(report, verifyErr)
+}
+
+// writeTestReport prints the result of testing an archive like unzip -t.
+func writeTestReport(w io.Writer, path string, report *zippy.VerifyReport, entries []testedEntry) error {
+	for _, entry := range entries {
+		status := "OK"
+		if !entry.OK {
+			status = "FAILED"
+		}
+
+		if _, err := fmt.Fprintf(w, "    testing: %-40s %s\n", entry.Name, status); err != nil {
+			return err
+		}
+
+		for _, problem := range entry.Problems {
+			if _, err := fmt.Fprintf(w, "        %s\n", problem); err != nil {
+				return err
+			}
+		}
+	}
+
+	if report.TrailingBytes > 0 {
+		if _, err := fmt.Fprintf(w, "%d extra bytes after the end of the archive\n", report.TrailingBytes); err != nil {
+			return err
+		}
+	}
+
+	if report.OK() {
+		_, err := fmt.Fprintf(w, "No errors detected in compressed data of %s.\n", path)
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%d of %d entries failed in %s.\n", len(report.Failed()), len(report.Entries), path)
+	return err
+}
+
+// testError returns the error of a test. Password problems take precedence
+// so that they are reported with their own exit code.
+func testError(report *zippy.VerifyReport, verifyErr error) error {
+	for _, entry := range report.Failed() {
+		for _, problem := range entry.Problems {
+			if exitCode(problem) == exitPassword {
+				return fmt.Errorf("%w: %w", verifyErr, problem)
+			}
+		}
+	}
+
+	return verifyErr
+}
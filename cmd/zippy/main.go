@@ -0,0 +1,258 @@
+// Command zippy adds, updates, extracts, lists and tests zip archives, with
+// flags that mirror Info-ZIP zip and unzip.
+//
+// Usage:
+//
+//	zippy [--json] <command> [flags] archive [files...]
+//
+// The commands are:
+//
+//	add      [-j] [-fz] [-x pattern]... [-P password] archive files...
+//	delete   archive patterns...                           (alias -d)
+//	update   [-j] [-fz] [-x pattern]... archive files...   (alias -u)
+//	freshen  [-j] [-fz] [-x pattern]... archive [files...] (alias -f)
+//	copy     archive dest [patterns...]
+//	extract  [-j] [-o] [-d dir] [-x pattern]... [-P password] archive [patterns...]
+//	list     [-v] archive
+//	test     [-P password] archive [patterns...]
+//
+// The extract command also extracts tar archives, uncompressed or compressed
+// with gzip, zstd or xz, detecting the format from the archive contents.
+//
+// Flags may appear before or after the positional arguments, and "--" ends
+// the flags. With --json, results and errors are printed as JSON.
+//
+// The exit codes are:
+//
+//	0  success
+//	1  error
+//	2  invalid usage
+//	3  archive, file or entry not found
+//	4  archive failed its test or is corrupt
+//	5  password missing or incorrect
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/engmtcdrm/go-zippy"
+)
+
+// Exit codes returned by zippy.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitCorrupt  = 4
+	exitPassword = 5
+)
+
+// errUsage reports invalid command line arguments.
+var errUsage = errors.New("invalid usage")
+
+// usage is printed when the command line is invalid.
+const usage = `usage: zippy [--json] <command> [flags] archive [files...]
+
+commands:
+  add      [-j] [-fz] [-x pattern]... [-P password] archive files...
+  delete   archive patterns...                              (alias -d)
+  update   [-j] [-fz] [-x pattern]... archive files...      (alias -u)
+  freshen  [-j] [-fz] [-x pattern]... archive [files...]    (alias -f)
+  copy     archive dest [patterns...]
+  extract  [-j] [-o] [-d dir] [-x pattern]... [-P password] archive [patterns...]
+  list     [-v] archive
+  test     [-P password] archive [patterns...]
+`
+
+// aliases maps the Info-ZIP style command flags to their commands.
+var aliases = map[string]string{
+	"-d": "delete",
+	"-u": "update",
+	"-f": "freshen",
+}
+
+// command runs a subcommand with its parsed flags and positional arguments.
+type command struct {
+	flags func(flags *flag.FlagSet, opts *options)
+	run   func(opts *options, args []string, stdout io.Writer) error
+}
+
+var commands = map[string]command{
+	"add":     {flags: archiveFlags, run: runAdd},
+	"delete":  {run: runDelete},
+	"update":  {flags: archiveFlags, run: runUpdate},
+	"freshen": {flags: archiveFlags, run: runFreshen},
+	"copy":    {run: runCopy},
+	"extract": {flags: extractFlags, run: runExtract},
+	"list":    {flags: listFlags, run: runList},
+	"test":    {flags: passwordFlag, run: runTest},
+}
+
+// options holds the flags of all commands.
+type options struct {
+	json      bool
+	junk      bool
+	overwrite bool
+	verbose   bool
+	zip64     bool
+	dest      string
+	password  string
+	exclude   stringList
+}
+
+// stringList is a flag that may be repeated.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs zippy with the command line arguments and returns its exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	opts := &options{}
+
+	for len(args) > 0 && (args[0] == "--json" || args[0] == "-json") {
+		opts.json = true
+		args = args[1:]
+	}
+
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	name := args[0]
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "zippy: unknown command '%s'\n\n%s", args[0], usage)
+		return exitUsage
+	}
+
+	flags := flag.NewFlagSet("zippy "+name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.BoolVar(&opts.json, "json", opts.json, "print results as JSON")
+	if cmd.flags != nil {
+		cmd.flags(flags, opts)
+	}
+
+	positional, err := parseInterspersed(flags, args[1:])
+	if err == nil {
+		err = cmd.run(opts, positional, stdout)
+	}
+
+	if err == nil {
+		return exitOK
+	}
+
+	code := exitCode(err)
+	if opts.json {
+		writeJSON(stderr, map[string]any{"error": err.Error(), "exit_code": code})
+	} else {
+		fmt.Fprintf(stderr, "zippy: %v\n", err)
+		if code == exitUsage {
+			fmt.Fprint(stderr, "\n"+usage)
+		}
+	}
+
+	return code
+}
+
+// parseInterspersed parses flags that may be mixed with positional arguments,
+// and returns the positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+
+		rest := flags.Args()
+		consumed := len(args) - len(rest)
+
+		// Everything after "--" is positional
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+
+		if len(rest) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// exitCode returns the exit code reporting an error.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errUsage):
+		return exitUsage
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, zippy.ErrEntryNotFound):
+		return exitNotFound
+	case errors.Is(err, zippy.ErrPasswordRequired), errors.Is(err, zippy.ErrBadPassword), errors.Is(err, zippy.ErrAuthentication):
+		return exitPassword
+	case errors.Is(err, zippy.ErrVerificationFailed), errors.Is(err, zippy.ErrChecksumMismatch), errors.Is(err, zippy.ErrSizeMismatch),
+		errors.Is(err, zip.ErrFormat), errors.Is(err, zip.ErrChecksum), errors.Is(err, zip.ErrAlgorithm):
+		return exitCorrupt
+	default:
+		return exitError
+	}
+}
+
+// writeJSON writes a value as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// archiveFlags registers the flags of the commands that archive files.
+func archiveFlags(flags *flag.FlagSet, opts *options) {
+	flags.BoolVar(&opts.junk, "j", false, "junk the paths of files")
+	flags.BoolVar(&opts.zip64, "fz", false, "force ZIP64 records")
+	flags.Var(&opts.exclude, "x", "exclude files matching a pattern")
+	passwordFlag(flags, opts)
+}
+
+// extractFlags registers the flags of the extract command.
+func extractFlags(flags *flag.FlagSet, opts *options) {
+	flags.BoolVar(&opts.junk, "j", false, "junk the paths of entries")
+	flags.BoolVar(&opts.overwrite, "o", false, "overwrite existing files")
+	flags.StringVar(&opts.dest, "d", ".", "directory to extract to")
+	flags.Var(&opts.exclude, "x", "exclude entries matching a pattern")
+	passwordFlag(flags, opts)
+}
+
+// listFlags registers the flags of the list command.
+func listFlags(flags *flag.FlagSet, opts *options) {
+	flags.BoolVar(&opts.verbose, "v", false, "list entries verbosely")
+}
+
+// passwordFlag registers the password flag.
+func passwordFlag(flags *flag.FlagSet, opts *options) {
+	flags.StringVar(&opts.password, "P", "", "password of encrypted entries")
+}
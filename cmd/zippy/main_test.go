@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/engmtcdrm/go-zippy"
+	"github.com/engmtcdrm/go-zippy/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+const testZipFileName = "test.zip"
+
+// binary is the path of the zippy binary built for the tests.
+var binary string
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+// runTests builds the zippy binary and runs the tests against it.
+func runTests(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "zippy-cmd-")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	binary = filepath.Join(dir, "zippy")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	build := exec.Command("go", "build", "-o", binary, ".")
+	if out, err := build.CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build zippy: %v\n%s", err, out)
+		return 1
+	}
+
+	return m.Run()
+}
+
+// zippyCmd runs the zippy binary in dir and returns its output and exit code.
+func zippyCmd(t *testing.T, dir string, args ...string) (string, string, int) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), stderr.String(), exitErr.ExitCode()
+	}
+
+	assert.NoError(t, err)
+	return stdout.String(), stderr.String(), 0
+}
+
+// createSourceDir creates a directory of files to archive in dir.
+func createSourceDir(t *testing.T, dir string) {
+	t.Helper()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "src", "build"), os.ModePerm))
+
+	for _, name := range []string{"a.txt", "b.txt", "main.o", filepath.Join("build", "out.txt")} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "src", name), []byte("contents of "+name), 0644))
+	}
+}
+
+// entryNames returns the sorted entry names of a zip archive.
+func entryNames(t *testing.T, path string) []string {
+	t.Helper()
+
+	files, err := zippy.Contents(path)
+	assert.NoError(t, err)
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Tests for the add command.
+func Test_add(t *testing.T) {
+	t.Run("adds files", func(t *testing.T) {
+		dir := t.TempDir()
+		createSourceDir(t, dir)
+
+		_, stderr, code := zippyCmd(t, dir, "add", testZipFileName, "src")
+		assert.Equal(t, exitOK, code, stderr)
+		assert.Equal(t, []string{"src/", "src/a.txt", "src/b.txt", "src/build/", "src/build/out.txt", "src/main.o"}, entryNames(t, filepath.Join(dir, testZipFileName)))
+	})
+
+	t.Run("excludes and junks paths", func(t *testing.T) {
+		dir := t.TempDir()
+		createSourceDir(t, dir)
+
+		_, stderr, code := zippyCmd(t, dir, "add", "-j", testZipFileName, "src/a.txt", "src/main.o", "-x", "*.o")
+		assert.Equal(t, exitOK, code, stderr)
+		assert.Equal(t, []string{"a.txt"}, entryNames(t, filepath.Join(dir, testZipFileName)))
+	})
+
+	t.Run("json output", func(t *testing.T) {
+		dir := t.TempDir()
+		createSourceDir(t, dir)
+
+		stdout, stderr, code := zippyCmd(t, dir, "--json", "add", testZipFileName, "src/a.txt")
+		assert.Equal(t, exitOK, code, stderr)
+
+		var listing zippy.Listing
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &listing))
+		assert.Equal(t, 1, listing.Files)
+		assert.Equal(t, "src/a.txt", listing.Entries[0].Name)
+	})
+
+	t.Run("missing arguments", func(t *testing.T) {
+		_, stderr, code := zippyCmd(t, t.TempDir(), "add", testZipFileName)
+		assert.Equal(t, exitUsage, code)
+		assert.Contains(t, stderr, "usage:")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, _, code := zippyCmd(t, t.TempDir(), "add", testZipFileName, "missing.txt")
+		assert.Equal(t, exitNotFound, code)
+	})
+}
+
+// Tests for the delete command.
+func Test_delete(t *testing.T) {
+	dir := t.TempDir()
+	zipFilePath := filepath.Join(dir, testZipFileName)
+
+	_, err := testutils.CreateZipFile(zipFilePath, 3, 0)
+	assert.NoError(t, err)
+
+	names := entryNames(t, zipFilePath)
+
+	_, stderr, code := zippyCmd(t, dir, "-d", testZipFileName, names[0])
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, names[1:], entryNames(t, zipFilePath))
+
+	_, stderr, code = zippyCmd(t, dir, "delete", testZipFileName, "*.txt")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Empty(t, entryNames(t, zipFilePath))
+}
+
+// Tests for the update and freshen commands.
+func Test_update_freshen(t *testing.T) {
+	dir := t.TempDir()
+	createSourceDir(t, dir)
+	zipFilePath := filepath.Join(dir, testZipFileName)
+
+	_, stderr, code := zippyCmd(t, dir, "add", testZipFileName, "src/a.txt")
+	assert.Equal(t, exitOK, code, stderr)
+
+	changed := filepath.Join(dir, "src", "a.txt")
+	assert.NoError(t, os.WriteFile(changed, []byte("changed"), 0644))
+	assert.NoError(t, os.Chtimes(changed, time.Now(), time.Now().Add(time.Hour)))
+
+	// Freshening from the entry names never adds files
+	_, stderr, code = zippyCmd(t, dir, "-f", testZipFileName)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, []string{"src/a.txt"}, entryNames(t, zipFilePath))
+
+	_, stderr, code = zippyCmd(t, dir, "extract", "-d", "out", testZipFileName)
+	assert.Equal(t, exitOK, code, stderr)
+
+	contents, err := os.ReadFile(filepath.Join(dir, "out", "src", "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", string(contents))
+
+	_, stderr, code = zippyCmd(t, dir, "-u", testZipFileName, "src/b.txt")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, []string{"src/a.txt", "src/b.txt"}, entryNames(t, zipFilePath))
+
+	_, _, code = zippyCmd(t, dir, "freshen", "missing.zip", "src/a.txt")
+	assert.Equal(t, exitNotFound, code)
+}
+
+// Tests for the copy command.
+func Test_copy(t *testing.T) {
+	dir := t.TempDir()
+	zipFilePath := filepath.Join(dir, testZipFileName)
+
+	_, err := testutils.CreateZipFile(zipFilePath, 2, 1)
+	assert.NoError(t, err)
+
+	_, stderr, code := zippyCmd(t, dir, "copy", testZipFileName, "all.zip")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Equal(t, entryNames(t, zipFilePath), entryNames(t, filepath.Join(dir, "all.zip")))
+
+	_, stderr, code = zippyCmd(t, dir, "copy", testZipFileName, "top.zip", "test*")
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Len(t, entryNames(t, filepath.Join(dir, "top.zip")), 2)
+}
+
+// Tests for the extract command.
+func Test_extract(t *testing.T) {
+	t.Run("extracts files", func(t *testing.T) {
+		dir := t.TempDir()
+		zipFilePath := filepath.Join(dir, testZipFileName)
+
+		expected, err := testutils.CreateZipFile(zipFilePath, 2, 1)
+		assert.NoError(t, err)
+
+		stdout, stderr, code := zippyCmd(t, dir, "--json", "extract", testZipFileName, "-d", "out")
+		assert.Equal(t, exitOK, code, stderr)
+
+		var result struct {
+			Extracted []string `json:"extracted"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &result))
+		assert.Len(t, result.Extracted, expected+1)
+
+		for _, name := range result.Extracted {
+			_, err := os.Stat(filepath.Join(dir, "out", name))
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("extracts tar archives", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "file.txt"), []byte("hello"), 0644))
+
+		tarry := zippy.NewTarry(filepath.Join(dir, "archive.bin"), zippy.TarGzip)
+		tarry.Junk = true
+		assert.NoError(t, tarry.Add(filepath.Join(dir, "file.txt")))
+
+		_, stderr, code := zippyCmd(t, dir, "extract", "archive.bin", "-d", "out")
+		assert.Equal(t, exitOK, code, stderr)
+		assert.FileExists(t, filepath.Join(dir, "out", "file.txt"))
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "file.7z"), []byte("7z\xbc\xaf\x27\x1c\x00\x04"), 0644))
+
+		_, stderr, code := zippyCmd(t, dir, "extract", "file.7z")
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr, "unsupported archive format: 7z")
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		dir := t.TempDir()
+		zipFilePath := filepath.Join(dir, testZipFileName)
+
+		_, err := testutils.CreateZipFile(zipFilePath, 1, 0)
+		assert.NoError(t, err)
+
+		_, stderr, code := zippyCmd(t, dir, "extract", testZipFileName)
+		assert.Equal(t, exitOK, code, stderr)
+
+		_, stderr, code = zippyCmd(t, dir, "extract", testZipFileName)
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr, "exists")
+
+		_, stderr, code = zippyCmd(t, dir, "extract", "-o", testZipFileName)
+		assert.Equal(t, exitOK, code, stderr)
+	})
+
+	t.Run("excludes and junks paths", func(t *testing.T) {
+		dir := t.TempDir()
+		createSourceDir(t, dir)
+
+		_, stderr, code := zippyCmd(t, dir, "add", testZipFileName, "src")
+		assert.Equal(t, exitOK, code, stderr)
+
+		_, stderr, code = zippyCmd(t, dir, "extract", "-j", "-x", "*.o", "-x", "src/build", "-d", "out", testZipFileName)
+		assert.Equal(t, exitOK, code, stderr)
+
+		entries, err := os.ReadDir(filepath.Join(dir, "out"))
+		assert.NoError(t, err)
+
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+
+		assert.Equal(t, []string{"a.txt", "b.txt", "src"}, names)
+	})
+
+	t.Run("password", func(t *testing.T) {
+		dir := t.TempDir()
+		createSourceDir(t, dir)
+
+		_, stderr, code := zippyCmd(t, dir, "add", "-P", "secret", testZipFileName, "src/a.txt")
+		assert.Equal(t, exitOK, code, stderr)
+
+		_, _, code = zippyCmd(t, dir, "extract", "-d", "none", testZipFileName)
+		assert.Equal(t, exitPassword, code)
+
+		_, _, code = zippyCmd(t, dir, "extract", "-P", "wrong", "-d", "wrong", testZipFileName)
+		assert.Equal(t, exitPassword, code)
+
+		_, stderr, code = zippyCmd(t, dir, "extract", "-P", "secret", "-d", "out", testZipFileName)
+		assert.Equal(t, exitOK, code, stderr)
+		assert.FileExists(t, filepath.Join(dir, "out", "src", "a.txt"))
+	})
+
+	t.Run("missing archive", func(t *testing.T) {
+		_, stderr, code := zippyCmd(t, t.TempDir(), "--json", "extract", "missing.zip")
+		assert.Equal(t, exitNotFound, code)
+
+		var result struct {
+			Error    string `json:"error"`
+			ExitCode int    `json:"exit_code"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(stderr), &result))
+		assert.Equal(t, exitNotFound, result.ExitCode)
+		assert.NotEmpty(t, result.Error)
+	})
+}
+
+// Tests for the list command.
+func Test_list(t *testing.T) {
+	dir := t.TempDir()
+	zipFilePath := filepath.Join(dir, testZipFileName)
+
+	expected, err := testutils.CreateZipFile(zipFilePath, 3, 0)
+	assert.NoError(t, err)
+
+	stdout, stderr, code := zippyCmd(t, dir, "list", testZipFileName)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, fmt.Sprintf("%d files", expected))
+
+	stdout, stderr, code = zippyCmd(t, dir, "list", "-v", testZipFileName)
+	assert.Equal(t, exitOK, code, stderr)
+	assert.Contains(t, stdout, "CRC-32")
+
+	stdout, stderr, code = zippyCmd(t, dir, "list", "--json", testZipFileName)
+	assert.Equal(t, exitOK, code, stderr)
+
+	var listing zippy.Listing
+	assert.NoError(t, json.Unmarshal([]byte(stdout), &listing))
+	assert.Equal(t, expected, listing.Files)
+
+	_, _, code = zippyCmd(t, dir, "list", "missing.zip")
+	assert.Equal(t, exitNotFound, code)
+}
+
+// Tests for the test command.
+func Test_test(t *testing.T) {
+	t.Run("intact archive", func(t *testing.T) {
+		dir := t.TempDir()
+		zipFilePath := filepath.Join(dir, testZipFileName)
+
+		_, err := testutils.CreateZipFile(zipFilePath, 2, 1)
+		assert.NoError(t, err)
+
+		stdout, stderr, code := zippyCmd(t, dir, "test", testZipFileName)
+		assert.Equal(t, exitOK, code, stderr)
+		assert.Contains(t, stdout, "No errors detected")
+	})
+
+	t.Run("corrupt archive", func(t *testing.T) {
+		dir := t.TempDir()
+		zipFilePath := filepath.Join(dir, testZipFileName)
+
+		_, err := testutils.CreateZipFile(zipFilePath, 1, 0)
+		assert.NoError(t, err)
+
+		// Flip a byte of the stored data, just after the local header and name
+		data, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+		nameLen := int(data[26]) | int(data[27])<<8
+		data[30+nameLen] ^= 0xff
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		stdout, _, code := zippyCmd(t, dir, "--json", "test", testZipFileName)
+		assert.Equal(t, exitCorrupt, code)
+
+		var result struct {
+			OK bool `json:"ok"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(stdout), &result))
+		assert.False(t, result.OK)
+	})
+
+	t.Run("not a zip file", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, testZipFileName), []byte("not a zip file"), 0644))
+
+		_, _, code := zippyCmd(t, dir, "test", testZipFileName)
+		assert.Equal(t, exitCorrupt, code)
+	})
+}
+
+// Tests for command line parsing.
+func Test_run_usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no command", args: nil},
+		{name: "unknown command", args: []string{"bogus"}},
+		{name: "unknown flag", args: []string{"list", "-q", testZipFileName}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, exitUsage, run(tt.args, &stdout, &stderr))
+			assert.Contains(t, stderr.String(), "usage:")
+		})
+	}
+
+	t.Run("arguments after --", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, exitNotFound, run([]string{"list", "--", "-v"}, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "-v")
+	})
+}
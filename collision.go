@@ -0,0 +1,124 @@
+package zippy
+
+import (
+	"archive/zip"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NameFolding selects which entry names are treated as the same file, as
+// filesystems that do not tell them apart would.
+type NameFolding uint8
+
+const (
+	FoldCase    NameFolding = 1 << iota // FoldCase treats names differing only in case as the same, like Windows and macOS filesystems.
+	FoldUnicode                         // FoldUnicode treats the composed and decomposed forms of a name as the same, like macOS filesystems.
+)
+
+// fold returns the form of name shared by all the names treated as the same.
+func (f NameFolding) fold(name string) string {
+	if f&FoldUnicode != 0 {
+		name = norm.NFC.String(name)
+	}
+
+	if f&FoldCase != 0 {
+		name = strings.Map(foldRune, name)
+	}
+
+	return name
+}
+
+// foldRune returns the smallest rune equivalent to r under simple case
+// folding, so a name keeps its length in runes when folded.
+func foldRune(r rune) rune {
+	folded := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		folded = min(folded, f)
+	}
+
+	return folded
+}
+
+// collisions returns the names of each group of files that would be written
+// to the same file when extracted under the names returned by target,
+// comparing names with folding. The directories holding a file collide with
+// files of the same name, but directories only colliding with directories are
+// left out, as creating them twice is harmless.
+func collisions(files []*zip.File, target func(name string) string, folding NameFolding) [][]string {
+	type written struct {
+		files   []int // Indexes of the files written to or through the target.
+		regular bool  // Whether a file that is not a directory is written to it.
+	}
+
+	targets := make(map[string]*written)
+	var order []string
+
+	add := func(key string, i int, regular bool) {
+		t, ok := targets[key]
+		if !ok {
+			t = &written{}
+			targets[key] = t
+			order = append(order, key)
+		}
+
+		t.files = append(t.files, i)
+		t.regular = t.regular || regular
+	}
+
+	for i, file := range files {
+		key := folding.fold(strings.TrimSuffix(target(file.Name), "/"))
+		add(key, i, !file.FileInfo().IsDir())
+
+		for j := strings.LastIndex(key, "/"); j > 0; j = strings.LastIndex(key[:j], "/") {
+			add(key[:j], i, false)
+		}
+	}
+
+	var groups [][]string
+	for _, key := range order {
+		if t := targets[key]; t.regular && len(t.files) > 1 {
+			names := make([]string, len(t.files))
+			for i, index := range t.files {
+				names[i] = files[index].Name
+			}
+
+			groups = append(groups, names)
+		}
+	}
+
+	return groups
+}
+
+// duplicateNames returns the number of files with each name that appears
+// more than once in files.
+func duplicateNames(files []*zip.File) map[string]int {
+	counts := make(map[string]int, len(files))
+	for _, file := range files {
+		counts[file.Name]++
+	}
+
+	for name, count := range counts {
+		if count < 2 {
+			delete(counts, name)
+		}
+	}
+
+	return counts
+}
+
+// checkCollisions fails with a [CollisionError] if any of files would be
+// written to the same file as another.
+func (u *Unzippy) checkCollisions(files []*zip.File) error {
+	target := func(name string) string {
+		name, _ = u.Options.extractName(name)
+		return name
+	}
+
+	if groups := collisions(files, target, u.Options.Folding); len(groups) > 0 {
+		return &CollisionError{Archive: u.Path, Names: groups}
+	}
+
+	return nil
+}
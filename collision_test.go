@@ -0,0 +1,271 @@
+package zippy
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [NameFolding.fold] function.
+func Test_NameFolding_fold(t *testing.T) {
+	composed, decomposed := "caf\u00e9.txt", "cafe\u0301.txt"
+
+	tests := []struct {
+		name    string
+		folding NameFolding
+		a, b    string
+		same    bool
+	}{
+		{"none", 0, "README", "readme", false},
+		{"case", FoldCase, "README", "readme", true},
+		{"case kelvin sign", FoldCase, "\u212a.txt", "k.txt", true},
+		{"case keeps forms apart", FoldCase, composed, decomposed, false},
+		{"unicode", FoldUnicode, composed, decomposed, true},
+		{"unicode keeps case", FoldUnicode, "README", "readme", false},
+		{"both", FoldCase | FoldUnicode, "CAFÉ.TXT", decomposed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.same, tt.folding.fold(tt.a) == tt.folding.fold(tt.b))
+		})
+	}
+}
+
+// Tests for [collisions] function.
+func Test_collisions(t *testing.T) {
+	// zipFiles returns zip files with the given names.
+	zipFiles := func(names ...string) []*zip.File {
+		files := make([]*zip.File, len(names))
+		for i, name := range names {
+			files[i] = &zip.File{FileHeader: zip.FileHeader{Name: name}}
+		}
+
+		return files
+	}
+
+	tests := []struct {
+		name    string
+		files   []*zip.File
+		junk    bool
+		folding NameFolding
+		want    [][]string
+	}{
+		{
+			name:  "distinct",
+			files: zipFiles("README", "readme", "dir/", "dir/a.txt"),
+		},
+		{
+			name:  "duplicate",
+			files: zipFiles("a.txt", "b.txt", "a.txt"),
+			want:  [][]string{{"a.txt", "a.txt"}},
+		},
+		{
+			name:  "duplicate directory",
+			files: zipFiles("dir/", "dir/"),
+		},
+		{
+			name:    "case",
+			files:   zipFiles("README", "dir/", "readme", "Dir/"),
+			folding: FoldCase,
+			want:    [][]string{{"README", "readme"}},
+		},
+		{
+			name:    "file and directory",
+			files:   zipFiles("Build", "build/out.o"),
+			folding: FoldCase,
+			want:    [][]string{{"Build", "build/out.o"}},
+		},
+		{
+			name:    "file and directory entry",
+			files:   zipFiles("docs", "docs/"),
+			folding: FoldCase,
+			want:    [][]string{{"docs", "docs/"}},
+		},
+		{
+			name:    "unicode",
+			files:   zipFiles("caf\u00e9.txt", "cafe\u0301.txt"),
+			folding: FoldUnicode,
+			want:    [][]string{{"caf\u00e9.txt", "cafe\u0301.txt"}},
+		},
+		{
+			name:  "junk",
+			files: zipFiles("a/x.txt", "b/x.txt", "c/y.txt"),
+			junk:  true,
+			want:  [][]string{{"a/x.txt", "b/x.txt"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := &UnzippyOptions{Junk: tt.junk}
+			target := func(name string) string {
+				name, _ = options.extractName(name)
+				return name
+			}
+
+			assert.Equal(t, tt.want, collisions(tt.files, target, tt.folding))
+		})
+	}
+}
+
+// Tests for [UnzippyOptions.Collisions] option.
+func Test_Unzippy_Collisions(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "archive.zip")
+	createTestZip(t, zipPath, [][2]string{
+		{"README", "upper"},
+		{"readme", "lower"},
+		{"caf\u00e9.txt", "composed"},
+		{"cafe\u0301.txt", "decomposed"},
+	})
+
+	tests := []struct {
+		name    string
+		folding NameFolding
+		want    [][]string
+	}{
+		{"case", FoldCase, [][]string{{"README", "readme"}}},
+		{"unicode", FoldUnicode, [][]string{{"caf\u00e9.txt", "cafe\u0301.txt"}}},
+		{"both", FoldCase | FoldUnicode, [][]string{{"README", "readme"}, {"caf\u00e9.txt", "cafe\u0301.txt"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+
+			u, err := NewUnzippy(zipPath, &UnzippyOptions{Collisions: true, Folding: tt.folding})
+			assert.NoError(t, err)
+
+			_, err = u.ExtractTo(dest)
+			assert.ErrorIs(t, err, ErrNameCollision)
+
+			var collisionErr *CollisionError
+			if assert.ErrorAs(t, err, &collisionErr) {
+				assert.Equal(t, zipPath, collisionErr.Archive)
+				assert.Equal(t, tt.want, collisionErr.Names)
+			}
+
+			entries, err := os.ReadDir(dest)
+			assert.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+
+	t.Run("selected entries", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{Collisions: true, Folding: FoldCase | FoldUnicode})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractFilesTo(filepath.Join(t.TempDir(), "dest"), "README", "caf\u00e9.txt")
+		assert.NoError(t, err)
+		assert.Len(t, files, 2)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{Collisions: true, Folding: FoldCase, DryRun: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "dest"))
+		assert.ErrorIs(t, err, ErrNameCollision)
+		assert.Nil(t, u.Plan)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		duplicatePath := filepath.Join(tempDir, "duplicate.zip")
+		createTestZip(t, duplicatePath, [][2]string{{"a.txt", "first"}, {"b.txt", "bravo"}, {"a.txt", "second"}})
+
+		u, err := NewUnzippy(duplicatePath, &UnzippyOptions{Collisions: true, Overwrite: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(t.TempDir(), "dest"))
+		var collisionErr *CollisionError
+		if assert.ErrorAs(t, err, &collisionErr) {
+			assert.Equal(t, [][]string{{"a.txt", "a.txt"}}, collisionErr.Names)
+		}
+	})
+}
+
+// Tests for [UnzippyOptions.IgnoreCase] option.
+func Test_Unzippy_IgnoreCase(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "archive.zip")
+	createTestZip(t, zipPath, [][2]string{
+		{"README.md", "readme"},
+		{"Docs/Guide.TXT", "guide"},
+		{"Docs/notes.txt", "notes"},
+	})
+
+	t.Run("patterns", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{IgnoreCase: true, FailUnmatched: true})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractFilesTo(filepath.Join(t.TempDir(), "dest"), "readme.*", "docs/guide.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"README.md", "Docs/Guide.TXT"}, zipFileNames(files))
+	})
+
+	t.Run("exclude", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{IgnoreCase: true, Exclude: []string{"*.txt"}, DryRun: true})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractTo(filepath.Join(t.TempDir(), "dest"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"README.md"}, zipFileNames(files))
+	})
+
+	t.Run("case-sensitive", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{FailUnmatched: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractFilesTo(filepath.Join(t.TempDir(), "dest"), "readme.*")
+		assert.ErrorIs(t, err, ErrNoMatch)
+	})
+
+	t.Run("zippy", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.IgnoreCase = true
+		z.FailUnmatched = true
+
+		assert.NoError(t, z.Delete("DOCS/*"))
+		assert.Equal(t, map[string]string{"README.md": "readme"}, readTestZip(t, zipPath, false))
+	})
+
+	t.Run("zippy exclude", func(t *testing.T) {
+		srcDir := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "DEBUG.LOG"), []byte("log"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "notes.txt"), []byte("notes"), 0644))
+
+		addedPath := filepath.Join(t.TempDir(), "added.zip")
+		z := NewZippy(addedPath)
+		z.Junk = true
+		z.IgnoreCase = true
+		z.Exclude = []string{"*.log"}
+
+		assert.NoError(t, z.Add(filepath.Join(srcDir, "*")))
+		assert.Equal(t, map[string]string{"notes.txt": "notes"}, readTestZip(t, addedPath, false))
+	})
+}
+
+// Tests for duplicate names reported by [Unzippy.Verify].
+func Test_Unzippy_Verify_duplicates(t *testing.T) {
+	zipPath := filepath.Join(t.TempDir(), "duplicate.zip")
+	createTestZip(t, zipPath, [][2]string{{"a.txt", "first"}, {"b.txt", "bravo"}, {"a.txt", "second"}})
+
+	u, err := NewUnzippy(zipPath, nil)
+	assert.NoError(t, err)
+
+	report, err := u.Verify()
+	assert.ErrorIs(t, err, ErrVerificationFailed)
+
+	if assert.NotNil(t, report) {
+		failed := report.Failed()
+		if assert.Len(t, failed, 2) {
+			assert.Equal(t, "a.txt", failed[0].Name)
+			assert.Equal(t, "a.txt", failed[1].Name)
+			assert.ErrorIs(t, failed[0].Problems[0], ErrNameCollision)
+		}
+	}
+}
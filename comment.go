@@ -0,0 +1,128 @@
+package zippy
+
+import (
+	"archive/zip"
+)
+
+// readComments returns the archive comment and the entry comments of a zip
+// archive, keyed by entry name.
+func readComments(path string) (string, map[string]string, error) {
+	zipRead, err := openZip(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zipRead.Close()
+
+	comments := make(map[string]string, len(zipRead.File))
+	for _, file := range zipRead.File {
+		comments[file.Name] = file.Comment
+	}
+
+	return zipRead.Comment, comments, nil
+}
+
+// entryComment returns the comment of the named entry of a zip archive.
+func entryComment(path, name string) (string, error) {
+	_, comments, err := readComments(path)
+	if err != nil {
+		return "", err
+	}
+
+	comment, ok := comments[name]
+	if !ok {
+		return "", &EntryNotFoundError{EntryError{Op: "read comment of", Archive: path, Entry: name, Err: ErrEntryNotFound}}
+	}
+
+	return comment, nil
+}
+
+// Comment returns the archive comment.
+func (z *Zippy) Comment() (string, error) {
+	comment, _, err := readComments(z.Path)
+	return comment, err
+}
+
+// EntryComment returns the comment of the named entry.
+func (z *Zippy) EntryComment(name string) (string, error) {
+	return entryComment(z.Path, name)
+}
+
+// SetComment replaces the archive comment. Entries are copied unchanged.
+func (z *Zippy) SetComment(comment string) error {
+	return z.rewrite(func(zReader *zip.ReadCloser, zWriter *zip.Writer) error {
+		if err := zWriter.SetComment(comment); err != nil {
+			return err
+		}
+
+		for _, file := range zReader.File {
+			if err := copyRaw(zWriter, file); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SetEntryComment replaces the comment of the named entry. Entries are copied
+// unchanged otherwise.
+func (z *Zippy) SetEntryComment(name, comment string) error {
+	return z.SetEntryComments(map[string]string{name: comment})
+}
+
+// SetEntryComments replaces the comments of several entries at once.
+//
+// comments maps entry names to their new comments. Every named entry must
+// exist in the archive.
+func (z *Zippy) SetEntryComments(comments map[string]string) error {
+	return z.rewrite(func(zReader *zip.ReadCloser, zWriter *zip.Writer) error {
+		for name, comment := range comments {
+			if len(comment) > uint16max {
+				return &LimitError{
+					EntryError: EntryError{Op: "set comment of", Archive: z.Path, Entry: name},
+					Field:      "comment",
+					Limit:      uint16max,
+					Size:       int64(len(comment)),
+				}
+			}
+		}
+
+		found := make(map[string]bool, len(comments))
+
+		for _, file := range zReader.File {
+			if comment, ok := comments[file.Name]; ok {
+				file.Comment = comment
+				found[file.Name] = true
+
+				if requiresUTF8(comment) {
+					file.Flags |= 0x800
+				}
+			}
+		}
+
+		for name := range comments {
+			if !found[name] {
+				return &EntryNotFoundError{EntryError{Op: "set comment of", Archive: z.Path, Entry: name, Err: ErrEntryNotFound}}
+			}
+		}
+
+		for _, file := range zReader.File {
+			if err := copyRaw(zWriter, file); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Comment returns the archive comment.
+func (u *Unzippy) Comment() (string, error) {
+	comment, _, err := readComments(u.Path)
+	return comment, err
+}
+
+// EntryComment returns the comment of the named entry.
+func (u *Unzippy) EntryComment(name string) (string, error) {
+	return entryComment(u.Path, name)
+}
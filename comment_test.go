@@ -0,0 +1,228 @@
+package zippy
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/engmtcdrm/go-zippy/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// createCommentTestFile creates a zip archive with an archive comment and a
+// comment on its first file entry, returning the entry name.
+func createCommentTestFile(t *testing.T, zipFilePath string) string {
+	t.Helper()
+
+	_, err := testutils.CreateZipFile(zipFilePath, 3, 1)
+	assert.NoError(t, err)
+
+	z := NewZippy(zipFilePath)
+	assert.NoError(t, z.SetComment("build 1234"))
+
+	zipReader, err := zip.OpenReader(zipFilePath)
+	assert.NoError(t, err)
+	defer zipReader.Close()
+
+	var name string
+	for _, file := range zipReader.File {
+		if !file.FileInfo().IsDir() {
+			name = file.Name
+			break
+		}
+	}
+
+	assert.NoError(t, z.SetEntryComment(name, "entry comment"))
+
+	return name
+}
+
+// Tests for [Zippy.SetComment] and [Zippy.Comment] functions.
+func Test_Zippy_SetComment(t *testing.T) {
+	t.Run("set and read", func(t *testing.T) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+		_, err := testutils.CreateZipFile(zipFilePath, 2, 0)
+		assert.NoError(t, err)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.SetComment("build 1234"))
+
+		comment, err := z.Comment()
+		assert.NoError(t, err)
+		assert.Equal(t, "build 1234", comment)
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+		comment, err = u.Comment()
+		assert.NoError(t, err)
+		assert.Equal(t, "build 1234", comment)
+
+		report, err := u.Verify()
+		assert.NoError(t, err)
+		assert.True(t, report.OK())
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+		_, err := testutils.CreateZipFile(zipFilePath, 1, 0)
+		assert.NoError(t, err)
+
+		before, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+
+		z := NewZippy(zipFilePath)
+		assert.Error(t, z.SetComment(strings.Repeat("a", uint16max+1)))
+
+		after, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+		assert.Equal(t, before, after)
+
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(zipFilePath), "zippy-*"))
+		assert.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("nonexistent archive", func(t *testing.T) {
+		z := NewZippy(filepath.Join(t.TempDir(), testZipFileName))
+		assert.Error(t, z.SetComment("comment"))
+
+		_, err := z.Comment()
+		assert.Error(t, err)
+	})
+}
+
+// Tests for [Zippy.SetEntryComment] and [Zippy.EntryComment] functions.
+func Test_Zippy_SetEntryComment(t *testing.T) {
+	t.Run("set and read", func(t *testing.T) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+		name := createCommentTestFile(t, zipFilePath)
+
+		z := NewZippy(zipFilePath)
+		comment, err := z.EntryComment(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "entry comment", comment)
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+		comment, err = u.EntryComment(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "entry comment", comment)
+
+		// The archive comment is kept
+		comment, err = z.Comment()
+		assert.NoError(t, err)
+		assert.Equal(t, "build 1234", comment)
+	})
+
+	t.Run("utf-8 comment", func(t *testing.T) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+		name := createCommentTestFile(t, zipFilePath)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.SetEntryComment(name, "größe"))
+
+		comment, err := z.EntryComment(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "größe", comment)
+	})
+
+	t.Run("missing entry", func(t *testing.T) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+		createCommentTestFile(t, zipFilePath)
+
+		z := NewZippy(zipFilePath)
+		assert.ErrorIs(t, z.SetEntryComment("missing.txt", "comment"), ErrEntryNotFound)
+
+		_, err := z.EntryComment("missing.txt")
+		assert.ErrorIs(t, err, ErrEntryNotFound)
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+		name := createCommentTestFile(t, zipFilePath)
+
+		z := NewZippy(zipFilePath)
+		assert.Error(t, z.SetEntryComment(name, strings.Repeat("a", uint16max+1)))
+
+		comment, err := z.EntryComment(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "entry comment", comment)
+	})
+}
+
+// Tests that [Zippy.Add], [Zippy.Copy] and [Zippy.Delete] keep comments.
+func Test_Zippy_keepComments(t *testing.T) {
+	assertComments := func(t *testing.T, zipFilePath, name string) {
+		t.Helper()
+
+		z := NewZippy(zipFilePath)
+
+		comment, err := z.Comment()
+		assert.NoError(t, err)
+		assert.Equal(t, "build 1234", comment)
+
+		comment, err = z.EntryComment(name)
+		assert.NoError(t, err)
+		assert.Equal(t, "entry comment", comment)
+	}
+
+	t.Run("add", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		name := createCommentTestFile(t, zipFilePath)
+
+		newFile := filepath.Join(tempDir, "new.txt")
+		assert.NoError(t, os.WriteFile(newFile, []byte("new"), 0644))
+
+		assert.NoError(t, NewZippy(zipFilePath).Add(newFile))
+		assertComments(t, zipFilePath, name)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		name := createCommentTestFile(t, zipFilePath)
+
+		dest := filepath.Join(tempDir, "all.zip")
+		assert.NoError(t, NewZippy(zipFilePath).Copy(dest))
+		assertComments(t, dest, name)
+
+		dest = filepath.Join(tempDir, "some.zip")
+		assert.NoError(t, NewZippy(zipFilePath).Copy(dest, name))
+		assertComments(t, dest, name)
+	})
+
+	t.Run("copy with encryption", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		name := createCommentTestFile(t, zipFilePath)
+
+		dest := filepath.Join(tempDir, "encrypted.zip")
+		z := NewZippy(zipFilePath)
+		z.Encryption = &Encryption{Method: AES256, Password: "secret"}
+		assert.NoError(t, z.Copy(dest))
+		assertComments(t, dest, name)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		name := createCommentTestFile(t, zipFilePath)
+
+		zipReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+		var other string
+		for _, file := range zipReader.File {
+			if !file.FileInfo().IsDir() && file.Name != name {
+				other = file.Name
+				break
+			}
+		}
+		zipReader.Close()
+
+		assert.NoError(t, NewZippy(zipFilePath).Delete(other))
+		assertComments(t, zipFilePath, name)
+	})
+}
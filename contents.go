@@ -2,11 +2,19 @@ package zippy
 
 import (
 	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"time"
 )
 
 // Contents returns a list of files in the zip archive.
+//
+// The archive is closed before returning, so the files cannot be opened. Use
+// [List] for entry metadata that does not depend on an open archive.
 func Contents(zipFile string) ([]*zip.File, error) {
-	zipRead, err := zip.OpenReader(zipFile)
+	zipRead, err := openZip(zipFile)
 	if err != nil {
 		return nil, err
 	}
@@ -14,3 +22,224 @@ func Contents(zipFile string) ([]*zip.File, error) {
 
 	return zipRead.File, err
 }
+
+// Entry describes a single entry of a zip archive.
+type Entry struct {
+	Name             string           `json:"name"`              // Name of the entry.
+	CompressedSize   uint64           `json:"compressed_size"`   // Size of the stored data, including any encryption overhead.
+	UncompressedSize uint64           `json:"uncompressed_size"` // Size of the entry once extracted.
+	Ratio            float64          `json:"ratio"`             // Fraction of the uncompressed size saved by compression.
+	Method           string           `json:"method"`            // Name of the compression method.
+	CRC32            uint32           `json:"crc32"`             // CRC-32 checksum of the uncompressed data.
+	Modified         time.Time        `json:"modified"`          // Modification time.
+	Mode             fs.FileMode      `json:"-"`                 // File mode and permission bits.
+	UnixMode         uint32           `json:"unix_mode"`         // Unix mode as stored in the archive. Zero if the entry was not created on Unix.
+	Encrypted        bool             `json:"encrypted"`         // Whether the entry is encrypted.
+	Encryption       EncryptionMethod `json:"-"`                 // Encryption method of the entry.
+	Comment          string           `json:"comment"`           // Entry comment.
+	IsDir            bool             `json:"is_dir"`            // Whether the entry is a directory.
+	IsSymlink        bool             `json:"is_symlink"`        // Whether the entry is a symbolic link.
+}
+
+// MarshalJSON encodes the entry with its mode and encryption as strings.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type entry Entry
+
+	return json.Marshal(struct {
+		entry
+		Mode       string `json:"mode"`
+		Encryption string `json:"encryption"`
+	}{
+		entry:      entry(e),
+		Mode:       e.Mode.String(),
+		Encryption: e.Encryption.String(),
+	})
+}
+
+// Listing describes the entries of a zip archive and their totals.
+type Listing struct {
+	Comment          string  `json:"comment"`           // Archive comment.
+	Entries          []Entry `json:"entries"`           // Entries of the archive, in central directory order.
+	Files            int     `json:"files"`             // Number of entries that are not directories.
+	Dirs             int     `json:"dirs"`              // Number of directory entries.
+	CompressedSize   uint64  `json:"compressed_size"`   // Total size of the stored data.
+	UncompressedSize uint64  `json:"uncompressed_size"` // Total size of the entries once extracted.
+	Ratio            float64 `json:"ratio"`             // Fraction of the total uncompressed size saved by compression.
+}
+
+// methodNames maps compression method IDs to their names.
+var methodNames = map[uint16]string{
+	zip.Store:   "store",
+	zip.Deflate: "deflate",
+	9:           "deflate64",
+	12:          "bzip2",
+	14:          "lzma",
+	93:          "zstd",
+	95:          "xz",
+	98:          "ppmd",
+}
+
+// methodName returns the name of a compression method.
+func methodName(method uint16) string {
+	if name, ok := methodNames[method]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("method %d", method)
+}
+
+// ratio returns the fraction of size saved by compression.
+func ratio(compressed, uncompressed uint64) float64 {
+	if uncompressed == 0 {
+		return 0
+	}
+
+	return 1 - float64(compressed)/float64(uncompressed)
+}
+
+// newEntry builds the metadata of a zip file entry.
+func newEntry(file *zip.File) Entry {
+	mode := file.Mode()
+
+	entry := Entry{
+		Name:             file.Name,
+		CompressedSize:   file.CompressedSize64,
+		UncompressedSize: file.UncompressedSize64,
+		Ratio:            ratio(file.CompressedSize64, file.UncompressedSize64),
+		Method:           methodName(file.Method),
+		CRC32:            file.CRC32,
+		Modified:         file.Modified,
+		Mode:             mode,
+		Encryption:       EntryEncryption(file),
+		Comment:          file.Comment,
+		IsDir:            mode.IsDir(),
+		IsSymlink:        mode&fs.ModeSymlink != 0,
+	}
+
+	entry.Encrypted = entry.Encryption != NoEncryption
+
+	if file.CreatorVersion>>8 == creatorUnix {
+		entry.UnixMode = file.ExternalAttrs >> 16
+	}
+
+	// AES entries store their real compression method in the extra field
+	if file.Method == winzipAESMethod {
+		if extra, ok := findExtra(file.Extra, winzipAESExtraID); ok && len(extra) >= 7 {
+			entry.Method = methodName(uint16(extra[5]) | uint16(extra[6])<<8)
+		}
+	}
+
+	return entry
+}
+
+// List returns the metadata of every entry in the zip archive along with
+// aggregate totals.
+func List(zipFile string) (*Listing, error) {
+	zipRead, err := openZip(zipFile)
+	if err != nil {
+		return nil, err
+	}
+	defer zipRead.Close()
+
+	listing := &Listing{
+		Comment: zipRead.Comment,
+		Entries: make([]Entry, 0, len(zipRead.File)),
+	}
+
+	for _, file := range zipRead.File {
+		entry := newEntry(file)
+
+		if entry.IsDir {
+			listing.Dirs++
+		} else {
+			listing.Files++
+		}
+
+		listing.CompressedSize += entry.CompressedSize
+		listing.UncompressedSize += entry.UncompressedSize
+		listing.Entries = append(listing.Entries, entry)
+	}
+
+	listing.Ratio = ratio(listing.CompressedSize, listing.UncompressedSize)
+
+	return listing, nil
+}
+
+// entryCount returns the number of entries as a word, like unzip.
+func (l *Listing) entryCount() string {
+	if len(l.Entries) == 1 {
+		return "1 file"
+	}
+
+	return fmt.Sprintf("%d files", len(l.Entries))
+}
+
+// WriteTable writes the listing in the format of unzip -l.
+func (l *Listing) WriteTable(w io.Writer) error {
+	if l.Comment != "" {
+		if _, err := fmt.Fprintln(w, l.Comment); err != nil {
+			return err
+		}
+	}
+
+	lines := []string{
+		"  Length      Date    Time    Name",
+		"---------  ---------- -----   ----",
+	}
+
+	for _, entry := range l.Entries {
+		lines = append(lines, fmt.Sprintf("%9d  %s   %s", entry.UncompressedSize, entry.Modified.Format("2006-01-02 15:04"), entry.Name))
+	}
+
+	lines = append(lines,
+		"---------                     -------",
+		fmt.Sprintf("%9d                     %s", l.UncompressedSize, l.entryCount()),
+	)
+
+	return writeLines(w, lines)
+}
+
+// WriteVerbose writes the listing in the format of unzip -v.
+func (l *Listing) WriteVerbose(w io.Writer) error {
+	if l.Comment != "" {
+		if _, err := fmt.Fprintln(w, l.Comment); err != nil {
+			return err
+		}
+	}
+
+	lines := []string{
+		" Length   Method    Size  Cmpr    Date    Time   CRC-32   Encryption  Name",
+		"--------  ------  ------- ---- ---------- ----- --------  ----------  ----",
+	}
+
+	for _, entry := range l.Entries {
+		lines = append(lines, fmt.Sprintf("%8d  %-7s %7d %3.0f%% %s %08x  %-10s  %s",
+			entry.UncompressedSize, entry.Method, entry.CompressedSize, entry.Ratio*100,
+			entry.Modified.Format("2006-01-02 15:04"), entry.CRC32, entry.Encryption, entry.Name))
+	}
+
+	lines = append(lines,
+		"--------          -------  ---                                          -------",
+		fmt.Sprintf("%8d         %8d %3.0f%%                                          %s", l.UncompressedSize, l.CompressedSize, l.Ratio*100, l.entryCount()),
+	)
+
+	return writeLines(w, lines)
+}
+
+// WriteJSON writes the listing as indented JSON.
+func (l *Listing) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(l)
+}
+
+// writeLines writes each line followed by a newline.
+func writeLines(w io.Writer, lines []string) error {
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
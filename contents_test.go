@@ -2,10 +2,15 @@ package zippy
 
 import (
 	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/engmtcdrm/go-zippy/internal/testutils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,3 +46,138 @@ func TestContents(t *testing.T) {
 		assert.Nil(t, zipFiles)
 	})
 }
+
+// Tests for [List] function.
+func TestList(t *testing.T) {
+	t.Run("Zip file exists", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+		expected, err := testutils.CreateZipFile(zipFilePath, 3, 1)
+		assert.NoError(t, err)
+
+		listing, err := List(zipFilePath)
+		assert.NoError(t, err)
+		assert.Len(t, listing.Entries, expected+1)
+		assert.Equal(t, expected, listing.Files)
+		assert.Equal(t, 1, listing.Dirs)
+
+		var total uint64
+		for _, entry := range listing.Entries {
+			total += entry.UncompressedSize
+			if !entry.IsDir {
+				assert.Equal(t, "deflate", entry.Method)
+			}
+			assert.False(t, entry.Encrypted)
+			assert.False(t, entry.IsSymlink)
+		}
+		assert.Equal(t, total, listing.UncompressedSize)
+	})
+
+	t.Run("Encrypted entries", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := createAESTestFile(t, tempDir, AES128, "secret")
+
+		listing, err := List(zipFilePath)
+		assert.NoError(t, err)
+
+		for _, entry := range listing.Entries {
+			assert.Equal(t, "deflate", entry.Method)
+			assert.Equal(t, entry.Name == "secret.txt", entry.Encrypted)
+		}
+	})
+
+	t.Run("Unix mode and comments", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+		zipFile, err := os.Create(zipFilePath)
+		assert.NoError(t, err)
+
+		zipWriter := zip.NewWriter(zipFile)
+		assert.NoError(t, zipWriter.SetComment("archive comment"))
+
+		header := &zip.FileHeader{Name: "script.sh", Comment: "entry comment", Method: zip.Store}
+		header.SetMode(0755)
+		writer, err := zipWriter.CreateHeader(header)
+		assert.NoError(t, err)
+		_, err = writer.Write([]byte("#!/bin/sh\n"))
+		assert.NoError(t, err)
+
+		link := &zip.FileHeader{Name: "link"}
+		link.SetMode(os.ModeSymlink | 0777)
+		writer, err = zipWriter.CreateHeader(link)
+		assert.NoError(t, err)
+		_, err = writer.Write([]byte("script.sh"))
+		assert.NoError(t, err)
+
+		assert.NoError(t, zipWriter.Close())
+		assert.NoError(t, zipFile.Close())
+
+		listing, err := List(zipFilePath)
+		assert.NoError(t, err)
+		assert.Equal(t, "archive comment", listing.Comment)
+		assert.Equal(t, "entry comment", listing.Entries[0].Comment)
+		assert.Equal(t, "store", listing.Entries[0].Method)
+		assert.Equal(t, uint32(0100755), listing.Entries[0].UnixMode)
+		assert.Equal(t, os.FileMode(0755), listing.Entries[0].Mode)
+		assert.True(t, listing.Entries[1].IsSymlink)
+	})
+
+	t.Run("Zip file does not exist", func(t *testing.T) {
+		listing, err := List("nonexistent.zip")
+		assert.Error(t, err)
+		assert.Nil(t, listing)
+	})
+}
+
+// Tests for [Listing] output formatters.
+func TestListing_Write(t *testing.T) {
+	tempDir := t.TempDir()
+	zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+	_, err := testutils.CreateZipFile(zipFilePath, 2, 0)
+	assert.NoError(t, err)
+
+	listing, err := List(zipFilePath)
+	assert.NoError(t, err)
+
+	t.Run("table", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, listing.WriteTable(&buf))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 6)
+		assert.Contains(t, lines[0], "Length")
+		assert.Contains(t, lines[2], listing.Entries[0].Name)
+		assert.Contains(t, lines[5], "2 files")
+	})
+
+	t.Run("verbose", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, listing.WriteVerbose(&buf))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 6)
+		assert.Contains(t, lines[0], "CRC-32")
+		assert.Contains(t, lines[2], fmt.Sprintf("%08x", listing.Entries[0].CRC32))
+		assert.Contains(t, lines[2], "deflate")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, listing.WriteJSON(&buf))
+
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Equal(t, float64(2), decoded["files"])
+
+		entries := decoded["entries"].([]any)
+		assert.Len(t, entries, 2)
+
+		entry := entries[0].(map[string]any)
+		assert.Equal(t, listing.Entries[0].Name, entry["name"])
+		assert.Equal(t, "none", entry["encryption"])
+		assert.Equal(t, listing.Entries[0].Mode.String(), entry["mode"])
+	})
+}
@@ -0,0 +1,227 @@
+package zippy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Format is an archive or compression format recognized by [Detect].
+type Format int
+
+const (
+	FormatUnknown Format = iota // Not a recognized format.
+	FormatZip                   // Zip archive.
+	FormatTar                   // Uncompressed tar archive.
+	FormatGzip                  // Gzip compressed stream.
+	FormatBzip2                 // Bzip2 compressed stream.
+	FormatXz                    // Xz compressed stream.
+	FormatZstd                  // Zstandard compressed stream.
+	Format7z                    // 7-Zip archive.
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case FormatZip:
+		return "zip"
+	case FormatTar:
+		return "tar"
+	case FormatGzip:
+		return "gzip"
+	case FormatBzip2:
+		return "bzip2"
+	case FormatXz:
+		return "xz"
+	case FormatZstd:
+		return "zstd"
+	case Format7z:
+		return "7z"
+	default:
+		return "unknown"
+	}
+}
+
+// UnsupportedFormatError is returned by [Open] for archives in a format that
+// cannot be extracted. It matches [ErrUnsupportedFormat] with [errors.Is].
+type UnsupportedFormatError struct {
+	Path   string // Path to the archive.
+	Format Format // Detected format of the archive.
+}
+
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("failed to open '%s': %s: %s", e.Path, ErrUnsupportedFormat, e.Format)
+}
+
+// Is reports whether target is [ErrUnsupportedFormat].
+func (e *UnsupportedFormatError) Is(target error) bool {
+	return target == ErrUnsupportedFormat
+}
+
+// signatures maps the magic bytes at the start of a file to its format.
+var signatures = []struct {
+	magic  []byte
+	format Format
+}{
+	{[]byte("PK\x03\x04"), FormatZip},
+	{[]byte("PK\x05\x06"), FormatZip}, // Empty archive
+	{[]byte("PK\x07\x08"), FormatZip}, // Spanned archive
+	{[]byte("PK00"), FormatZip},       // Spanned archive in a single segment
+	{[]byte("\x1f\x8b"), FormatGzip},
+	{[]byte("\xfd7zXZ\x00"), FormatXz},
+	{[]byte("\x28\xb5\x2f\xfd"), FormatZstd},
+	{[]byte("7z\xbc\xaf\x27\x1c"), Format7z},
+}
+
+// tarBlockSize is the size of a tar header block.
+const tarBlockSize = 512
+
+// Detect returns the format of the data in r from its magic bytes. Data in an
+// unrecognized format is reported as [FormatUnknown] without an error.
+//
+// Compressed streams are reported by their compression, so a ".tar.gz" file is
+// [FormatGzip].
+func Detect(r io.ReaderAt) (Format, error) {
+	header := make([]byte, tarBlockSize)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return FormatUnknown, err
+	}
+	header = header[:n]
+
+	for _, s := range signatures {
+		if bytes.HasPrefix(header, s.magic) {
+			return s.format, nil
+		}
+	}
+
+	if len(header) >= 4 && string(header[:3]) == "BZh" && header[3] >= '1' && header[3] <= '9' {
+		return FormatBzip2, nil
+	}
+
+	if isTarHeader(header) {
+		return FormatTar, nil
+	}
+
+	return FormatUnknown, nil
+}
+
+// isTarHeader reports whether block is a tar header block, by its magic or,
+// for old archives without one, by its checksum.
+func isTarHeader(block []byte) bool {
+	if len(block) < tarBlockSize {
+		return false
+	}
+
+	if bytes.HasPrefix(block[257:], []byte("ustar")) {
+		return true
+	}
+
+	field := strings.Trim(string(block[148:156]), " \x00")
+	checksum, err := strconv.ParseInt(field, 8, 64)
+	if err != nil {
+		return false
+	}
+
+	// Some writers sum the bytes as signed values
+	var unsigned, signed int64
+	for i, b := range block {
+		if i >= 148 && i < 156 {
+			b = ' '
+		}
+
+		unsigned += int64(b)
+		signed += int64(int8(b))
+	}
+
+	return checksum == unsigned || checksum == signed
+}
+
+// Open returns an extractor for the archive at path, chosen by the format of
+// its contents rather than its name: an [Unzippy] for zip archives, including
+// self-extracting archives and the final segment of split archives, or an
+// [Untarry] for tar archives, uncompressed or compressed with gzip, zstd or
+// xz. Other formats, including compressed files that are not tar archives,
+// fail with an [UnsupportedFormatError].
+func Open(path string, options *UnzippyOptions) (UnzippyInterface, error) {
+	if path == "" {
+		return nil, ErrEmptyPath
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	format, err := Detect(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Self-extracting archives start with their stub, and the final segment
+	// of a split archive in the middle of the archive, so both are only found
+	// by their end of central directory record
+	if format == FormatUnknown {
+		if info, err := file.Stat(); err == nil {
+			if _, err := readEOCD(file, info.Size()); err == nil {
+				format = FormatZip
+			}
+		}
+	}
+
+	var compression TarCompression
+	switch format {
+	case FormatZip:
+		return NewUnzippy(path, options)
+	case FormatTar:
+		return NewUntarry(path, TarUncompressed, options)
+	case FormatGzip:
+		compression = TarGzip
+	case FormatZstd:
+		compression = TarZstd
+	case FormatXz:
+		compression = TarXz
+	default:
+		return nil, &UnsupportedFormatError{Path: path, Format: format}
+	}
+
+	tarred, err := compressedTar(file, compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open '%s': %w", path, err)
+	}
+
+	if !tarred {
+		return nil, &UnsupportedFormatError{Path: path, Format: format}
+	}
+
+	return NewUntarry(path, compression, options)
+}
+
+// compressedTar reports whether the compressed stream in r holds a tar
+// archive, which may be empty.
+func compressedTar(r io.Reader, compression TarCompression) (bool, error) {
+	decompressor, err := decompressReader(r, compression)
+	if err != nil {
+		return false, err
+	}
+	defer decompressor.Close()
+
+	block := make([]byte, tarBlockSize)
+	if _, err := io.ReadFull(decompressor, block); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	// An empty tar archive is only its zeroed end blocks
+	empty := bytes.Count(block, []byte{0}) == len(block)
+
+	return empty || isTarHeader(block), nil
+}
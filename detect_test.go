@@ -0,0 +1,174 @@
+package zippy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/engmtcdrm/go-zippy/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// tarBlock returns a tar header block for a file named name.
+func tarBlock(t *testing.T, name string, format tar.Format) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Typeflag: tar.TypeReg, Format: format})
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+
+	return buf.Bytes()[:tarBlockSize]
+}
+
+// Tests for [Detect] function.
+func Test_Detect(t *testing.T) {
+	// Old tar archives have no magic, only a checksum
+	v7 := tarBlock(t, "file.txt", tar.FormatUSTAR)
+	copy(v7[257:265], make([]byte, 8))
+	copy(v7[148:156], "        ")
+	var sum int
+	for _, b := range v7 {
+		sum += int(b)
+	}
+	copy(v7[148:156], fmt.Sprintf("%06o\x00 ", sum))
+	corrupt := bytes.Clone(v7)
+	corrupt[0] = 'x'
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format Format
+	}{
+		{"zip", []byte("PK\x03\x04\x14\x00"), FormatZip},
+		{"empty zip", []byte("PK\x05\x06" + string(make([]byte, 18))), FormatZip},
+		{"spanned zip", []byte("PK\x07\x08PK\x03\x04"), FormatZip},
+		{"single segment spanned zip", []byte("PK00PK\x03\x04"), FormatZip},
+		{"gzip", []byte("\x1f\x8b\x08\x00"), FormatGzip},
+		{"bzip2", []byte("BZh91AY&SY"), FormatBzip2},
+		{"xz", []byte("\xfd7zXZ\x00\x00\x04"), FormatXz},
+		{"zstd", []byte("\x28\xb5\x2f\xfd\x04\x00"), FormatZstd},
+		{"7z", []byte("7z\xbc\xaf\x27\x1c\x00\x04"), Format7z},
+		{"ustar", tarBlock(t, "file.txt", tar.FormatUSTAR), FormatTar},
+		{"gnu tar", tarBlock(t, "file.txt", tar.FormatGNU), FormatTar},
+		{"v7 tar", v7, FormatTar},
+		{"bad tar checksum", corrupt, FormatUnknown},
+		{"bzip2 without level", []byte("BZh0"), FormatUnknown},
+		{"text", []byte("hello world"), FormatUnknown},
+		{"empty", nil, FormatUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := Detect(bytes.NewReader(tt.data))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.format, format)
+		})
+	}
+}
+
+// Tests for [Open] function.
+func Test_Open(t *testing.T) {
+	tempDir := t.TempDir()
+	entry := tarTestEntry{&tar.Header{Name: "file.txt", Typeflag: tar.TypeReg}, "hello"}
+
+	zipPath := filepath.Join(tempDir, "archive.bin")
+	_, err := testutils.CreateZipFile(zipPath, 1, 0)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		path        string
+		compression TarCompression
+	}{
+		{name: "tar", path: "archive.tar", compression: TarUncompressed},
+		{name: "gzip", path: "archive.data", compression: TarGzip},
+		{name: "zstd", path: "archive.tar.zst", compression: TarZstd},
+		{name: "xz", path: "archive", compression: TarXz},
+	}
+
+	t.Run("zip", func(t *testing.T) {
+		extractor, err := Open(zipPath, nil)
+		assert.NoError(t, err)
+		assert.IsType(t, &Unzippy{}, extractor)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tt.path)
+			createTarFile(t, path, tt.compression, entry)
+
+			extractor, err := Open(path, nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			untarry := extractor.(*Untarry)
+			assert.Equal(t, tt.compression, untarry.Compression)
+
+			dest := filepath.Join(tempDir, tt.name)
+			_, err = extractor.ExtractTo(dest)
+			assert.NoError(t, err)
+			assert.FileExists(t, filepath.Join(dest, "file.txt"))
+		})
+	}
+
+	t.Run("empty compressed tar", func(t *testing.T) {
+		path := filepath.Join(tempDir, "empty.tgz")
+		createTarFile(t, path, TarGzip)
+
+		extractor, err := Open(path, nil)
+		assert.NoError(t, err)
+		assert.IsType(t, &Untarry{}, extractor)
+	})
+
+	t.Run("unsupported formats", func(t *testing.T) {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, err := gw.Write([]byte("not a tar archive"))
+		assert.NoError(t, err)
+		assert.NoError(t, gw.Close())
+
+		files := []struct {
+			name   string
+			data   []byte
+			format Format
+		}{
+			{"file.gz", buf.Bytes(), FormatGzip},
+			{"file.bz2", []byte("BZh91AY&SY"), FormatBzip2},
+			{"file.7z", []byte("7z\xbc\xaf\x27\x1c\x00\x04"), Format7z},
+			{"file.txt", []byte("hello world"), FormatUnknown},
+		}
+
+		for _, f := range files {
+			path := filepath.Join(tempDir, f.name)
+			assert.NoError(t, os.WriteFile(path, f.data, 0644))
+
+			_, err := Open(path, nil)
+			assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+			var formatErr *UnsupportedFormatError
+			if assert.True(t, errors.As(err, &formatErr), f.name) {
+				assert.Equal(t, f.format, formatErr.Format)
+				assert.Equal(t, path, formatErr.Path)
+				assert.Contains(t, err.Error(), f.format.String())
+			}
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Open(filepath.Join(tempDir, "missing.zip"), nil)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("empty path", func(t *testing.T) {
+		_, err := Open("", nil)
+		assert.ErrorIs(t, err, ErrEmptyPath)
+	})
+}
@@ -0,0 +1,406 @@
+package zippy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTextDiffSize is the largest entry size compared line by line.
+const maxTextDiffSize = 1 << 20
+
+// ChangeKind is the kind of difference found for an entry.
+type ChangeKind uint8
+
+const (
+	ChangeAdded    ChangeKind = iota + 1 // ChangeAdded is an entry only found in the new side.
+	ChangeRemoved                        // ChangeRemoved is an entry only found in the old side.
+	ChangeModified                       // ChangeModified is an entry whose contents differ.
+	ChangeMetadata                       // ChangeMetadata is an entry whose contents are equal but whose mode or modification time differ.
+)
+
+// changeKindNames maps change kinds to their names.
+var changeKindNames = map[ChangeKind]string{
+	ChangeAdded:    "added",
+	ChangeRemoved:  "removed",
+	ChangeModified: "modified",
+	ChangeMetadata: "metadata",
+}
+
+// String returns the name of the change kind.
+func (k ChangeKind) String() string {
+	if name, ok := changeKindNames[k]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("ChangeKind(%d)", uint8(k))
+}
+
+// MarshalText encodes the change kind as its name.
+func (k ChangeKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// FieldChange is a single field that differs between two entries.
+type FieldChange struct {
+	Field string `json:"field"` // Name of the field: crc32, size, mode or modified.
+	Old   string `json:"old"`   // Old value.
+	New   string `json:"new"`   // New value.
+}
+
+// Change is a difference found for a single entry.
+type Change struct {
+	Name     string        `json:"name"`                // Name of the entry.
+	Kind     ChangeKind    `json:"kind"`                // Kind of change.
+	Old      *Entry        `json:"old,omitempty"`       // Old entry. Nil if the entry was added.
+	New      *Entry        `json:"new,omitempty"`       // New entry. Nil if the entry was removed.
+	Fields   []FieldChange `json:"fields,omitempty"`    // Fields that differ, for modified entries.
+	TextDiff string        `json:"text_diff,omitempty"` // Unified diff of the contents, if requested and both sides are text.
+}
+
+// DiffOptions specifies how archives are compared.
+type DiffOptions struct {
+	ModTimes bool // ModTimes specifies whether to compare modification times.
+	TextDiff bool // TextDiff specifies whether to include a line diff of modified text entries. Encrypted entries are not compared line by line.
+}
+
+// DiffReport is the result of comparing two archives, or an archive and a
+// directory.
+type DiffReport struct {
+	Changes []Change `json:"changes"` // Changes holds every entry that differs, sorted by name.
+}
+
+// Equal reports whether no differences were found.
+func (r *DiffReport) Equal() bool {
+	return len(r.Changes) == 0
+}
+
+// Count returns the number of changes of the given kind.
+func (r *DiffReport) Count(kind ChangeKind) int {
+	count := 0
+	for _, change := range r.Changes {
+		if change.Kind == kind {
+			count++
+		}
+	}
+
+	return count
+}
+
+// diffSide is one side of a comparison.
+type diffSide struct {
+	entries  map[string]Entry
+	dirs     map[string]bool                          // Directories implied by the names of the entries.
+	realMode func(entry Entry) bool                   // Reports whether the mode of an entry was recorded rather than made up.
+	open     func(name string) (io.ReadCloser, error) // Opens the contents of an entry.
+}
+
+// addImpliedDirs records the parent directories of every entry.
+func (s *diffSide) addImpliedDirs() {
+	s.dirs = make(map[string]bool)
+
+	for name := range s.entries {
+		for i, c := range strings.TrimSuffix(name, "/") {
+			if c == '/' {
+				s.dirs[name[:i+1]] = true
+			}
+		}
+	}
+}
+
+// archiveSide reads the entries of an open zip archive.
+func archiveSide(zReader *zip.ReadCloser) *diffSide {
+	files := make(map[string]*zip.File, len(zReader.File))
+	side := &diffSide{entries: make(map[string]Entry, len(zReader.File))}
+
+	for _, file := range zReader.File {
+		files[file.Name] = file
+		side.entries[file.Name] = newEntry(file)
+	}
+
+	side.realMode = func(entry Entry) bool {
+		return entry.UnixMode != 0
+	}
+
+	side.open = func(name string) (io.ReadCloser, error) {
+		return files[name].Open()
+	}
+
+	side.addImpliedDirs()
+
+	return side
+}
+
+// dirSide reads the files of a directory tree, described like the entries
+// zippy would write for them.
+func dirSide(root string) (*diffSide, error) {
+	side := &diffSide{entries: make(map[string]Entry)}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if path == root {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		entry := Entry{
+			Name:      filepath.ToSlash(rel),
+			Modified:  info.ModTime(),
+			Mode:      info.Mode(),
+			IsDir:     info.IsDir(),
+			IsSymlink: info.Mode()&fs.ModeSymlink != 0,
+		}
+
+		switch {
+		case entry.IsDir:
+			entry.Name += "/"
+		case entry.IsSymlink:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			target = filepath.ToSlash(target)
+			entry.UncompressedSize = uint64(len(target))
+			entry.CRC32 = crc32.ChecksumIEEE([]byte(target))
+		default:
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			hash := crc32.NewIEEE()
+			size, err := io.Copy(hash, file)
+			if err != nil {
+				return err
+			}
+
+			entry.UncompressedSize = uint64(size)
+			entry.CRC32 = hash.Sum32()
+		}
+
+		side.entries[entry.Name] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	side.realMode = func(entry Entry) bool {
+		return true
+	}
+
+	side.open = func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(root, filepath.FromSlash(name)))
+	}
+
+	side.addImpliedDirs()
+
+	return side, nil
+}
+
+// Diff compares two zip archives by entry name, CRC32 checksum, size and
+// mode, and optionally modification time.
+func Diff(oldZip, newZip string, options *DiffOptions) (*DiffReport, error) {
+	oldReader, err := zip.OpenReader(oldZip)
+	if err != nil {
+		return nil, err
+	}
+	defer oldReader.Close()
+
+	newReader, err := zip.OpenReader(newZip)
+	if err != nil {
+		return nil, err
+	}
+	defer newReader.Close()
+
+	return diffSides(archiveSide(oldReader), archiveSide(newReader), options)
+}
+
+// DiffDir compares a zip archive with a directory, such as one the archive was
+// extracted to. The archive is the old side and the directory the new side.
+func DiffDir(zipFile, dir string, options *DiffOptions) (*DiffReport, error) {
+	zReader, err := zip.OpenReader(zipFile)
+	if err != nil {
+		return nil, err
+	}
+	defer zReader.Close()
+
+	newSide, err := dirSide(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffSides(archiveSide(zReader), newSide, options)
+}
+
+// diffSides compares the entries of two sides.
+func diffSides(oldSide, newSide *diffSide, options *DiffOptions) (*DiffReport, error) {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+
+	report := &DiffReport{Changes: []Change{}}
+
+	for name, oldEntry := range oldSide.entries {
+		newEntry, ok := newSide.entries[name]
+		if !ok {
+			// Directories without entries of their own are not removed
+			if !oldEntry.IsDir || !newSide.dirs[name] {
+				report.Changes = append(report.Changes, Change{Name: name, Kind: ChangeRemoved, Old: &oldEntry})
+			}
+			continue
+		}
+
+		change, err := diffEntries(oldSide, newSide, oldEntry, newEntry, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if change != nil {
+			report.Changes = append(report.Changes, *change)
+		}
+	}
+
+	for name, newEntry := range newSide.entries {
+		if _, ok := oldSide.entries[name]; !ok && (!newEntry.IsDir || !oldSide.dirs[name]) {
+			report.Changes = append(report.Changes, Change{Name: name, Kind: ChangeAdded, New: &newEntry})
+		}
+	}
+
+	sort.Slice(report.Changes, func(i, j int) bool {
+		return report.Changes[i].Name < report.Changes[j].Name
+	})
+
+	return report, nil
+}
+
+// diffEntries compares two entries with the same name, returning nil if they
+// are equal.
+func diffEntries(oldSide, newSide *diffSide, oldEntry, newEntry Entry, options *DiffOptions) (*Change, error) {
+	change := &Change{Name: oldEntry.Name, Kind: ChangeMetadata, Old: &oldEntry, New: &newEntry}
+
+	if !oldEntry.IsDir && !oldEntry.Encrypted && !newEntry.Encrypted {
+		if oldEntry.CRC32 != newEntry.CRC32 {
+			change.Kind = ChangeModified
+			change.Fields = append(change.Fields, FieldChange{"crc32", fmt.Sprintf("%08x", oldEntry.CRC32), fmt.Sprintf("%08x", newEntry.CRC32)})
+		}
+	}
+
+	if oldEntry.UncompressedSize != newEntry.UncompressedSize {
+		change.Kind = ChangeModified
+		change.Fields = append(change.Fields, FieldChange{"size", fmt.Sprint(oldEntry.UncompressedSize), fmt.Sprint(newEntry.UncompressedSize)})
+	}
+
+	if oldSide.realMode(oldEntry) && newSide.realMode(newEntry) && oldEntry.Mode != newEntry.Mode {
+		change.Fields = append(change.Fields, FieldChange{"mode", oldEntry.Mode.String(), newEntry.Mode.String()})
+	}
+
+	// Zip modification times are only precise to two seconds
+	if options.ModTimes && oldEntry.Modified.Sub(newEntry.Modified).Abs() >= 2*time.Second {
+		change.Fields = append(change.Fields, FieldChange{"modified", oldEntry.Modified.Format(time.RFC3339), newEntry.Modified.Format(time.RFC3339)})
+	}
+
+	if len(change.Fields) == 0 {
+		return nil, nil
+	}
+
+	if options.TextDiff && change.Kind == ChangeModified {
+		textDiff, err := diffText(oldSide, newSide, oldEntry, newEntry)
+		if err != nil {
+			return nil, err
+		}
+
+		change.TextDiff = textDiff
+	}
+
+	return change, nil
+}
+
+// diffText returns a unified diff of two regular entries, or "" if either is
+// encrypted, too large or not text.
+func diffText(oldSide, newSide *diffSide, oldEntry, newEntry Entry) (string, error) {
+	for _, entry := range []Entry{oldEntry, newEntry} {
+		if entry.IsSymlink || entry.Encrypted || entry.UncompressedSize > maxTextDiffSize {
+			return "", nil
+		}
+	}
+
+	oldData, err := readDiffEntry(oldSide, oldEntry.Name)
+	if err != nil {
+		return "", err
+	}
+
+	newData, err := readDiffEntry(newSide, newEntry.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if !isText(oldData) || !isText(newData) {
+		return "", nil
+	}
+
+	return unifiedDiff("a/"+oldEntry.Name, "b/"+newEntry.Name, string(oldData), string(newData)), nil
+}
+
+// readDiffEntry reads the contents of an entry, up to the text diff limit.
+func readDiffEntry(side *diffSide, name string) ([]byte, error) {
+	reader, err := side.open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(io.LimitReader(reader, maxTextDiffSize))
+}
+
+// WriteText writes the report in a human readable form, one change per line
+// followed by the fields that differ and any text diff.
+func (r *DiffReport) WriteText(w io.Writer) error {
+	var lines []string
+
+	for _, change := range r.Changes {
+		lines = append(lines, fmt.Sprintf("%-9s %s", change.Kind, change.Name))
+
+		for _, field := range change.Fields {
+			lines = append(lines, fmt.Sprintf("          %s: %s -> %s", field.Field, field.Old, field.New))
+		}
+
+		if change.TextDiff != "" {
+			lines = append(lines, strings.TrimSuffix(change.TextDiff, "\n"))
+		}
+	}
+
+	lines = append(lines, fmt.Sprintf("%d added, %d removed, %d modified, %d metadata only",
+		r.Count(ChangeAdded), r.Count(ChangeRemoved), r.Count(ChangeModified), r.Count(ChangeMetadata)))
+
+	return writeLines(w, lines)
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *DiffReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
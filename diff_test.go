@@ -0,0 +1,176 @@
+package zippy
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [Diff] function.
+func Test_Diff(t *testing.T) {
+	tempDir := t.TempDir()
+	oldZip := filepath.Join(tempDir, "old.zip")
+	newZip := filepath.Join(tempDir, "new.zip")
+
+	createTestZip(t, oldZip, [][2]string{
+		{"bin/", ""},
+		{"bin/tool", "tool v1"},
+		{"README", "line 1\nline 2\nline 3\n"},
+		{"removed.txt", "gone"},
+		{"same.txt", "same"},
+	})
+	createTestZip(t, newZip, [][2]string{
+		{"bin/tool", "tool v2"},
+		{"README", "line 1\nline two\nline 3\n"},
+		{"added.txt", "new"},
+		{"same.txt", "same"},
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		report, err := Diff(oldZip, newZip, nil)
+		assert.NoError(t, err)
+		assert.False(t, report.Equal())
+
+		kinds := make(map[string]ChangeKind)
+		for _, change := range report.Changes {
+			kinds[change.Name] = change.Kind
+		}
+
+		// bin/ has no entry in the new archive but is implied by bin/tool
+		assert.Equal(t, map[string]ChangeKind{
+			"README":      ChangeModified,
+			"added.txt":   ChangeAdded,
+			"bin/tool":    ChangeModified,
+			"removed.txt": ChangeRemoved,
+		}, kinds)
+		assert.Equal(t, "README", report.Changes[0].Name)
+		assert.Empty(t, report.Changes[0].TextDiff)
+	})
+
+	t.Run("text diff", func(t *testing.T) {
+		report, err := Diff(oldZip, newZip, &DiffOptions{TextDiff: true})
+		assert.NoError(t, err)
+		assert.Equal(t, "--- a/README\n+++ b/README\n@@ -1,3 +1,3 @@\n line 1\n-line 2\n+line two\n line 3\n", report.Changes[0].TextDiff)
+
+		var buf bytes.Buffer
+		assert.NoError(t, report.WriteText(&buf))
+		assert.Contains(t, buf.String(), "modified  README\n          crc32: ")
+		assert.Contains(t, buf.String(), "-line 2\n+line two\n")
+		assert.Contains(t, buf.String(), "1 added, 1 removed, 2 modified, 0 metadata only\n")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		report, err := Diff(oldZip, newZip, nil)
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		assert.NoError(t, report.WriteJSON(&buf))
+
+		var decoded struct {
+			Changes []struct {
+				Name string `json:"name"`
+				Kind string `json:"kind"`
+			} `json:"changes"`
+		}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		assert.Len(t, decoded.Changes, 4)
+		assert.Equal(t, "added", decoded.Changes[1].Kind)
+	})
+
+	t.Run("equal", func(t *testing.T) {
+		report, err := Diff(oldZip, oldZip, &DiffOptions{ModTimes: true})
+		assert.NoError(t, err)
+		assert.True(t, report.Equal())
+	})
+
+	t.Run("missing archive", func(t *testing.T) {
+		_, err := Diff(oldZip, filepath.Join(tempDir, "missing.zip"), nil)
+		assert.Error(t, err)
+	})
+}
+
+// Tests for [DiffDir] function.
+func Test_DiffDir(t *testing.T) {
+	tempDir := t.TempDir()
+	srcDir := filepath.Join(tempDir, "src")
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "sub"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), []byte("a"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "sub", "b.txt"), []byte("b"), 0644))
+
+	zipFilePath := filepath.Join(tempDir, testZipFileName)
+	z := NewZippy(zipFilePath)
+	z.Junk = true
+	assert.NoError(t, z.Add(filepath.Join(srcDir, "a.txt")))
+
+	extract := func(t *testing.T) string {
+		dest := filepath.Join(t.TempDir(), "output")
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+		_, err = u.ExtractTo(dest)
+		assert.NoError(t, err)
+
+		return dest
+	}
+
+	t.Run("intact extraction", func(t *testing.T) {
+		report, err := DiffDir(zipFilePath, extract(t), &DiffOptions{ModTimes: true})
+		assert.NoError(t, err)
+		assert.True(t, report.Equal(), report.Changes)
+	})
+
+	t.Run("modified extraction", func(t *testing.T) {
+		dest := extract(t)
+		assert.NoError(t, os.WriteFile(filepath.Join(dest, "a.txt"), []byte("changed"), 0644))
+		assert.NoError(t, os.WriteFile(filepath.Join(dest, "extra.txt"), []byte("extra"), 0644))
+
+		report, err := DiffDir(zipFilePath, dest, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Count(ChangeModified))
+		assert.Equal(t, 1, report.Count(ChangeAdded))
+	})
+
+	t.Run("metadata only", func(t *testing.T) {
+		dest := extract(t)
+		assert.NoError(t, os.Chmod(filepath.Join(dest, "a.txt"), 0600))
+		assert.NoError(t, os.Chtimes(filepath.Join(dest, "a.txt"), time.Now(), time.Now().Add(time.Hour)))
+
+		report, err := DiffDir(zipFilePath, dest, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Count(ChangeMetadata))
+		assert.Len(t, report.Changes[0].Fields, 1)
+
+		report, err = DiffDir(zipFilePath, dest, &DiffOptions{ModTimes: true})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Count(ChangeMetadata))
+		assert.Len(t, report.Changes[0].Fields, 2)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := DiffDir(zipFilePath, filepath.Join(tempDir, "missing"), nil)
+		assert.Error(t, err)
+	})
+}
+
+// Tests for [unifiedDiff] function.
+func Test_unifiedDiff(t *testing.T) {
+	t.Run("equal", func(t *testing.T) {
+		assert.Empty(t, unifiedDiff("a", "b", "x\ny\n", "x\ny\n"))
+	})
+
+	t.Run("from empty", func(t *testing.T) {
+		assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n", unifiedDiff("a", "b", "", "x\ny\n"))
+	})
+
+	t.Run("separate hunks", func(t *testing.T) {
+		oldText := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+		newText := "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n"
+
+		assert.Equal(t, "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -10,3 +10,4 @@\n 10\n 11\n 12\n+13\n", unifiedDiff("a", "b", oldText, newText))
+	})
+}
@@ -0,0 +1,120 @@
+package zippy
+
+import (
+	"archive/zip"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// DirEntryPolicy controls how directory entries are written when entries are
+// deleted from or copied out of an archive.
+type DirEntryPolicy uint8
+
+const (
+	DirEntriesKeep       DirEntryPolicy = iota // DirEntriesKeep keeps directory entries, even when none of their contents are left.
+	DirEntriesPrune                            // DirEntriesPrune removes directory entries whose contents are all left out.
+	DirEntriesSynthesize                       // DirEntriesSynthesize keeps directory entries and adds the missing entries of parent directories.
+	DirEntriesOmit                             // DirEntriesOmit writes no directory entries, like zip -D.
+)
+
+// writeKept writes the entries of files named in keep, in the order of files,
+// applying the directory entry policy. copy writes a single entry. When
+// deleting, the entries left out are recorded as deleted in a dry run plan.
+func (z *Zippy) writeKept(files []*zip.File, keep map[string]bool, deleting bool, copy func(file *zip.File) error) error {
+	emptied := emptiedDirs(files, keep)
+	synthesized := make(map[string]bool)
+
+	for _, file := range files {
+		left := !keep[file.Name]
+		if file.FileInfo().IsDir() {
+			left = left || z.DirEntries == DirEntriesOmit || (z.DirEntries == DirEntriesPrune && emptied[file.Name])
+		}
+
+		if left {
+			if deleting && z.plan != nil {
+				z.plan.add(PlanDelete, file.Name, "", false)
+			}
+
+			continue
+		}
+
+		if z.DirEntries == DirEntriesSynthesize {
+			if err := z.synthesizeDirs(file, keep, synthesized); err != nil {
+				return err
+			}
+		}
+
+		if err := copy(file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emptiedDirs returns the directory entries of files that have contents, but
+// whose contents are all left out of keep or are emptied directories.
+func emptiedDirs(files []*zip.File, keep map[string]bool) map[string]bool {
+	var dirs []string
+	for _, file := range files {
+		if file.FileInfo().IsDir() && strings.HasSuffix(file.Name, "/") {
+			dirs = append(dirs, file.Name)
+		}
+	}
+
+	// Nested directories are decided before their parents
+	sort.Slice(dirs, func(i, j int) bool {
+		return len(dirs[i]) > len(dirs[j])
+	})
+
+	emptied := make(map[string]bool)
+	for _, dir := range dirs {
+		hasContents, keptContents := false, false
+
+		for _, file := range files {
+			if file.Name == dir || !strings.HasPrefix(file.Name, dir) {
+				continue
+			}
+
+			hasContents = true
+			if keep[file.Name] && !emptied[file.Name] {
+				keptContents = true
+				break
+			}
+		}
+
+		emptied[dir] = hasContents && !keptContents
+	}
+
+	return emptied
+}
+
+// synthesizeDirs writes the entries of the parent directories of file that
+// are neither kept nor written yet, outermost first.
+func (z *Zippy) synthesizeDirs(file *zip.File, keep, synthesized map[string]bool) error {
+	parts := strings.Split(strings.TrimSuffix(file.Name, "/"), "/")
+
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/") + "/"
+		if keep[dir] || synthesized[dir] {
+			continue
+		}
+
+		synthesized[dir] = true
+
+		if z.plan != nil {
+			z.plan.add(PlanAdd, dir, "", false)
+			continue
+		}
+
+		header := &zip.FileHeader{Name: dir, Method: zip.Store, Modified: file.Modified}
+		header.SetMode(fs.ModeDir | 0755)
+
+		if _, err := z.zWriter.CreateHeader(header); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package zippy
+
+import (
+	"archive/zip"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [Zippy.DirEntries] option.
+func Test_Zippy_DirEntries(t *testing.T) {
+	// Archives with and without explicit directory entries
+	styles := map[string][][2]string{
+		"explicit": {
+			{"dir/", ""},
+			{"dir/sub/", ""},
+			{"dir/sub/a.txt", "alpha"},
+			{"dir/b.txt", "bravo"},
+			{"empty/", ""},
+			{"c.txt", "charlie"},
+		},
+		"implicit": {
+			{"dir/sub/a.txt", "alpha"},
+			{"dir/b.txt", "bravo"},
+			{"c.txt", "charlie"},
+		},
+	}
+
+	modes := []struct {
+		name   string
+		policy DirEntryPolicy
+	}{
+		{"keep", DirEntriesKeep},
+		{"prune", DirEntriesPrune},
+		{"synthesize", DirEntriesSynthesize},
+		{"omit", DirEntriesOmit},
+	}
+
+	tests := []struct {
+		name string
+		run  func(z *Zippy, dest string) error
+		want map[string]map[DirEntryPolicy][]string // Entries written for each style and mode.
+	}{
+		{
+			name: "delete",
+			run: func(z *Zippy, dest string) error {
+				return z.Delete("dir/sub/a.txt")
+			},
+			want: map[string]map[DirEntryPolicy][]string{
+				"explicit": {
+					DirEntriesKeep:       {"c.txt", "dir/", "dir/b.txt", "dir/sub/", "empty/"},
+					DirEntriesPrune:      {"c.txt", "dir/", "dir/b.txt", "empty/"},
+					DirEntriesSynthesize: {"c.txt", "dir/", "dir/b.txt", "dir/sub/", "empty/"},
+					DirEntriesOmit:       {"c.txt", "dir/b.txt"},
+				},
+				"implicit": {
+					DirEntriesKeep:       {"c.txt", "dir/b.txt"},
+					DirEntriesPrune:      {"c.txt", "dir/b.txt"},
+					DirEntriesSynthesize: {"c.txt", "dir/", "dir/b.txt"},
+					DirEntriesOmit:       {"c.txt", "dir/b.txt"},
+				},
+			},
+		},
+		{
+			name: "copy",
+			run: func(z *Zippy, dest string) error {
+				return z.Copy(dest, "dir/sub/*")
+			},
+			want: map[string]map[DirEntryPolicy][]string{
+				"explicit": {
+					DirEntriesKeep:       {"dir/", "dir/sub/", "dir/sub/a.txt"},
+					DirEntriesPrune:      {"dir/", "dir/sub/", "dir/sub/a.txt"},
+					DirEntriesSynthesize: {"dir/", "dir/sub/", "dir/sub/a.txt"},
+					DirEntriesOmit:       {"dir/sub/a.txt"},
+				},
+				"implicit": {
+					DirEntriesKeep:       {"dir/sub/a.txt"},
+					DirEntriesPrune:      {"dir/sub/a.txt"},
+					DirEntriesSynthesize: {"dir/", "dir/sub/", "dir/sub/a.txt"},
+					DirEntriesOmit:       {"dir/sub/a.txt"},
+				},
+			},
+		},
+		{
+			name: "copy emptied directory",
+			run: func(z *Zippy, dest string) error {
+				return z.Copy(dest, "dir/", "c.txt")
+			},
+			want: map[string]map[DirEntryPolicy][]string{
+				"explicit": {
+					DirEntriesKeep:       {"c.txt", "dir/"},
+					DirEntriesPrune:      {"c.txt"},
+					DirEntriesSynthesize: {"c.txt", "dir/"},
+					DirEntriesOmit:       {"c.txt"},
+				},
+				"implicit": {
+					DirEntriesKeep:       {"c.txt"},
+					DirEntriesPrune:      {"c.txt"},
+					DirEntriesSynthesize: {"c.txt"},
+					DirEntriesOmit:       {"c.txt"},
+				},
+			},
+		},
+		{
+			name: "copy all",
+			run: func(z *Zippy, dest string) error {
+				return z.Copy(dest)
+			},
+			want: map[string]map[DirEntryPolicy][]string{
+				"explicit": {
+					DirEntriesKeep:       {"c.txt", "dir/", "dir/b.txt", "dir/sub/", "dir/sub/a.txt", "empty/"},
+					DirEntriesPrune:      {"c.txt", "dir/", "dir/b.txt", "dir/sub/", "dir/sub/a.txt", "empty/"},
+					DirEntriesSynthesize: {"c.txt", "dir/", "dir/b.txt", "dir/sub/", "dir/sub/a.txt", "empty/"},
+					DirEntriesOmit:       {"c.txt", "dir/b.txt", "dir/sub/a.txt"},
+				},
+				"implicit": {
+					DirEntriesKeep:       {"c.txt", "dir/b.txt", "dir/sub/a.txt"},
+					DirEntriesPrune:      {"c.txt", "dir/b.txt", "dir/sub/a.txt"},
+					DirEntriesSynthesize: {"c.txt", "dir/", "dir/b.txt", "dir/sub/", "dir/sub/a.txt"},
+					DirEntriesOmit:       {"c.txt", "dir/b.txt", "dir/sub/a.txt"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for style, entries := range styles {
+			for _, mode := range modes {
+				t.Run(tt.name+"/"+style+"/"+mode.name, func(t *testing.T) {
+					tempDir := t.TempDir()
+					zipPath := filepath.Join(tempDir, "archive.zip")
+					dest := filepath.Join(tempDir, "copy.zip")
+					createTestZip(t, zipPath, entries)
+
+					z := NewZippy(zipPath)
+					z.DirEntries = mode.policy
+					assert.NoError(t, tt.run(z, dest))
+
+					written := dest
+					if tt.name == "delete" {
+						written = zipPath
+					}
+
+					contents := readTestZip(t, written, false)
+					names := make([]string, 0, len(contents))
+					for name := range contents {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+
+					assert.Equal(t, tt.want[style][mode.policy], names)
+				})
+			}
+		}
+	}
+
+	t.Run("synthesized entries", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipPath := filepath.Join(tempDir, "archive.zip")
+		dest := filepath.Join(tempDir, "copy.zip")
+		createTestZip(t, zipPath, styles["implicit"])
+
+		z := NewZippy(zipPath)
+		z.DirEntries = DirEntriesSynthesize
+		assert.NoError(t, z.Copy(dest))
+
+		zReader, err := zip.OpenReader(dest)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer zReader.Close()
+
+		// Parents come before their contents
+		assert.Equal(t, []string{"dir/", "dir/sub/", "dir/sub/a.txt", "dir/b.txt", "c.txt"}, zipFileNames(zReader.File))
+		assert.True(t, zReader.File[0].FileInfo().IsDir())
+		assert.True(t, zReader.File[2].Modified.Equal(zReader.File[0].Modified))
+
+		u, err := NewUnzippy(dest, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		if assert.NoError(t, err) {
+			assert.True(t, report.OK(), "%v", report.Failed())
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		zipPath := filepath.Join(t.TempDir(), "archive.zip")
+		createTestZip(t, zipPath, styles["implicit"])
+
+		z := NewZippy(zipPath)
+		z.DirEntries = DirEntriesSynthesize
+		z.DryRun = true
+
+		assert.NoError(t, z.Delete("c.txt"))
+		if assert.NotNil(t, z.Plan) {
+			assert.Equal(t, []PlanStep{
+				{Action: PlanAdd, Entry: "dir/"},
+				{Action: PlanAdd, Entry: "dir/sub/"},
+				{Action: PlanKeep, Entry: "dir/sub/a.txt"},
+				{Action: PlanKeep, Entry: "dir/b.txt"},
+				{Action: PlanDelete, Entry: "c.txt"},
+			}, z.Plan.Steps)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package zippy
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// NameEncoding is the encoding of entry names stored without the UTF-8 flag.
+// Names with an Info-ZIP Unicode path extra field are read from the field
+// whatever the encoding.
+type NameEncoding uint8
+
+const (
+	EncodingAuto     NameEncoding = iota // EncodingAuto keeps valid UTF-8 names and guesses the code page of the others.
+	EncodingUTF8                         // EncodingUTF8 keeps names as they are stored.
+	EncodingCP437                        // EncodingCP437 decodes names from IBM PC code page 437, the original zip encoding.
+	EncodingShiftJIS                     // EncodingShiftJIS decodes names from Shift-JIS, used by Japanese Windows.
+	EncodingGBK                          // EncodingGBK decodes names from GBK, used by Simplified Chinese Windows.
+)
+
+// decoder returns the decoder of the code page, or nil if names are kept as
+// they are stored.
+func (e NameEncoding) decoder() *encoding.Decoder {
+	switch e {
+	case EncodingCP437:
+		return charmap.CodePage437.NewDecoder()
+	case EncodingShiftJIS:
+		return japanese.ShiftJIS.NewDecoder()
+	case EncodingGBK:
+		return simplifiedchinese.GBK.NewDecoder()
+	}
+
+	return nil
+}
+
+// decodeNames replaces the names of files stored without the UTF-8 flag with
+// their decoded names.
+func decodeNames(files []*zip.File, encoding NameEncoding) {
+	for _, file := range files {
+		file.Name = decodeName(file, encoding)
+	}
+}
+
+// decodeName returns the name of a file, decoded from the Unicode path extra
+// field or the encoding if it is stored without the UTF-8 flag.
+func decodeName(file *zip.File, encoding NameEncoding) string {
+	if file.Flags&0x800 != 0 {
+		return file.Name
+	}
+
+	if name, ok := unicodePath(file.Name, file.Extra); ok {
+		return name
+	}
+
+	if encoding == EncodingAuto {
+		if utf8.ValidString(file.Name) {
+			return file.Name
+		}
+
+		encoding = detectEncoding(file.Name)
+	}
+
+	decoder := encoding.decoder()
+	if decoder == nil {
+		return file.Name
+	}
+
+	name, err := decoder.String(file.Name)
+	if err != nil {
+		return file.Name
+	}
+
+	return name
+}
+
+// unicodePath returns the name stored in the Unicode path extra field of a
+// file. The field is ignored if it was written for another name, as tools
+// renaming the entry may have left it behind.
+func unicodePath(name string, extra []byte) (string, bool) {
+	data, ok := findExtra(extra, unicodePathExtraID)
+	if !ok || len(data) < 5 || data[0] != 1 {
+		return "", false
+	}
+
+	if binary.LittleEndian.Uint32(data[1:5]) != crc32.ChecksumIEEE([]byte(name)) || !utf8.Valid(data[5:]) {
+		return "", false
+	}
+
+	return string(data[5:]), true
+}
+
+// detectEncoding guesses the code page of a name that is not valid UTF-8.
+// Names made only of GB2312 characters are read as GBK, names that decode as
+// Shift-JIS without half-width katakana as Shift-JIS, and the others as
+// CP437, which decodes any byte.
+func detectEncoding(name string) NameEncoding {
+	if isGB2312(name) {
+		return EncodingGBK
+	}
+
+	decoded, err := japanese.ShiftJIS.NewDecoder().String(name)
+	if err == nil && !strings.ContainsFunc(decoded, func(r rune) bool {
+		return r == utf8.RuneError || (r >= 0xff61 && r <= 0xff9f)
+	}) {
+		return EncodingShiftJIS
+	}
+
+	return EncodingCP437
+}
+
+// isGB2312 reports whether every byte of a name outside of ASCII is part of a
+// two byte GB2312 character, the common subset of GBK.
+func isGB2312(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < utf8.RuneSelf {
+			continue
+		}
+
+		if i+1 >= len(name) || name[i] < 0xa1 || name[i] > 0xf7 || name[i+1] < 0xa1 || name[i+1] > 0xfe {
+			return false
+		}
+
+		i++
+	}
+
+	return true
+}
+
+// setNameEncoding marks a header whose name is not plain ASCII as UTF-8, so
+// other tools read it correctly. When its comment is not valid UTF-8 the flag
+// would misread the comment, so the Unicode path extra field is added instead.
+// Names that are not valid UTF-8 are left as they are.
+func setNameEncoding(header *zip.FileHeader) {
+	if !requiresUTF8(header.Name) {
+		return
+	}
+
+	header.Extra = stripExtra(header.Extra, unicodePathExtraID)
+
+	if utf8.ValidString(header.Comment) {
+		header.NonUTF8 = false
+		header.Flags |= 0x800
+		return
+	}
+
+	data := []byte{1} // Version
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE([]byte(header.Name)))
+	data = append(data, header.Name...)
+
+	header.NonUTF8 = true
+	header.Flags &^= 0x800
+	header.Extra = appendExtra(header.Extra, unicodePathExtraID, data)
+}
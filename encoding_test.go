@@ -0,0 +1,203 @@
+package zippy
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// encodeName encodes a name to a legacy code page.
+func encodeName(t *testing.T, name string, encoding NameEncoding) string {
+	t.Helper()
+
+	encoders := map[NameEncoding]func() (string, error){
+		EncodingCP437:    func() (string, error) { return charmap.CodePage437.NewEncoder().String(name) },
+		EncodingShiftJIS: func() (string, error) { return japanese.ShiftJIS.NewEncoder().String(name) },
+		EncodingGBK:      func() (string, error) { return simplifiedchinese.GBK.NewEncoder().String(name) },
+	}
+
+	encoded, err := encoders[encoding]()
+	assert.NoError(t, err)
+
+	return encoded
+}
+
+// unicodePathExtra returns a Unicode path extra field for a stored name.
+func unicodePathExtra(stored, name string) []byte {
+	data := []byte{1}
+	data = binary.LittleEndian.AppendUint32(data, crc32.ChecksumIEEE([]byte(stored)))
+	data = append(data, name...)
+
+	return appendExtra(nil, unicodePathExtraID, data)
+}
+
+// Tests for [decodeName] function.
+func Test_decodeName(t *testing.T) {
+	cp437 := encodeName(t, "café.txt", EncodingCP437)
+	shiftJIS := encodeName(t, "日本語のファイル.txt", EncodingShiftJIS)
+	gbk := encodeName(t, "中文文件.txt", EncodingGBK)
+
+	tests := []struct {
+		name     string
+		file     *zip.File
+		encoding NameEncoding
+		want     string
+	}{
+		{
+			name: "auto shift-jis",
+			file: &zip.File{FileHeader: zip.FileHeader{Name: shiftJIS}},
+			want: "日本語のファイル.txt",
+		},
+		{
+			name: "auto gbk",
+			file: &zip.File{FileHeader: zip.FileHeader{Name: gbk}},
+			want: "中文文件.txt",
+		},
+		{
+			name: "auto cp437",
+			file: &zip.File{FileHeader: zip.FileHeader{Name: cp437}},
+			want: "café.txt",
+		},
+		{
+			name: "auto valid utf-8",
+			file: &zip.File{FileHeader: zip.FileHeader{Name: "naïve.txt"}},
+			want: "naïve.txt",
+		},
+		{
+			name:     "explicit gbk",
+			file:     &zip.File{FileHeader: zip.FileHeader{Name: gbk}},
+			encoding: EncodingGBK,
+			want:     "中文文件.txt",
+		},
+		{
+			name:     "explicit cp437",
+			file:     &zip.File{FileHeader: zip.FileHeader{Name: "\x8e\x99.txt"}},
+			encoding: EncodingCP437,
+			want:     "ÄÖ.txt",
+		},
+		{
+			name:     "utf-8 keeps stored name",
+			file:     &zip.File{FileHeader: zip.FileHeader{Name: cp437}},
+			encoding: EncodingUTF8,
+			want:     cp437,
+		},
+		{
+			name:     "utf-8 flag",
+			file:     &zip.File{FileHeader: zip.FileHeader{Name: "日本.txt", Flags: 0x800}},
+			encoding: EncodingShiftJIS,
+			want:     "日本.txt",
+		},
+		{
+			name:     "unicode path",
+			file:     &zip.File{FileHeader: zip.FileHeader{Name: cp437, Extra: unicodePathExtra(cp437, "カフェ.txt")}},
+			encoding: EncodingCP437,
+			want:     "カフェ.txt",
+		},
+		{
+			name: "stale unicode path",
+			file: &zip.File{FileHeader: zip.FileHeader{Name: cp437, Extra: unicodePathExtra("other.txt", "カフェ.txt")}},
+			want: "café.txt",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, decodeName(tt.file, tt.encoding))
+		})
+	}
+}
+
+// Tests for [setNameEncoding] function.
+func Test_setNameEncoding(t *testing.T) {
+	t.Run("ascii", func(t *testing.T) {
+		header := &zip.FileHeader{Name: "plain.txt"}
+		setNameEncoding(header)
+		assert.Zero(t, header.Flags&0x800)
+		assert.Empty(t, header.Extra)
+	})
+
+	t.Run("utf-8 flag", func(t *testing.T) {
+		header := &zip.FileHeader{Name: "日本語.txt", NonUTF8: true}
+		setNameEncoding(header)
+		assert.NotZero(t, header.Flags&0x800)
+		assert.False(t, header.NonUTF8)
+	})
+
+	t.Run("unicode path", func(t *testing.T) {
+		header := &zip.FileHeader{Name: "日本語.txt", Comment: "\xff"}
+		setNameEncoding(header)
+		assert.Zero(t, header.Flags&0x800)
+
+		name, ok := unicodePath(header.Name, header.Extra)
+		assert.True(t, ok)
+		assert.Equal(t, "日本語.txt", name)
+	})
+
+	t.Run("invalid utf-8", func(t *testing.T) {
+		header := &zip.FileHeader{Name: "\x93\xfa.txt"}
+		setNameEncoding(header)
+		assert.Zero(t, header.Flags&0x800)
+		assert.Empty(t, header.Extra)
+	})
+
+	t.Run("add", func(t *testing.T) {
+		tempDir := t.TempDir()
+		path := filepath.Join(tempDir, "日本語.txt")
+		assert.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+
+		z := NewZippy(filepath.Join(tempDir, "names.zip"))
+		z.Junk = true
+		assert.NoError(t, z.Add(path))
+
+		zReader, err := zip.OpenReader(z.Path)
+		if assert.NoError(t, err) {
+			defer zReader.Close()
+
+			assert.Equal(t, "日本語.txt", zReader.File[0].Name)
+			assert.NotZero(t, zReader.File[0].Flags&0x800)
+		}
+	})
+}
+
+// Tests for [UnzippyOptions.Encoding] option.
+func Test_Unzippy_Encoding(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "legacy.zip")
+	stored := encodeName(t, "報告書.txt", EncodingShiftJIS)
+	createRawZip(t, zipPath, &zip.FileHeader{Name: stored, Method: zip.Store, CRC32: crc32.ChecksumIEEE([]byte("report"))}, "report")
+
+	t.Run("auto", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		dest := filepath.Join(tempDir, "auto")
+		files, err := u.ExtractFilesTo(dest, "報告書.*")
+		assert.NoError(t, err)
+		if assert.Len(t, files, 1) {
+			assert.Equal(t, "報告書.txt", files[0].Name)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dest, "報告書.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "report", string(data))
+	})
+
+	t.Run("utf-8", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{Encoding: EncodingUTF8, DryRun: true})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractTo(filepath.Join(tempDir, "raw"))
+		assert.NoError(t, err)
+		if assert.Len(t, files, 1) {
+			assert.Equal(t, stored, files[0].Name)
+		}
+	})
+}
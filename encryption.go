@@ -0,0 +1,536 @@
+package zippy
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"hash"
+	"io"
+)
+
+// EncryptionMethod is the method used to encrypt zip archive entries.
+type EncryptionMethod uint8
+
+const (
+	NoEncryption EncryptionMethod = iota // NoEncryption leaves entries unencrypted.
+	AES128                               // AES128 uses WinZip AE-2 with a 128-bit key.
+	AES192                               // AES192 uses WinZip AE-2 with a 192-bit key.
+	AES256                               // AES256 uses WinZip AE-2 with a 256-bit key.
+	ZipCrypto                            // ZipCrypto is the legacy PKWARE encryption. It is only supported when extracting.
+)
+
+// String returns the name of the encryption method.
+func (m EncryptionMethod) String() string {
+	switch m {
+	case NoEncryption:
+		return "none"
+	case AES128:
+		return "AES-128"
+	case AES192:
+		return "AES-192"
+	case AES256:
+		return "AES-256"
+	case ZipCrypto:
+		return "ZipCrypto"
+	default:
+		return "unknown"
+	}
+}
+
+// keyLen returns the AES key length in bytes, or 0 if the method is not an
+// AES method.
+func (m EncryptionMethod) keyLen() int {
+	switch m {
+	case AES128:
+		return 16
+	case AES192:
+		return 24
+	case AES256:
+		return 32
+	default:
+		return 0
+	}
+}
+
+// Encryption configures password protection of entries written to a zip
+// archive.
+type Encryption struct {
+	Method   EncryptionMethod // Method is the encryption method to use.
+	Password string           // Password used to derive the encryption keys.
+	Patterns []string         // Patterns limits encryption to matching entry names, matched case-insensitively with [Zippy.IgnoreCase]. Glob patterns are supported. If empty, every entry is encrypted.
+}
+
+// matches reports whether an entry name should be encrypted, matching the
+// patterns case-insensitively if ignoreCase is set.
+func (e *Encryption) matches(name string, ignoreCase bool) (bool, error) {
+	if len(e.Patterns) == 0 {
+		return true, nil
+	}
+
+	return nameMatches(name, ignoreCase, e.Patterns...)
+}
+
+// validate checks that the encryption settings can be used to write entries.
+func (e *Encryption) validate() error {
+	if e.Method.keyLen() == 0 {
+		return ErrUnsupportedEncryption
+	}
+
+	if e.Password == "" {
+		return ErrEmptyPassword
+	}
+
+	return nil
+}
+
+// WinZip AES constants, see https://www.winzip.com/en/support/aes-encryption/
+const (
+	winzipAESMethod     = 99   // Compression method marking an AES entry
+	winzipAESVendor     = "AE" // Vendor ID in the AES extra field
+	winzipAE1           = 1    // AE-1, CRC is stored
+	winzipAE2           = 2    // AE-2, CRC is not stored
+	winzipKeyIterations = 1000 // PBKDF2 iteration count
+	winzipVerifierLen   = 2    // Password verification value length
+	winzipAuthCodeLen   = 10   // Truncated HMAC-SHA1 length
+)
+
+// aesKeys are the keys derived from a password and salt for a WinZip AES
+// entry.
+type aesKeys struct {
+	encryption []byte
+	auth       []byte
+	verifier   []byte
+}
+
+// deriveAESKeys derives the WinZip AES keys using PBKDF2-HMAC-SHA1.
+func deriveAESKeys(password string, salt []byte, keyLen int) (*aesKeys, error) {
+	key, err := pbkdf2.Key(sha1.New, password, salt, winzipKeyIterations, 2*keyLen+winzipVerifierLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &aesKeys{
+		encryption: key[:keyLen],
+		auth:       key[keyLen : 2*keyLen],
+		verifier:   key[2*keyLen:],
+	}, nil
+}
+
+// winzipCTR is AES in counter mode as used by WinZip. Unlike [cipher.NewCTR]
+// the counter is little-endian and starts at 1.
+type winzipCTR struct {
+	block     cipher.Block
+	counter   [aes.BlockSize]byte
+	keystream [aes.BlockSize]byte
+	pos       int
+}
+
+func newWinZipCTR(key []byte) (*winzipCTR, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &winzipCTR{block: block, pos: aes.BlockSize}, nil
+}
+
+// XORKeyStream implements [cipher.Stream].
+func (c *winzipCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.pos == aes.BlockSize {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+
+			c.block.Encrypt(c.keystream[:], c.counter[:])
+			c.pos = 0
+		}
+
+		dst[i] = src[i] ^ c.keystream[c.pos]
+		c.pos++
+	}
+}
+
+// aesExtra builds the WinZip AES extra field data.
+func aesExtra(version uint16, method EncryptionMethod, actualMethod uint16) []byte {
+	data := binary.LittleEndian.AppendUint16(nil, version)
+	data = append(data, winzipAESVendor...)
+	data = append(data, byte(method))
+	return binary.LittleEndian.AppendUint16(data, actualMethod)
+}
+
+// aesEncryptWriter encrypts and authenticates the compressed data of an entry.
+type aesEncryptWriter struct {
+	w       io.Writer
+	stream  cipher.Stream
+	mac     hash.Hash
+	written int64
+}
+
+func (w *aesEncryptWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	w.stream.XORKeyStream(buf, p)
+	w.mac.Write(buf)
+
+	n, err := w.w.Write(buf)
+	w.written += int64(n)
+	return n, err
+}
+
+// aesEntryWriter compresses, encrypts and authenticates the data of an entry
+// and completes its header when closed.
+type aesEntryWriter struct {
+	header   *zip.FileHeader
+	comp     io.WriteCloser
+	enc      *aesEncryptWriter
+	overhead int64
+	written  int64
+}
+
+func (w *aesEntryWriter) Write(p []byte) (int, error) {
+	n, err := w.comp.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Close flushes the compressor, writes the authentication code and records
+// the final sizes in the header.
+func (w *aesEntryWriter) Close() error {
+	if err := w.comp.Close(); err != nil {
+		return err
+	}
+
+	if _, err := w.enc.w.Write(w.enc.mac.Sum(nil)[:winzipAuthCodeLen]); err != nil {
+		return err
+	}
+
+	w.header.CompressedSize64 = uint64(w.overhead + w.enc.written + winzipAuthCodeLen)
+	w.header.UncompressedSize64 = uint64(w.written)
+	w.header.CompressedSize = uint32(min(w.header.CompressedSize64, uint32max))
+	w.header.UncompressedSize = uint32(min(w.header.UncompressedSize64, uint32max))
+
+	return nil
+}
+
+// createEncrypted adds an entry encrypted with WinZip AES to the archive being
+// written. The returned writer must be closed before the next entry is added.
+func (z *Zippy) createEncrypted(header *zip.FileHeader, enc *Encryption) (io.WriteCloser, error) {
+	if err := enc.validate(); err != nil {
+		return nil, err
+	}
+
+	keyLen := enc.Method.keyLen()
+
+	salt := make([]byte, keyLen/2)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keys, err := deriveAESKeys(enc.Password, salt, keyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := newWinZipCTR(keys.encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	actualMethod := header.Method
+	if actualMethod != zip.Store {
+		actualMethod = zip.Deflate
+	}
+
+	// The sizes are only known once the data is written, so they are stored
+	// in a data descriptor. AE-2 entries do not store a CRC.
+	header.Method = winzipAESMethod
+	header.Flags |= 0x1 | 0x8
+	header.CRC32 = 0
+	header.Extra = appendExtra(stripExtra(header.Extra, winzipAESExtraID), winzipAESExtraID, aesExtra(winzipAE2, enc.Method, actualMethod))
+	prepareRawHeader(header, zipVersion51)
+
+	raw, err := z.zWriter.CreateRaw(header)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := raw.Write(salt); err != nil {
+		return nil, err
+	}
+
+	if _, err := raw.Write(keys.verifier); err != nil {
+		return nil, err
+	}
+
+	ew := &aesEncryptWriter{
+		w:      raw,
+		stream: stream,
+		mac:    hmac.New(sha1.New, keys.auth),
+	}
+
+	var comp io.WriteCloser = nopWriteCloser{ew}
+	if actualMethod == zip.Deflate {
+		comp, err = flate.NewWriter(ew, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &aesEntryWriter{
+		header:   header,
+		comp:     comp,
+		enc:      ew,
+		overhead: int64(len(salt) + winzipVerifierLen),
+	}, nil
+}
+
+// encryptionFor returns the encryption to apply to the named entry, or nil if
+// the entry should not be encrypted.
+func (z *Zippy) encryptionFor(name string) (*Encryption, error) {
+	if z.Encryption == nil || z.Encryption.Method == NoEncryption {
+		return nil, nil
+	}
+
+	match, err := z.Encryption.matches(name, z.IgnoreCase)
+	if err != nil || !match {
+		return nil, err
+	}
+
+	return z.Encryption, nil
+}
+
+// createEntry adds an entry to the archive being written, encrypting it if the
+// entry matches the configured encryption. The returned writer must be closed
+// before the next entry is added.
+func (z *Zippy) createEntry(header *zip.FileHeader) (io.WriteCloser, error) {
+	enc, err := z.encryptionFor(header.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	setNameEncoding(header)
+
+	if enc == nil || header.FileInfo().IsDir() {
+		writer, err := z.zWriter.CreateHeader(header)
+		if err != nil {
+			return nil, err
+		}
+
+		return nopWriteCloser{writer}, nil
+	}
+
+	return z.createEncrypted(header, enc)
+}
+
+// copyFile copies an entry from an existing archive to the archive being
+// written. Entries are copied raw unless they need to be encrypted.
+func (z *Zippy) copyFile(file *zip.File) error {
+	if z.plan != nil {
+		z.plan.add(PlanKeep, file.Name, "", false)
+		return nil
+	}
+
+	enc, err := z.encryptionFor(file.Name)
+	if err != nil {
+		return err
+	}
+
+	if enc == nil || file.FileInfo().IsDir() || file.Flags&0x1 != 0 {
+		return copyRaw(z.zWriter, file)
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	header := file.FileHeader
+	header.Extra = stripExtra(header.Extra, zip64ExtraID)
+	setNameEncoding(&header)
+
+	writer, err := z.createEncrypted(&header, enc)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// EntryEncryption returns the encryption method used by an entry in a zip
+// archive.
+func EntryEncryption(file *zip.File) EncryptionMethod {
+	if file.Flags&0x1 == 0 {
+		return NoEncryption
+	}
+
+	if file.Method != winzipAESMethod {
+		return ZipCrypto
+	}
+
+	extra, ok := findExtra(file.Extra, winzipAESExtraID)
+	if !ok || len(extra) < 7 {
+		return EncryptionMethod(0xff)
+	}
+
+	return EncryptionMethod(extra[4])
+}
+
+// hasCRC reports whether the CRC-32 stored for an entry can be used to
+// validate its contents. WinZip AE-2 entries do not store a CRC.
+func hasCRC(file *zip.File) bool {
+	if file.Method != winzipAESMethod {
+		return true
+	}
+
+	extra, ok := findExtra(file.Extra, winzipAESExtraID)
+	return !ok || len(extra) < 2 || binary.LittleEndian.Uint16(extra[0:2]) != winzipAE2
+}
+
+// aesDecryptReader authenticates and decrypts the data of a WinZip AES entry.
+// The authentication code is checked once the encrypted data is exhausted.
+type aesDecryptReader struct {
+	raw     io.Reader
+	data    io.Reader
+	stream  cipher.Stream
+	mac     hash.Hash
+	archive string
+	name    string
+}
+
+func (r *aesDecryptReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if n > 0 {
+		r.mac.Write(p[:n])
+		r.stream.XORKeyStream(p[:n], p[:n])
+	}
+
+	if err == io.EOF {
+		authCode := make([]byte, winzipAuthCodeLen)
+		if _, readErr := io.ReadFull(r.raw, authCode); readErr != nil {
+			return n, readErr
+		}
+
+		if !hmac.Equal(authCode, r.mac.Sum(nil)[:winzipAuthCodeLen]) {
+			return n, &AuthenticationError{EntryError{Op: "decrypt", Archive: r.archive, Entry: r.name, Err: ErrAuthentication}}
+		}
+	}
+
+	return n, err
+}
+
+// drainReadCloser reads a decompressed stream and, once it ends, drains the
+// underlying reader so trailing checks such as authentication codes run.
+type drainReadCloser struct {
+	io.Reader
+	underlying io.Reader
+	closer     io.Closer
+}
+
+func (r *drainReadCloser) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		if _, drainErr := io.Copy(io.Discard, r.underlying); drainErr != nil {
+			return n, drainErr
+		}
+	}
+
+	return n, err
+}
+
+func (r *drainReadCloser) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+
+	return r.closer.Close()
+}
+
+// decompress returns a reader that inflates the data of an entry stored with
+// the given compression method.
+func decompress(r io.Reader, method uint16, archive string, file *zip.File) (io.ReadCloser, error) {
+	switch method {
+	case zip.Store:
+		return &drainReadCloser{Reader: r, underlying: r}, nil
+	case zip.Deflate:
+		fr := flate.NewReader(r)
+		return &drainReadCloser{Reader: fr, underlying: r, closer: fr}, nil
+	default:
+		return nil, &UnsupportedMethodError{
+			EntryError: EntryError{Op: "open", Archive: archive, Entry: file.Name, Err: zip.ErrAlgorithm},
+			Method:     method,
+		}
+	}
+}
+
+// openAES opens a WinZip AES entry of the archive at path archive for reading.
+func openAES(archive string, file *zip.File, password string) (io.ReadCloser, error) {
+	extra, ok := findExtra(file.Extra, winzipAESExtraID)
+	if !ok || len(extra) < 7 {
+		return nil, &EncryptedEntryError{
+			EntryError: EntryError{Op: "open", Archive: archive, Entry: file.Name, Err: ErrUnsupportedEncryption},
+		}
+	}
+
+	method := EncryptionMethod(extra[4])
+	actualMethod := binary.LittleEndian.Uint16(extra[5:7])
+
+	keyLen := method.keyLen()
+	if keyLen == 0 {
+		return nil, &EncryptedEntryError{
+			EntryError: EntryError{Op: "open", Archive: archive, Entry: file.Name, Err: ErrUnsupportedEncryption},
+			Method:     method,
+		}
+	}
+
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, keyLen/2+winzipVerifierLen)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, &EntryError{Op: "open", Archive: archive, Entry: file.Name, Err: err}
+	}
+
+	keys, err := deriveAESKeys(password, header[:keyLen/2], keyLen)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(keys.verifier, header[keyLen/2:]) {
+		return nil, &PasswordError{EntryError{Op: "decrypt", Archive: archive, Entry: file.Name, Err: ErrBadPassword}}
+	}
+
+	dataLen := int64(file.CompressedSize64) - int64(len(header)) - winzipAuthCodeLen
+	if dataLen < 0 {
+		return nil, &EntryError{Op: "open", Archive: archive, Entry: file.Name, Err: zip.ErrFormat}
+	}
+
+	stream, err := newWinZipCTR(keys.encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	return decompress(&aesDecryptReader{
+		raw:     raw,
+		data:    io.LimitReader(raw, dataLen),
+		stream:  stream,
+		mac:     hmac.New(sha1.New, keys.auth),
+		archive: archive,
+		name:    file.Name,
+	}, actualMethod, archive, file)
+}
@@ -0,0 +1,464 @@
+package zippy
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/engmtcdrm/go-zippy/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// decryptAESTestEntry decrypts a WinZip AES entry without relying on the
+// package reader.
+func decryptAESTestEntry(t *testing.T, file *zip.File, password string) []byte {
+	t.Helper()
+
+	extra, ok := findExtra(file.Extra, winzipAESExtraID)
+	assert.True(t, ok)
+	assert.Len(t, extra, 7)
+
+	method := EncryptionMethod(extra[4])
+	actualMethod := binary.LittleEndian.Uint16(extra[5:7])
+	keyLen := method.keyLen()
+
+	rawReader, err := file.OpenRaw()
+	assert.NoError(t, err)
+
+	raw, err := io.ReadAll(rawReader)
+	assert.NoError(t, err)
+
+	salt := raw[:keyLen/2]
+	verifier := raw[keyLen/2 : keyLen/2+winzipVerifierLen]
+	data := raw[keyLen/2+winzipVerifierLen : len(raw)-winzipAuthCodeLen]
+	authCode := raw[len(raw)-winzipAuthCodeLen:]
+
+	keys, err := deriveAESKeys(password, salt, keyLen)
+	assert.NoError(t, err)
+	assert.Equal(t, keys.verifier, verifier)
+
+	mac := hmac.New(sha1.New, keys.auth)
+	mac.Write(data)
+	assert.Equal(t, mac.Sum(nil)[:winzipAuthCodeLen], authCode)
+
+	stream, err := newWinZipCTR(keys.encryption)
+	assert.NoError(t, err)
+
+	plain := make([]byte, len(data))
+	stream.XORKeyStream(plain, data)
+
+	if actualMethod == zip.Deflate {
+		plain, err = io.ReadAll(flate.NewReader(bytes.NewReader(plain)))
+		assert.NoError(t, err)
+	}
+
+	return plain
+}
+
+// Tests for [EncryptionMethod.String] function.
+func Test_EncryptionMethod_String(t *testing.T) {
+	assert.Equal(t, "none", NoEncryption.String())
+	assert.Equal(t, "AES-128", AES128.String())
+	assert.Equal(t, "AES-192", AES192.String())
+	assert.Equal(t, "AES-256", AES256.String())
+	assert.Equal(t, "unknown", EncryptionMethod(200).String())
+}
+
+// Tests for [winzipCTR] counter handling.
+func Test_winzipCTR(t *testing.T) {
+	key := make([]byte, 16)
+
+	t.Run("counter starts at one", func(t *testing.T) {
+		stream, err := newWinZipCTR(key)
+		assert.NoError(t, err)
+
+		out := make([]byte, 16)
+		stream.XORKeyStream(out, make([]byte, 16))
+
+		expected := make([]byte, 16)
+		counter := make([]byte, 16)
+		counter[0] = 1
+		stream.block.Encrypt(expected, counter)
+		assert.Equal(t, expected, out)
+	})
+
+	t.Run("split writes match single write", func(t *testing.T) {
+		src := bytes.Repeat([]byte("zippy"), 20)
+
+		whole, err := newWinZipCTR(key)
+		assert.NoError(t, err)
+		expected := make([]byte, len(src))
+		whole.XORKeyStream(expected, src)
+
+		split, err := newWinZipCTR(key)
+		assert.NoError(t, err)
+		actual := make([]byte, len(src))
+		split.XORKeyStream(actual[:7], src[:7])
+		split.XORKeyStream(actual[7:], src[7:])
+
+		assert.Equal(t, expected, actual)
+	})
+}
+
+// Tests for [Zippy.Add] with encryption.
+func Test_Zippy_Add_Encryption(t *testing.T) {
+	t.Run("encrypt every entry", func(t *testing.T) {
+		for _, method := range []EncryptionMethod{AES128, AES192, AES256} {
+			tempDir := t.TempDir()
+			zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+			srcFiles, err := testutils.CreateTempFiles(tempDir, 2)
+			assert.NoError(t, err)
+
+			z := NewZippy(zipFilePath)
+			z.Junk = true
+			z.Encryption = &Encryption{Method: method, Password: "secret"}
+
+			err = z.Add(filepath.Join(tempDir, "*.txt"))
+			assert.NoError(t, err)
+
+			zipReader, err := zip.OpenReader(zipFilePath)
+			assert.NoError(t, err)
+			defer zipReader.Close()
+
+			assert.Len(t, zipReader.File, len(srcFiles))
+
+			for _, file := range zipReader.File {
+				assert.Equal(t, uint16(winzipAESMethod), file.Method)
+				assert.NotZero(t, file.Flags&0x1)
+				assert.Equal(t, uint16(zipVersion51), file.ReaderVersion)
+
+				expected, err := os.ReadFile(filepath.Join(tempDir, file.Name))
+				assert.NoError(t, err)
+				assert.Equal(t, expected, decryptAESTestEntry(t, file, "secret"))
+				assert.Equal(t, uint64(len(expected)), file.UncompressedSize64)
+			}
+		}
+	})
+
+	t.Run("encrypt matching entries only", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+		_, err := testutils.CreateTempFiles(tempDir, 2)
+		assert.NoError(t, err)
+
+		z := NewZippy(zipFilePath)
+		z.Junk = true
+		z.Encryption = &Encryption{Method: AES256, Password: "secret", Patterns: []string{"test0-*"}}
+
+		err = z.Add(filepath.Join(tempDir, "*.txt"))
+		assert.NoError(t, err)
+
+		zipReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+		defer zipReader.Close()
+
+		for _, file := range zipReader.File {
+			match, _ := filepath.Match("test0-*", file.Name)
+			assert.Equal(t, match, file.Flags&0x1 != 0, file.Name)
+		}
+	})
+
+	t.Run("patterns ignore case", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+		_, err := testutils.CreateTempFiles(tempDir, 2)
+		assert.NoError(t, err)
+
+		z := NewZippy(zipFilePath)
+		z.Junk = true
+		z.IgnoreCase = true
+		z.Encryption = &Encryption{Method: AES256, Password: "secret", Patterns: []string{"TEST0-*.TXT"}}
+
+		assert.NoError(t, z.Add(filepath.Join(tempDir, "*.txt")))
+
+		zipReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+		defer zipReader.Close()
+
+		for _, file := range zipReader.File {
+			match, _ := filepath.Match("test0-*", file.Name)
+			assert.Equal(t, match, file.Flags&0x1 != 0, file.Name)
+		}
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+		_, err := testutils.CreateTempFiles(tempDir, 1)
+		assert.NoError(t, err)
+
+		z := NewZippy(zipFilePath)
+		z.Encryption = &Encryption{Method: AES256}
+
+		err = z.Add(filepath.Join(tempDir, "*.txt"))
+		assert.ErrorIs(t, err, ErrEmptyPassword)
+	})
+
+	t.Run("bad encryption pattern", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+		_, err := testutils.CreateTempFiles(tempDir, 1)
+		assert.NoError(t, err)
+
+		z := NewZippy(zipFilePath)
+		z.Encryption = &Encryption{Method: AES256, Password: "secret", Patterns: []string{"["}}
+
+		err = z.Add(filepath.Join(tempDir, "*.txt"))
+		assert.Error(t, err)
+	})
+}
+
+// Tests for [Zippy.Copy] with encryption.
+func Test_Zippy_Copy_Encryption(t *testing.T) {
+	t.Run("encrypt copied entries", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		destPath := filepath.Join(tempDir, "dest.zip")
+
+		expected, err := testutils.CreateZipFile(zipFilePath, 3, 1)
+		assert.NoError(t, err)
+
+		z := NewZippy(zipFilePath)
+		z.Encryption = &Encryption{Method: AES128, Password: "secret"}
+
+		err = z.Copy(destPath)
+		assert.NoError(t, err)
+
+		srcReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+		defer srcReader.Close()
+
+		destReader, err := zip.OpenReader(destPath)
+		assert.NoError(t, err)
+		defer destReader.Close()
+
+		assert.Len(t, destReader.File, len(srcReader.File))
+
+		encrypted := 0
+		for i, file := range destReader.File {
+			if file.FileInfo().IsDir() {
+				assert.Zero(t, file.Flags&0x1)
+				continue
+			}
+
+			srcData, err := srcReader.File[i].Open()
+			assert.NoError(t, err)
+			plain, err := io.ReadAll(srcData)
+			assert.NoError(t, err)
+			srcData.Close()
+
+			assert.Equal(t, plain, decryptAESTestEntry(t, file, "secret"))
+			encrypted++
+		}
+
+		assert.Equal(t, expected, encrypted)
+	})
+
+	t.Run("raw copy when not encrypting", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		destPath := filepath.Join(tempDir, "dest.zip")
+
+		_, err := testutils.CreateZipFile(zipFilePath, 3, 0)
+		assert.NoError(t, err)
+
+		z := NewZippy(zipFilePath)
+		z.Encryption = &Encryption{Method: AES128, Password: "secret", Patterns: []string{"nothing"}}
+
+		err = z.Copy(destPath, "*")
+		assert.NoError(t, err)
+
+		destReader, err := zip.OpenReader(destPath)
+		assert.NoError(t, err)
+		defer destReader.Close()
+
+		for _, file := range destReader.File {
+			assert.Zero(t, file.Flags&0x1)
+		}
+	})
+}
+
+// createAESTestFile creates a zip archive with an encrypted entry and an
+// unencrypted entry.
+func createAESTestFile(t *testing.T, tempDir string, method EncryptionMethod, password string) string {
+	t.Helper()
+
+	zipFilePath := filepath.Join(tempDir, testZipFileName)
+	srcDir := filepath.Join(tempDir, "src")
+	assert.NoError(t, os.MkdirAll(srcDir, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "secret.txt"), bytes.Repeat([]byte("secret "), 50), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "public.txt"), []byte("public"), 0644))
+
+	z := NewZippy(zipFilePath)
+	z.Junk = true
+	z.Encryption = &Encryption{Method: method, Password: password, Patterns: []string{"secret.txt"}}
+	assert.NoError(t, z.Add(filepath.Join(srcDir, "*.txt")))
+
+	return zipFilePath
+}
+
+// Tests for [EntryEncryption] function.
+func Test_EntryEncryption(t *testing.T) {
+	tempDir := t.TempDir()
+	zipFilePath := createAESTestFile(t, tempDir, AES192, "secret")
+
+	files, err := Contents(zipFilePath)
+	assert.NoError(t, err)
+
+	for _, file := range files {
+		if file.Name == "secret.txt" {
+			assert.Equal(t, AES192, EntryEncryption(file))
+		} else {
+			assert.Equal(t, NoEncryption, EntryEncryption(file))
+		}
+	}
+}
+
+// Tests for [Unzippy.ExtractTo] with WinZip AES entries.
+func Test_Unzippy_ExtractTo_AES(t *testing.T) {
+	t.Run("valid password", func(t *testing.T) {
+		for _, method := range []EncryptionMethod{AES128, AES192, AES256} {
+			tempDir := t.TempDir()
+			zipFilePath := createAESTestFile(t, tempDir, method, "secret")
+			dest := filepath.Join(tempDir, "output")
+
+			u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Password: "secret"})
+			assert.NoError(t, err)
+
+			files, err := u.ExtractTo(dest)
+			assert.NoError(t, err)
+			assert.Len(t, files, 2)
+
+			for _, name := range []string{"secret.txt", "public.txt"} {
+				expected, err := os.ReadFile(filepath.Join(tempDir, "src", name))
+				assert.NoError(t, err)
+				actual, err := os.ReadFile(filepath.Join(dest, name))
+				assert.NoError(t, err)
+				assert.Equal(t, expected, actual)
+			}
+		}
+	})
+
+	t.Run("password func", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := createAESTestFile(t, tempDir, AES256, "secret")
+
+		var asked []string
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{
+			Password: "ignored",
+			PasswordFunc: func(name string) (string, error) {
+				asked = append(asked, name)
+				return "secret", nil
+			},
+		})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "output"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"secret.txt"}, asked)
+	})
+
+	t.Run("bad password", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := createAESTestFile(t, tempDir, AES256, "secret")
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Password: "wrong"})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "output"))
+		assert.ErrorIs(t, err, ErrBadPassword)
+		assert.NotContains(t, err.Error(), "checksum")
+	})
+
+	t.Run("missing password", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := createAESTestFile(t, tempDir, AES256, "secret")
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "output"))
+		assert.ErrorIs(t, err, ErrPasswordRequired)
+	})
+
+	t.Run("tampered data", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := createAESTestFile(t, tempDir, AES256, "secret")
+
+		zipReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+
+		var offset int64
+		for _, file := range zipReader.File {
+			if file.Name == "secret.txt" {
+				offset, err = file.DataOffset()
+				assert.NoError(t, err)
+			}
+		}
+		zipReader.Close()
+
+		// Flip a byte after the salt and password verifier
+		data, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+		data[offset+16+winzipVerifierLen] ^= 0xff
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Password: "secret"})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "output"))
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrBadPassword)
+		assert.NoFileExists(t, filepath.Join(tempDir, "output", "secret.txt"))
+	})
+
+	t.Run("tampered authentication code", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := createAESTestFile(t, tempDir, AES256, "secret")
+
+		zipReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+
+		var end int64
+		for _, file := range zipReader.File {
+			if file.Name == "secret.txt" {
+				offset, err := file.DataOffset()
+				assert.NoError(t, err)
+				end = offset + int64(file.CompressedSize64)
+			}
+		}
+		zipReader.Close()
+
+		// Flip the last byte of the authentication code, after the data
+		data, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+		data[end-1] ^= 0xff
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Password: "secret"})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "output"))
+		assert.ErrorIs(t, err, ErrAuthentication)
+
+		var authErr *AuthenticationError
+		if assert.ErrorAs(t, err, &authErr) {
+			assert.Equal(t, zipFilePath, authErr.Archive)
+			assert.Equal(t, "secret.txt", authErr.Entry)
+		}
+
+		assert.NoFileExists(t, filepath.Join(tempDir, "output", "secret.txt"))
+	})
+}
@@ -1,7 +1,266 @@
 package zippy
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
-	ErrEmptyPath = errors.New("path cannot be empty")
+	ErrEmptyPath             = errors.New("path cannot be empty")
+	ErrEmptyPassword         = errors.New("password cannot be empty")
+	ErrUnsupportedEncryption = errors.New("unsupported encryption method")
+	ErrPasswordRequired      = errors.New("password required for encrypted entry")
+	ErrBadPassword           = errors.New("incorrect password")
+	ErrAuthentication        = errors.New("authentication code mismatch")
+	ErrVerificationFailed    = errors.New("archive verification failed")
+	ErrHeaderMismatch        = errors.New("local header does not match central directory")
+	ErrOverlappingEntries    = errors.New("entry overlaps another entry")
+	ErrSymlinkLoop           = errors.New("symbolic link loop")
+	ErrUnsafePath            = errors.New("path is outside of the destination")
+	ErrEntryNotFound         = errors.New("entry not found in archive")
+	ErrEntryExists           = errors.New("entry already exists in archive")
+	ErrInvalidName           = errors.New("invalid entry name")
+	ErrUnsupportedFormat     = errors.New("unsupported archive format")
+	ErrRefusedEntry          = errors.New("entry type refused")
+	ErrSegmentSize           = errors.New("invalid segment size")
+	ErrChecksumMismatch      = errors.New("checksum mismatch")
+	ErrSizeMismatch          = errors.New("size mismatch")
+	ErrUnsupportedMethod     = errors.New("unsupported compression method")
+	ErrEncryptedEntry        = errors.New("entry is encrypted")
+	ErrLimitExceeded         = errors.New("limit exceeded")
+	ErrNoMatch               = errors.New("name not matched")
+	ErrNameCollision         = errors.New("entry names collide")
 )
+
+// EntryError is a failure with an entry of an archive. The typed errors below
+// embed it, so [errors.As] with an *EntryError target finds the entry and
+// archive of any of them.
+type EntryError struct {
+	Op      string // Operation that failed, like "extract".
+	Archive string // Path to the archive.
+	Entry   string // Name of the entry.
+	Err     error  // Underlying cause, if any.
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("failed to %s '%s': %v", e.Op, e.Entry, e.Err)
+}
+
+// Unwrap returns the underlying cause.
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// As sets target to e if it is an **EntryError, so the errors embedding e
+// match it.
+func (e *EntryError) As(target any) bool {
+	if t, ok := target.(**EntryError); ok {
+		*t = e
+		return true
+	}
+
+	return false
+}
+
+// message formats the error with detail, followed by the cause if there is
+// one.
+func (e *EntryError) message(detail string) string {
+	if e.Err != nil {
+		return fmt.Sprintf("failed to %s '%s': %s: %v", e.Op, e.Entry, detail, e.Err)
+	}
+
+	return fmt.Sprintf("failed to %s '%s': %s", e.Op, e.Entry, detail)
+}
+
+// ChecksumError reports an entry whose CRC-32 checksum does not match the one
+// recorded in the archive. It matches [ErrChecksumMismatch].
+type ChecksumError struct {
+	EntryError
+	Expected uint32 // Checksum recorded in the archive.
+	Actual   uint32 // Checksum of the data read.
+}
+
+func (e *ChecksumError) Error() string {
+	return e.message(fmt.Sprintf("expected '%08x' checksum, got '%08x' checksum", e.Expected, e.Actual))
+}
+
+// Is reports whether target is [ErrChecksumMismatch].
+func (e *ChecksumError) Is(target error) bool {
+	return target == ErrChecksumMismatch
+}
+
+// SizeError reports an entry or file whose size does not match the expected
+// size. It matches [ErrSizeMismatch].
+type SizeError struct {
+	EntryError
+	Expected int64 // Expected size in bytes.
+	Actual   int64 // Number of bytes copied.
+}
+
+func (e *SizeError) Error() string {
+	return e.message(fmt.Sprintf("expected %d bytes, got %d bytes", e.Expected, e.Actual))
+}
+
+// Is reports whether target is [ErrSizeMismatch].
+func (e *SizeError) Is(target error) bool {
+	return target == ErrSizeMismatch
+}
+
+// UnsafePathError reports an entry that would be written outside of the
+// destination directory. It matches [ErrUnsafePath].
+type UnsafePathError struct {
+	EntryError
+	Path string // Path or link target outside of the destination.
+}
+
+// Is reports whether target is [ErrUnsafePath].
+func (e *UnsafePathError) Is(target error) bool {
+	return target == ErrUnsafePath
+}
+
+// RefusedEntryError reports an entry whose type is not extracted with the
+// options. It matches [ErrRefusedEntry].
+type RefusedEntryError struct {
+	EntryError
+	Type string // Type of the entry, like "hard link".
+}
+
+func (e *RefusedEntryError) Error() string {
+	return e.message(e.Type)
+}
+
+// Is reports whether target is [ErrRefusedEntry].
+func (e *RefusedEntryError) Is(target error) bool {
+	return target == ErrRefusedEntry
+}
+
+// SymlinkLoopError reports a directory reached again through a symbolic link
+// while adding files to an archive. It matches [ErrSymlinkLoop].
+type SymlinkLoopError struct {
+	EntryError
+	Path string // Resolved path of the directory reached again.
+}
+
+// Is reports whether target is [ErrSymlinkLoop].
+func (e *SymlinkLoopError) Is(target error) bool {
+	return target == ErrSymlinkLoop
+}
+
+// EntryNotFoundError reports an entry missing from an archive. It matches
+// [ErrEntryNotFound].
+type EntryNotFoundError struct {
+	EntryError
+}
+
+// Is reports whether target is [ErrEntryNotFound].
+func (e *EntryNotFoundError) Is(target error) bool {
+	return target == ErrEntryNotFound
+}
+
+// UnsupportedMethodError reports an entry compressed with a method that
+// cannot be decompressed. It matches [ErrUnsupportedMethod].
+type UnsupportedMethodError struct {
+	EntryError
+	Method uint16 // Compression method of the entry.
+}
+
+func (e *UnsupportedMethodError) Error() string {
+	return e.message(fmt.Sprintf("unsupported compression method %d", e.Method))
+}
+
+// Is reports whether target is [ErrUnsupportedMethod].
+func (e *UnsupportedMethodError) Is(target error) bool {
+	return target == ErrUnsupportedMethod
+}
+
+// EncryptedEntryError reports an encrypted entry that cannot be decrypted,
+// because no password was given or its encryption is not supported. It
+// matches [ErrEncryptedEntry].
+type EncryptedEntryError struct {
+	EntryError
+	Method EncryptionMethod // Encryption method of the entry, if known.
+}
+
+// Is reports whether target is [ErrEncryptedEntry].
+func (e *EncryptedEntryError) Is(target error) bool {
+	return target == ErrEncryptedEntry
+}
+
+// PasswordError reports an encrypted entry whose password is incorrect. It
+// matches [ErrBadPassword].
+type PasswordError struct {
+	EntryError
+}
+
+// Is reports whether target is [ErrBadPassword].
+func (e *PasswordError) Is(target error) bool {
+	return target == ErrBadPassword
+}
+
+// AuthenticationError reports an encrypted entry whose data does not match its
+// authentication code, because it was damaged or tampered with. It matches
+// [ErrAuthentication].
+type AuthenticationError struct {
+	EntryError
+}
+
+// Is reports whether target is [ErrAuthentication].
+func (e *AuthenticationError) Is(target error) bool {
+	return target == ErrAuthentication
+}
+
+// LimitError reports a value of an entry that is larger than allowed. It
+// matches [ErrLimitExceeded].
+type LimitError struct {
+	EntryError
+	Field string // What exceeded the limit, like "comment".
+	Limit int64  // Largest allowed size in bytes.
+	Size  int64  // Actual size in bytes.
+}
+
+func (e *LimitError) Error() string {
+	return e.message(fmt.Sprintf("%s is longer than %d bytes", e.Field, e.Limit))
+}
+
+// Is reports whether target is [ErrLimitExceeded].
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// UnmatchedError reports glob patterns that matched no entry of an archive.
+// It matches [ErrNoMatch].
+type UnmatchedError struct {
+	Archive  string   // Path to the archive.
+	Patterns []string // Patterns that matched nothing, in the order given.
+}
+
+func (e *UnmatchedError) Error() string {
+	return fmt.Sprintf("failed to match '%s' in '%s': %s", strings.Join(e.Patterns, "', '"), e.Archive, ErrNoMatch)
+}
+
+// Is reports whether target is [ErrNoMatch].
+func (e *UnmatchedError) Is(target error) bool {
+	return target == ErrNoMatch
+}
+
+// CollisionError reports entries of an archive that would be written to the
+// same file. It matches [ErrNameCollision].
+type CollisionError struct {
+	Archive string     // Path to the archive.
+	Names   [][]string // Names of each group of colliding entries, in archive order.
+}
+
+func (e *CollisionError) Error() string {
+	groups := make([]string, len(e.Names))
+	for i, names := range e.Names {
+		groups[i] = "'" + strings.Join(names, "', '") + "'"
+	}
+
+	return fmt.Sprintf("%s in '%s': %s", strings.Join(groups, "; "), e.Archive, ErrNameCollision)
+}
+
+// Is reports whether target is [ErrNameCollision].
+func (e *CollisionError) Is(target error) bool {
+	return target == ErrNameCollision
+}
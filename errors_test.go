@@ -0,0 +1,388 @@
+package zippy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [EntryError] type.
+func Test_EntryError(t *testing.T) {
+	cause := errors.New("cause")
+	tests := []struct {
+		name   string
+		err    error
+		target error
+		want   string
+	}{
+		{
+			name:   "checksum",
+			err:    &ChecksumError{EntryError: EntryError{Op: "copy", Entry: "a.txt"}, Expected: 1, Actual: 2},
+			target: ErrChecksumMismatch,
+			want:   "failed to copy 'a.txt': expected '00000001' checksum, got '00000002' checksum",
+		},
+		{
+			name:   "size",
+			err:    &SizeError{EntryError: EntryError{Op: "copy", Entry: "a.txt", Err: cause}, Expected: 10, Actual: 5},
+			target: ErrSizeMismatch,
+			want:   "failed to copy 'a.txt': expected 10 bytes, got 5 bytes: cause",
+		},
+		{
+			name:   "unsafe path",
+			err:    &UnsafePathError{EntryError: EntryError{Op: "extract", Entry: "a.txt", Err: ErrUnsafePath}},
+			target: ErrUnsafePath,
+			want:   "failed to extract 'a.txt': path is outside of the destination",
+		},
+		{
+			name:   "refused entry",
+			err:    &RefusedEntryError{EntryError: EntryError{Op: "extract", Entry: "a.txt", Err: ErrRefusedEntry}, Type: "hard link"},
+			target: ErrRefusedEntry,
+			want:   "failed to extract 'a.txt': hard link: entry type refused",
+		},
+		{
+			name:   "symbolic link loop",
+			err:    &SymlinkLoopError{EntryError: EntryError{Op: "add", Entry: "a.txt", Err: ErrSymlinkLoop}, Path: "/src"},
+			target: ErrSymlinkLoop,
+			want:   "failed to add 'a.txt': symbolic link loop",
+		},
+		{
+			name:   "entry not found",
+			err:    &EntryNotFoundError{EntryError{Op: "rename", Entry: "a.txt", Err: ErrEntryNotFound}},
+			target: ErrEntryNotFound,
+			want:   "failed to rename 'a.txt': entry not found in archive",
+		},
+		{
+			name:   "unsupported method",
+			err:    &UnsupportedMethodError{EntryError: EntryError{Op: "open", Entry: "a.txt", Err: zip.ErrAlgorithm}, Method: 99},
+			target: ErrUnsupportedMethod,
+			want:   "failed to open 'a.txt': unsupported compression method 99: zip: unsupported compression algorithm",
+		},
+		{
+			name:   "encrypted entry",
+			err:    &EncryptedEntryError{EntryError: EntryError{Op: "open", Entry: "a.txt", Err: ErrPasswordRequired}},
+			target: ErrEncryptedEntry,
+			want:   "failed to open 'a.txt': password required for encrypted entry",
+		},
+		{
+			name:   "bad password",
+			err:    &PasswordError{EntryError{Op: "decrypt", Entry: "a.txt", Err: ErrBadPassword}},
+			target: ErrBadPassword,
+			want:   "failed to decrypt 'a.txt': incorrect password",
+		},
+		{
+			name:   "authentication",
+			err:    &AuthenticationError{EntryError{Op: "decrypt", Entry: "a.txt", Err: ErrAuthentication}},
+			target: ErrAuthentication,
+			want:   "failed to decrypt 'a.txt': authentication code mismatch",
+		},
+		{
+			name:   "limit",
+			err:    &LimitError{EntryError: EntryError{Op: "set comment of", Entry: "a.txt"}, Field: "comment", Limit: 10, Size: 20},
+			target: ErrLimitExceeded,
+			want:   "failed to set comment of 'a.txt': comment is longer than 10 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped := errors.Join(errors.New("context"), tt.err)
+
+			assert.Equal(t, tt.want, tt.err.Error())
+			assert.ErrorIs(t, wrapped, tt.target)
+
+			var entryErr *EntryError
+			if assert.ErrorAs(t, wrapped, &entryErr) {
+				assert.Equal(t, "a.txt", entryErr.Entry)
+			}
+		})
+	}
+
+	t.Run("cause", func(t *testing.T) {
+		err := &SizeError{EntryError: EntryError{Op: "copy", Entry: "a.txt", Err: cause}}
+		assert.ErrorIs(t, err, cause)
+		assert.NotErrorIs(t, err, ErrChecksumMismatch)
+	})
+}
+
+// Tests for the typed errors returned while working with archives.
+func Test_typedErrors(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// assertEntryError checks that err matches target and reports the entry
+	// and archive.
+	assertEntryError := func(t *testing.T, err error, target error, archive, entry string) {
+		t.Helper()
+
+		assert.ErrorIs(t, err, target)
+
+		var entryErr *EntryError
+		if assert.ErrorAs(t, err, &entryErr) {
+			assert.Equal(t, archive, entryErr.Archive)
+			assert.Equal(t, entry, entryErr.Entry)
+		}
+	}
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		zipPath := filepath.Join(tempDir, "checksum.zip")
+		createRawZip(t, zipPath, &zip.FileHeader{Name: "file.txt", Method: zip.Store, CRC32: 0x1234}, "hello")
+
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "checksum"))
+		assertEntryError(t, err, ErrChecksumMismatch, zipPath, "file.txt")
+		assert.ErrorIs(t, err, zip.ErrChecksum)
+
+		var checksumErr *ChecksumError
+		if assert.ErrorAs(t, err, &checksumErr) {
+			assert.Equal(t, uint32(0x1234), checksumErr.Expected)
+		}
+	})
+
+	t.Run("unsupported method", func(t *testing.T) {
+		zipPath := filepath.Join(tempDir, "method.zip")
+		createRawZip(t, zipPath, &zip.FileHeader{Name: "file.txt", Method: 99}, "hello")
+
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "method"))
+		assertEntryError(t, err, ErrUnsupportedMethod, zipPath, "file.txt")
+		assert.ErrorIs(t, err, zip.ErrAlgorithm)
+
+		var methodErr *UnsupportedMethodError
+		if assert.ErrorAs(t, err, &methodErr) {
+			assert.Equal(t, uint16(99), methodErr.Method)
+		}
+	})
+
+	t.Run("unsafe path", func(t *testing.T) {
+		zipPath := filepath.Join(tempDir, "unsafe.zip")
+		createRawZip(t, zipPath, &zip.FileHeader{Name: "../evil.txt", Method: zip.Store}, "evil")
+
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "unsafe"))
+		assertEntryError(t, err, ErrUnsafePath, zipPath, "../evil.txt")
+
+		var unsafeErr *UnsafePathError
+		if assert.ErrorAs(t, err, &unsafeErr) {
+			assert.Equal(t, filepath.Join(tempDir, "evil.txt"), unsafeErr.Path)
+		}
+	})
+
+	encrypted := filepath.Join(tempDir, "encrypted.zip")
+	sourcePath := filepath.Join(tempDir, "secret.txt")
+	assert.NoError(t, os.WriteFile(sourcePath, []byte("secret"), 0644))
+
+	z := NewZippy(encrypted)
+	z.Junk = true
+	z.Encryption = &Encryption{Method: AES256, Password: "right"}
+	assert.NoError(t, z.Add(sourcePath))
+
+	t.Run("encrypted entry", func(t *testing.T) {
+		u, err := NewUnzippy(encrypted, nil)
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "encrypted"))
+		assertEntryError(t, err, ErrEncryptedEntry, encrypted, "secret.txt")
+		assert.ErrorIs(t, err, ErrPasswordRequired)
+
+		var encryptedErr *EncryptedEntryError
+		if assert.ErrorAs(t, err, &encryptedErr) {
+			assert.Equal(t, AES256, encryptedErr.Method)
+		}
+	})
+
+	t.Run("bad password", func(t *testing.T) {
+		u, err := NewUnzippy(encrypted, &UnzippyOptions{Password: "wrong"})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "password"))
+		assertEntryError(t, err, ErrBadPassword, encrypted, "secret.txt")
+		assert.ErrorAs(t, err, new(*PasswordError))
+	})
+
+	t.Run("entry not found", func(t *testing.T) {
+		err := NewZippy(encrypted).SetEntryComment("missing.txt", "comment")
+		assertEntryError(t, err, ErrEntryNotFound, encrypted, "missing.txt")
+		assert.ErrorAs(t, err, new(*EntryNotFoundError))
+
+		err = NewZippy(encrypted).Rename("missing.txt", "found.txt")
+		assertEntryError(t, err, ErrEntryNotFound, encrypted, "missing.txt")
+	})
+
+	t.Run("limit exceeded", func(t *testing.T) {
+		err := NewZippy(encrypted).SetEntryComment("secret.txt", strings.Repeat("x", uint16max+1))
+		assertEntryError(t, err, ErrLimitExceeded, encrypted, "secret.txt")
+
+		var limitErr *LimitError
+		if assert.ErrorAs(t, err, &limitErr) {
+			assert.Equal(t, int64(uint16max), limitErr.Limit)
+			assert.Equal(t, int64(uint16max+1), limitErr.Size)
+		}
+	})
+
+	t.Run("size mismatch", func(t *testing.T) {
+		err := validateCopy(encrypted, "secret.txt", sourcePath, 3, 6)
+		assertEntryError(t, err, ErrSizeMismatch, encrypted, "secret.txt")
+
+		var sizeErr *SizeError
+		if assert.ErrorAs(t, err, &sizeErr) {
+			assert.Equal(t, int64(6), sizeErr.Expected)
+			assert.Equal(t, int64(3), sizeErr.Actual)
+		}
+	})
+}
+
+// Tests for [UnmatchedError] type.
+func Test_UnmatchedError(t *testing.T) {
+	err := &UnmatchedError{Archive: "a.zip", Patterns: []string{"x.txt", "y*"}}
+
+	assert.Equal(t, "failed to match 'x.txt', 'y*' in 'a.zip': name not matched", err.Error())
+	assert.ErrorIs(t, errors.Join(errors.New("context"), err), ErrNoMatch)
+	assert.NotErrorIs(t, err, ErrEntryNotFound)
+}
+
+// Tests for [CollisionError] type.
+func Test_CollisionError(t *testing.T) {
+	err := &CollisionError{Archive: "a.zip", Names: [][]string{{"README", "readme"}, {"b.txt", "b.txt"}}}
+
+	assert.Equal(t, "'README', 'readme'; 'b.txt', 'b.txt' in 'a.zip': entry names collide", err.Error())
+	assert.ErrorIs(t, errors.Join(errors.New("context"), err), ErrNameCollision)
+	assert.NotErrorIs(t, err, ErrNoMatch)
+}
+
+// Tests for the FailUnmatched options of [Zippy], [UnzippyOptions] and
+// patterns that match no entry.
+func Test_FailUnmatched(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "archive.zip")
+	createRawZip(t, zipPath, &zip.FileHeader{Name: "a.txt", Method: zip.Store, CRC32: crc32.ChecksumIEEE([]byte("alpha"))}, "alpha")
+
+	original, err := os.ReadFile(zipPath)
+	assert.NoError(t, err)
+
+	// assertUnmatched checks that err is an UnmatchedError listing patterns.
+	assertUnmatched := func(t *testing.T, err error, archive string, patterns ...string) {
+		t.Helper()
+
+		assert.ErrorIs(t, err, ErrNoMatch)
+
+		var unmatchedErr *UnmatchedError
+		if assert.ErrorAs(t, err, &unmatchedErr) {
+			assert.Equal(t, archive, unmatchedErr.Archive)
+			assert.Equal(t, patterns, unmatchedErr.Patterns)
+		}
+	}
+
+	// assertUnchanged checks that the archive was not rewritten.
+	assertUnchanged := func(t *testing.T) {
+		t.Helper()
+
+		data, err := os.ReadFile(zipPath)
+		assert.NoError(t, err)
+		assert.Equal(t, original, data)
+
+		temps, err := filepath.Glob(filepath.Join(tempDir, "zippy-*"))
+		assert.NoError(t, err)
+		assert.Empty(t, temps)
+	}
+
+	t.Run("delete without match", func(t *testing.T) {
+		assert.NoError(t, NewZippy(zipPath).Delete("missing.txt", "typo*"))
+		assertUnchanged(t)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.FailUnmatched = true
+
+		assertUnmatched(t, z.Delete("a.txt", "missing.txt", "typo*"), zipPath, "missing.txt", "typo*")
+		assertUnchanged(t)
+	})
+
+	t.Run("delete bad pattern", func(t *testing.T) {
+		assert.ErrorIs(t, NewZippy(zipPath).Delete("["), filepath.ErrBadPattern)
+		assertUnchanged(t)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.FailUnmatched = true
+		dest := filepath.Join(tempDir, "copy", "copy.zip")
+
+		assertUnmatched(t, z.Copy(dest, "a.txt", "missing.txt"), zipPath, "missing.txt")
+		assert.NoDirExists(t, filepath.Dir(dest))
+
+		assert.NoError(t, z.Copy(dest, "a*"))
+		assert.FileExists(t, dest)
+	})
+
+	t.Run("extract without option", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		files, err := u.ExtractFilesTo(filepath.Join(tempDir, "default"), "typo*")
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{FailUnmatched: true})
+		assert.NoError(t, err)
+
+		dest := filepath.Join(tempDir, "extracted")
+		_, err = u.ExtractFilesTo(dest, "typo*", "a.txt", "b.txt")
+		assertUnmatched(t, err, zipPath, "typo*", "b.txt")
+		assert.NoFileExists(t, filepath.Join(dest, "a.txt"))
+
+		files, err := u.ExtractFilesTo(dest, "a.txt")
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+
+		files, err = u.ExtractFilesTo(filepath.Join(tempDir, "all"))
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{FailUnmatched: true})
+		assert.NoError(t, err)
+
+		_, err = u.Verify("typo*")
+		assertUnmatched(t, err, zipPath, "typo*")
+
+		report, err := u.Verify("a.txt")
+		assert.NoError(t, err)
+		assert.True(t, report.OK())
+	})
+
+	t.Run("tar", func(t *testing.T) {
+		tarPath := filepath.Join(tempDir, "archive.tar")
+		createTarFile(t, tarPath, TarUncompressed,
+			tarTestEntry{header: &tar.Header{Name: "dir/", Typeflag: tar.TypeDir}},
+			tarTestEntry{header: &tar.Header{Name: "dir/a.txt", Typeflag: tar.TypeReg}, body: "alpha"},
+		)
+
+		u, err := NewUntarry(tarPath, TarUncompressed, &UnzippyOptions{FailUnmatched: true})
+		assert.NoError(t, err)
+
+		dest := filepath.Join(tempDir, "untarred")
+		_, err = u.ExtractFilesTo(dest, "dir/*", "dir/b.txt")
+		assertUnmatched(t, err, tarPath, "dir/b.txt")
+		assert.NoDirExists(t, dest)
+
+		files, err := u.ExtractFilesTo(dest, "dir/*")
+		assert.NoError(t, err)
+		assert.Len(t, files, 2)
+	})
+}
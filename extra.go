@@ -0,0 +1,129 @@
+package zippy
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"time"
+	"unicode/utf8"
+)
+
+// Extra field header IDs used by zippy.
+const (
+	zip64ExtraID       = 0x0001 // ZIP64 extended information
+	extTimeExtraID     = 0x5455 // Info-ZIP extended timestamp
+	unicodePathExtraID = 0x7075 // Info-ZIP Unicode path
+	winzipAESExtraID   = 0x9901 // WinZip AES encryption
+)
+
+// creatorUnix is the "version made by" host system for Unix.
+const creatorUnix = 3
+
+// zipVersion51 is the version needed to extract entries encrypted with AES.
+const zipVersion51 = 51
+
+// extraField is a single record from a zip extra field block.
+type extraField struct {
+	ID   uint16
+	Data []byte
+}
+
+// parseExtra splits a raw extra field block into its records. Malformed
+// trailing bytes are ignored.
+func parseExtra(extra []byte) []extraField {
+	var fields []extraField
+
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra[0:2])
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			break
+		}
+
+		fields = append(fields, extraField{ID: id, Data: extra[4 : 4+size]})
+		extra = extra[4+size:]
+	}
+
+	return fields
+}
+
+// findExtra returns the data of the first extra field record with the given
+// ID.
+func findExtra(extra []byte, id uint16) ([]byte, bool) {
+	for _, field := range parseExtra(extra) {
+		if field.ID == id {
+			return field.Data, true
+		}
+	}
+
+	return nil, false
+}
+
+// appendExtra appends an extra field record to an extra field block.
+func appendExtra(extra []byte, id uint16, data []byte) []byte {
+	extra = binary.LittleEndian.AppendUint16(extra, id)
+	extra = binary.LittleEndian.AppendUint16(extra, uint16(len(data)))
+	return append(extra, data...)
+}
+
+// stripExtra returns a copy of the extra field block without the records
+// matching any of the given IDs.
+func stripExtra(extra []byte, ids ...uint16) []byte {
+	var stripped []byte
+
+	for _, field := range parseExtra(extra) {
+		keep := true
+		for _, id := range ids {
+			if field.ID == id {
+				keep = false
+				break
+			}
+		}
+
+		if keep {
+			stripped = appendExtra(stripped, field.ID, field.Data)
+		}
+	}
+
+	return stripped
+}
+
+// timeToMsDosTime converts a time to the MS-DOS date and time fields used in
+// zip headers.
+func timeToMsDosTime(t time.Time) (fDate uint16, fTime uint16) {
+	fDate = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	fTime = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return fDate, fTime
+}
+
+// requiresUTF8 reports whether a name needs the UTF-8 flag to be read
+// correctly.
+func requiresUTF8(s string) bool {
+	for _, r := range s {
+		if r >= utf8.RuneSelf {
+			return utf8.ValidString(s)
+		}
+	}
+
+	return false
+}
+
+// prepareRawHeader fills in the header fields that [zip.Writer.CreateHeader]
+// would normally set, for headers written with [zip.Writer.CreateRaw].
+func prepareRawHeader(header *zip.FileHeader, readerVersion uint16) {
+	if requiresUTF8(header.Name) || requiresUTF8(header.Comment) {
+		header.Flags |= 0x800
+	}
+
+	header.CreatorVersion = header.CreatorVersion&0xff00 | readerVersion
+	header.ReaderVersion = readerVersion
+
+	if !header.Modified.IsZero() {
+		header.ModifiedDate, header.ModifiedTime = timeToMsDosTime(header.Modified)
+
+		if _, ok := findExtra(header.Extra, extTimeExtraID); !ok {
+			data := []byte{1} // Flags: ModTime
+			data = binary.LittleEndian.AppendUint32(data, uint32(header.Modified.Unix()))
+			header.Extra = appendExtra(header.Extra, extTimeExtraID, data)
+		}
+	}
+}
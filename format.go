@@ -0,0 +1,316 @@
+package zippy
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Zip record signatures and fixed lengths.
+const (
+	localHeaderSignature    = 0x04034b50
+	centralHeaderSignature  = 0x02014b50
+	dataDescriptorSignature = 0x08074b50
+	eocdSignature           = 0x06054b50
+	eocd64Signature         = 0x06064b50
+	eocd64LocatorSignature  = 0x07064b50
+
+	localHeaderLen          = 30
+	centralHeaderLen        = 46
+	dataDescriptorLen       = 16 // with signature
+	dataDescriptor64Len     = 24 // with signature
+	eocdLen                 = 22
+	eocd64Len               = 56
+	eocd64LocatorLen        = 20
+	maxEOCDSearch           = eocdLen + 0xffff
+	maxCentralDirectoryRead = 1 << 30
+)
+
+// endOfCentralDirectory holds the values read from the end of central
+// directory records of an archive.
+type endOfCentralDirectory struct {
+	offset      int64  // Offset of the end of central directory record.
+	end         int64  // Offset just past the record and its comment.
+	disk        uint32 // Number of the disk holding the record, the last segment of a split archive.
+	cdDisk      uint32 // Number of the disk where the central directory starts.
+	diskEntries uint64 // Number of central directory entries on the disk holding the record.
+	entries     uint64 // Number of central directory entries.
+	cdSize      uint64 // Size of the central directory.
+	cdOffset    uint64 // Offset of the central directory as recorded.
+	baseOffset  int64  // Number of bytes prepended to the archive.
+	comment     string
+	zip64       bool
+}
+
+// readEOCD locates and reads the end of central directory records.
+func readEOCD(r io.ReaderAt, size int64) (*endOfCentralDirectory, error) {
+	searchLen := min(size, maxEOCDSearch)
+	buf := make([]byte, searchLen)
+	if _, err := r.ReadAt(buf, size-searchLen); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	pos := -1
+	for i := len(buf) - eocdLen; i >= 0; i-- {
+		if binary.LittleEndian.Uint32(buf[i:]) != eocdSignature {
+			continue
+		}
+
+		commentLen := int(binary.LittleEndian.Uint16(buf[i+20:]))
+		if i+eocdLen+commentLen <= len(buf) {
+			pos = i
+			break
+		}
+	}
+
+	if pos < 0 {
+		return nil, zip.ErrFormat
+	}
+
+	b := buf[pos:]
+	commentLen := int64(binary.LittleEndian.Uint16(b[20:]))
+	eocd := &endOfCentralDirectory{
+		offset:      size - searchLen + int64(pos),
+		disk:        uint32(binary.LittleEndian.Uint16(b[4:])),
+		cdDisk:      uint32(binary.LittleEndian.Uint16(b[6:])),
+		diskEntries: uint64(binary.LittleEndian.Uint16(b[8:])),
+		entries:     uint64(binary.LittleEndian.Uint16(b[10:])),
+		cdSize:      uint64(binary.LittleEndian.Uint32(b[12:])),
+		cdOffset:    uint64(binary.LittleEndian.Uint32(b[16:])),
+		comment:     string(b[eocdLen : eocdLen+commentLen]),
+	}
+	eocd.end = eocd.offset + eocdLen + commentLen
+
+	cdEnd := eocd.offset
+	if eocd.disk == 0xffff || eocd.entries == 0xffff || eocd.cdSize == 0xffffffff || eocd.cdOffset == 0xffffffff {
+		if offset, ok := readEOCD64(r, eocd); ok {
+			cdEnd = offset
+		}
+	}
+
+	// The offsets of split archives are relative to their segments
+	if eocd.disk > 0 {
+		return eocd, nil
+	}
+
+	eocd.baseOffset = cdEnd - int64(eocd.cdSize) - int64(eocd.cdOffset)
+	if eocd.baseOffset < 0 || (eocd.entries > 0 && eocd.baseOffset+int64(eocd.cdOffset) >= size) {
+		return nil, zip.ErrFormat
+	}
+
+	// Like archive/zip, prefer no prepended data when the recorded offset
+	// already points at a central directory header.
+	if eocd.baseOffset > 0 && eocd.entries > 0 {
+		sig := make([]byte, 4)
+		if _, err := r.ReadAt(sig, int64(eocd.cdOffset)); err == nil && binary.LittleEndian.Uint32(sig) == centralHeaderSignature {
+			eocd.baseOffset = 0
+		}
+	}
+
+	return eocd, nil
+}
+
+// readEOCD64 reads the ZIP64 end of central directory record, if present, and
+// returns its offset.
+func readEOCD64(r io.ReaderAt, eocd *endOfCentralDirectory) (int64, bool) {
+	locatorOffset := eocd.offset - eocd64LocatorLen
+	if locatorOffset < 0 {
+		return 0, false
+	}
+
+	locator := make([]byte, eocd64LocatorLen)
+	if _, err := r.ReadAt(locator, locatorOffset); err != nil {
+		return 0, false
+	}
+
+	if binary.LittleEndian.Uint32(locator) != eocd64LocatorSignature {
+		return 0, false
+	}
+
+	// The recorded offset does not account for prepended data, so fall back
+	// to the record immediately preceding the locator.
+	candidates := []int64{int64(binary.LittleEndian.Uint64(locator[8:])), locatorOffset - eocd64Len}
+	for _, offset := range candidates {
+		if offset < 0 {
+			continue
+		}
+
+		buf := make([]byte, eocd64Len)
+		if _, err := r.ReadAt(buf, offset); err != nil {
+			continue
+		}
+
+		if binary.LittleEndian.Uint32(buf) != eocd64Signature {
+			continue
+		}
+
+		eocd.disk = binary.LittleEndian.Uint32(buf[16:])
+		eocd.cdDisk = binary.LittleEndian.Uint32(buf[20:])
+		eocd.diskEntries = binary.LittleEndian.Uint64(buf[24:])
+		eocd.entries = binary.LittleEndian.Uint64(buf[32:])
+		eocd.cdSize = binary.LittleEndian.Uint64(buf[40:])
+		eocd.cdOffset = binary.LittleEndian.Uint64(buf[48:])
+		eocd.zip64 = true
+
+		return offset, true
+	}
+
+	return 0, false
+}
+
+// rawHeader holds the fields of a local or central directory header that are
+// not exposed by [zip.File].
+type rawHeader struct {
+	Name               string
+	CreatorVersion     uint16
+	ReaderVersion      uint16
+	Flags              uint16
+	Method             uint16
+	ModifiedTime       uint16
+	ModifiedDate       uint16
+	CRC32              uint32
+	CompressedSize64   uint64
+	UncompressedSize64 uint64
+	Extra              []byte
+	Comment            string
+	InternalAttrs      uint16
+	ExternalAttrs      uint32
+	Disk               uint32 // Number of the disk holding the local header. Only set for central headers.
+	Offset             int64  // Offset of the local header, including any prepended data.
+	DataOffset         int64  // Offset of the entry data. Only set for local headers.
+}
+
+// applyZip64 replaces saturated sizes, offsets and disk numbers with the
+// values from the ZIP64 extra field.
+func (h *rawHeader) applyZip64(offset *uint64, disk *uint32) {
+	data, ok := findExtra(h.Extra, zip64ExtraID)
+	if !ok {
+		return
+	}
+
+	next := func(field *uint64) {
+		if *field == 0xffffffff && len(data) >= 8 {
+			*field = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		}
+	}
+
+	next(&h.UncompressedSize64)
+	next(&h.CompressedSize64)
+	if offset != nil {
+		next(offset)
+	}
+
+	if disk != nil && *disk == 0xffff && len(data) >= 4 {
+		*disk = binary.LittleEndian.Uint32(data)
+	}
+}
+
+// readCentralDirectory reads every central directory header of an archive.
+func readCentralDirectory(r io.ReaderAt, eocd *endOfCentralDirectory) ([]*rawHeader, error) {
+	if eocd.cdSize > maxCentralDirectoryRead {
+		return nil, zip.ErrFormat
+	}
+
+	buf := make([]byte, eocd.cdSize)
+	if _, err := r.ReadAt(buf, eocd.baseOffset+int64(eocd.cdOffset)); err != nil {
+		return nil, err
+	}
+
+	var headers []*rawHeader
+	for len(buf) >= centralHeaderLen && binary.LittleEndian.Uint32(buf) == centralHeaderSignature {
+		nameLen := int(binary.LittleEndian.Uint16(buf[28:]))
+		extraLen := int(binary.LittleEndian.Uint16(buf[30:]))
+		commentLen := int(binary.LittleEndian.Uint16(buf[32:]))
+		total := centralHeaderLen + nameLen + extraLen + commentLen
+		if len(buf) < total {
+			return nil, zip.ErrFormat
+		}
+
+		h := &rawHeader{
+			CreatorVersion:     binary.LittleEndian.Uint16(buf[4:]),
+			ReaderVersion:      binary.LittleEndian.Uint16(buf[6:]),
+			Flags:              binary.LittleEndian.Uint16(buf[8:]),
+			Method:             binary.LittleEndian.Uint16(buf[10:]),
+			ModifiedTime:       binary.LittleEndian.Uint16(buf[12:]),
+			ModifiedDate:       binary.LittleEndian.Uint16(buf[14:]),
+			CRC32:              binary.LittleEndian.Uint32(buf[16:]),
+			CompressedSize64:   uint64(binary.LittleEndian.Uint32(buf[20:])),
+			UncompressedSize64: uint64(binary.LittleEndian.Uint32(buf[24:])),
+			Disk:               uint32(binary.LittleEndian.Uint16(buf[34:])),
+			InternalAttrs:      binary.LittleEndian.Uint16(buf[36:]),
+			ExternalAttrs:      binary.LittleEndian.Uint32(buf[38:]),
+			Name:               string(buf[centralHeaderLen : centralHeaderLen+nameLen]),
+			Extra:              buf[centralHeaderLen+nameLen : centralHeaderLen+nameLen+extraLen],
+			Comment:            string(buf[centralHeaderLen+nameLen+extraLen : total]),
+		}
+
+		offset := uint64(binary.LittleEndian.Uint32(buf[42:]))
+		h.applyZip64(&offset, &h.Disk)
+		h.Offset = int64(offset) + eocd.baseOffset
+
+		headers = append(headers, h)
+		buf = buf[total:]
+	}
+
+	return headers, nil
+}
+
+// readLocalHeader reads the local file header at the given offset.
+func readLocalHeader(r io.ReaderAt, offset int64) (*rawHeader, error) {
+	buf := make([]byte, localHeaderLen)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+
+	if binary.LittleEndian.Uint32(buf) != localHeaderSignature {
+		return nil, errors.New("local header signature not found")
+	}
+
+	nameLen := int64(binary.LittleEndian.Uint16(buf[26:]))
+	extraLen := int64(binary.LittleEndian.Uint16(buf[28:]))
+
+	variable := make([]byte, nameLen+extraLen)
+	if _, err := r.ReadAt(variable, offset+localHeaderLen); err != nil {
+		return nil, err
+	}
+
+	h := &rawHeader{
+		ReaderVersion:      binary.LittleEndian.Uint16(buf[4:]),
+		Flags:              binary.LittleEndian.Uint16(buf[6:]),
+		Method:             binary.LittleEndian.Uint16(buf[8:]),
+		ModifiedTime:       binary.LittleEndian.Uint16(buf[10:]),
+		ModifiedDate:       binary.LittleEndian.Uint16(buf[12:]),
+		CRC32:              binary.LittleEndian.Uint32(buf[14:]),
+		CompressedSize64:   uint64(binary.LittleEndian.Uint32(buf[18:])),
+		UncompressedSize64: uint64(binary.LittleEndian.Uint32(buf[22:])),
+		Name:               string(variable[:nameLen]),
+		Extra:              variable[nameLen:],
+		Offset:             offset,
+		DataOffset:         offset + localHeaderLen + nameLen + extraLen,
+	}
+	h.applyZip64(nil, nil)
+
+	return h, nil
+}
+
+// dataDescriptorSize returns the size of the data descriptor that follows the
+// data of an entry, reading it to determine whether it has a signature.
+func dataDescriptorSize(r io.ReaderAt, h *rawHeader, dataEnd int64) int64 {
+	if h.Flags&0x8 == 0 {
+		return 0
+	}
+
+	size := int64(dataDescriptorLen)
+	if h.CompressedSize64 >= uint32max || h.UncompressedSize64 >= uint32max {
+		size = dataDescriptor64Len
+	}
+
+	sig := make([]byte, 4)
+	if _, err := r.ReadAt(sig, dataEnd); err == nil && binary.LittleEndian.Uint32(sig) != dataDescriptorSignature {
+		size -= 4
+	}
+
+	return size
+}
@@ -2,7 +2,12 @@ module github.com/engmtcdrm/go-zippy
 
 go 1.25.6
 
-require golang.org/x/sys v0.41.0
+require (
+	github.com/klauspost/compress v1.20.1
+	github.com/ulikunitz/xz v0.5.17
+	golang.org/x/sys v0.41.0
+	golang.org/x/text v0.34.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,120 @@
+package zippy
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestZip creates a zip archive from the given entries, given as name
+// and contents pairs. The entries named in symlinks are stored as symbolic
+// links, with their contents as the link target.
+func createTestZip(t *testing.T, zipFilePath string, entries [][2]string, symlinks ...string) {
+	t.Helper()
+
+	zipFile, err := os.Create(zipFilePath)
+	assert.NoError(t, err)
+	defer zipFile.Close()
+
+	zWriter := zip.NewWriter(zipFile)
+	defer zWriter.Close()
+
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry[0], Method: zip.Deflate}
+		if slices.Contains(symlinks, entry[0]) {
+			header.SetMode(os.ModeSymlink | 0777)
+		}
+
+		writer, err := zWriter.CreateHeader(header)
+		assert.NoError(t, err)
+
+		_, err = io.WriteString(writer, entry[1])
+		assert.NoError(t, err)
+	}
+}
+
+// createRawZip writes a zip archive with a single entry whose header is
+// written as given, without checking it against the data. The uncompressed
+// size is the length of data unless the header sets it.
+func createRawZip(t *testing.T, path string, header *zip.FileHeader, data string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+
+	zWriter := zip.NewWriter(file)
+
+	header.CompressedSize64 = uint64(len(data))
+	if header.UncompressedSize64 == 0 {
+		header.UncompressedSize64 = uint64(len(data))
+	}
+
+	writer, err := zWriter.CreateRaw(header)
+	assert.NoError(t, err)
+
+	_, err = writer.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, zWriter.Close())
+}
+
+// readTestZip returns the contents of each entry of a zip archive, keyed by
+// entry name. The contents are read without decompressing them if raw is set.
+func readTestZip(t *testing.T, zipFilePath string, raw bool) map[string]string {
+	t.Helper()
+
+	zipReader, err := zip.OpenReader(zipFilePath)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer zipReader.Close()
+
+	entries := make(map[string]string)
+	for _, file := range zipReader.File {
+		var reader io.Reader
+		if raw {
+			reader, err = file.OpenRaw()
+		} else {
+			reader, err = file.Open()
+		}
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		data, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+
+		if closer, ok := reader.(io.Closer); ok {
+			closer.Close()
+		}
+
+		entries[file.Name] = string(data)
+	}
+
+	return entries
+}
+
+// readTestZipFiles returns the entries of a zip archive, keyed by entry name.
+// The archive is closed when the test finishes.
+func readTestZipFiles(t *testing.T, zipFilePath string) map[string]*zip.File {
+	t.Helper()
+
+	zipReader, err := zip.OpenReader(zipFilePath)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	t.Cleanup(func() { zipReader.Close() })
+
+	files := make(map[string]*zip.File)
+	for _, file := range zipReader.File {
+		files[file.Name] = file
+	}
+
+	return files
+}
@@ -0,0 +1,147 @@
+package zippy
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// MergeConflict controls which entry is kept when several sources of a merge
+// have an entry with the same name.
+type MergeConflict uint8
+
+const (
+	MergeFirstWins MergeConflict = iota // MergeFirstWins keeps the entry of the first source.
+	MergeLastWins                       // MergeLastWins keeps the entry of the last source.
+	MergeError                          // MergeError fails the merge with [ErrEntryExists].
+	MergeRename                         // MergeRename keeps every entry, adding a numeric suffix to later names.
+)
+
+// MergeOptions specifies how archives are merged.
+type MergeOptions struct {
+	Prefixes   []string      // Prefixes are prepended to the entry names of each source, in the order of the sources. Missing or empty prefixes leave names unchanged.
+	Conflict   MergeConflict // Conflict specifies how duplicate entry names are resolved. Directory entries are always merged.
+	ForceZip64 bool          // ForceZip64 writes ZIP64 records for every entry in the central directory, even when the merged archive does not need them. Local headers only hold them for entries that need them.
+}
+
+// mergedEntry is an entry to copy to a merged archive.
+type mergedEntry struct {
+	file *zip.File
+	name string
+}
+
+// Merges several zip archives into a new zip archive, keeping the entry of
+// the first source when names collide.
+//
+// dest is the new zip archive path. sources are the zip archives to merge.
+func Merge(dest string, sources ...string) error {
+	return MergeWithOptions(dest, nil, sources...)
+}
+
+// Merges several zip archives into a new zip archive. Entries are copied raw,
+// so their data is never decompressed or decrypted.
+//
+// dest is the new zip archive path, which is replaced once the merge is
+// complete. sources are the zip archives to merge.
+func MergeWithOptions(dest string, options *MergeOptions, sources ...string) error {
+	if dest == "" {
+		return ErrEmptyPath
+	}
+
+	if options == nil {
+		options = &MergeOptions{}
+	}
+
+	var entries []*mergedEntry
+	var readers []io.Closer
+	indexes := make(map[string]int)
+
+	for i, source := range sources {
+		zReader, err := zip.OpenReader(source)
+		if err != nil {
+			return err
+		}
+		defer zReader.Close()
+
+		readers = append(readers, zReader)
+
+		prefix := ""
+		if i < len(options.Prefixes) {
+			prefix = strings.Trim(options.Prefixes[i], "/")
+		}
+
+		if prefix != "" && !fs.ValidPath(prefix) {
+			return fmt.Errorf("failed to merge '%s' with prefix '%s': %w", source, prefix, ErrInvalidName)
+		}
+
+		for _, file := range zReader.File {
+			name := file.Name
+			if prefix != "" {
+				name = prefix + "/" + name
+			}
+
+			entry := &mergedEntry{file: file, name: name}
+
+			index, exists := indexes[name]
+			if !exists {
+				indexes[name] = len(entries)
+				entries = append(entries, entry)
+				continue
+			}
+
+			if file.FileInfo().IsDir() {
+				continue
+			}
+
+			switch options.Conflict {
+			case MergeFirstWins:
+				// Keep the entry already chosen
+			case MergeLastWins:
+				entries[index] = entry
+			case MergeError:
+				return fmt.Errorf("failed to merge '%s' from '%s': %w", name, source, ErrEntryExists)
+			case MergeRename:
+				entry.name = freeName(name, indexes)
+				indexes[entry.name] = len(entries)
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	var finish func(file *os.File) error
+	if options.ForceZip64 {
+		finish = forceZip64
+	}
+
+	return writeArchive(dest, "zippy-*", readers, finish, func(zWriter *zip.Writer) error {
+		for _, entry := range entries {
+			if entry.name != entry.file.Name {
+				renameFile(entry.file, entry.name)
+			}
+
+			if err := copyRaw(zWriter, entry.file); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// freeName returns the first name not in use made by adding a numeric suffix
+// before the extension of name.
+func freeName(name string, used map[string]int) string {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for n := 1; ; n++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, n, ext)
+		if _, ok := used[candidate]; !ok {
+			return candidate
+		}
+	}
+}
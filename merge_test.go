@@ -0,0 +1,104 @@
+package zippy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [Merge] and [MergeWithOptions] functions.
+func Test_Merge(t *testing.T) {
+	createSources := func(t *testing.T) (string, string, string) {
+		tempDir := t.TempDir()
+		first := filepath.Join(tempDir, "first.zip")
+		second := filepath.Join(tempDir, "second.zip")
+
+		createTestZip(t, first, [][2]string{{"bin/", ""}, {"bin/tool", "tool v1"}, {"README", "first"}})
+		createTestZip(t, second, [][2]string{{"bin/", ""}, {"bin/other", "other"}, {"README", "second"}})
+
+		return tempDir, first, second
+	}
+
+	t.Run("first wins", func(t *testing.T) {
+		tempDir, first, second := createSources(t)
+		dest := filepath.Join(tempDir, "merged.zip")
+
+		assert.NoError(t, Merge(dest, first, second))
+		assert.Equal(t, map[string]string{"bin/": "", "bin/tool": "tool v1", "README": "first", "bin/other": "other"}, readTestZip(t, dest, false))
+	})
+
+	t.Run("last wins", func(t *testing.T) {
+		tempDir, first, second := createSources(t)
+		dest := filepath.Join(tempDir, "merged.zip")
+
+		assert.NoError(t, MergeWithOptions(dest, &MergeOptions{Conflict: MergeLastWins}, first, second))
+		assert.Equal(t, "second", readTestZip(t, dest, false)["README"])
+	})
+
+	t.Run("error", func(t *testing.T) {
+		tempDir, first, second := createSources(t)
+		dest := filepath.Join(tempDir, "merged.zip")
+
+		assert.ErrorIs(t, MergeWithOptions(dest, &MergeOptions{Conflict: MergeError}, first, second), ErrEntryExists)
+
+		_, err := os.Stat(dest)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		tempDir, first, second := createSources(t)
+		dest := filepath.Join(tempDir, "merged.zip")
+
+		assert.NoError(t, MergeWithOptions(dest, &MergeOptions{Conflict: MergeRename}, first, second, first))
+
+		entries := readTestZip(t, dest, false)
+		assert.Equal(t, "first", entries["README"])
+		assert.Equal(t, "second", entries["README_1"])
+		assert.Equal(t, "first", entries["README_2"])
+		assert.Equal(t, "tool v1", entries["bin/tool_1"])
+	})
+
+	t.Run("prefixes", func(t *testing.T) {
+		tempDir, first, second := createSources(t)
+		dest := filepath.Join(tempDir, "merged.zip")
+
+		assert.NoError(t, MergeWithOptions(dest, &MergeOptions{Prefixes: []string{"", "plugins/second/"}}, first, second))
+		assert.Equal(t, map[string]string{
+			"bin/":                     "",
+			"bin/tool":                 "tool v1",
+			"README":                   "first",
+			"plugins/second/bin/":      "",
+			"plugins/second/bin/other": "other",
+			"plugins/second/README":    "second",
+		}, readTestZip(t, dest, false))
+
+		assert.ErrorIs(t, MergeWithOptions(dest, &MergeOptions{Prefixes: []string{"../up"}}, first), ErrInvalidName)
+	})
+
+	t.Run("raw copy", func(t *testing.T) {
+		tempDir := t.TempDir()
+		source := createAESTestFile(t, tempDir, AES256, "secret")
+		dest := filepath.Join(tempDir, "merged.zip")
+
+		assert.NoError(t, MergeWithOptions(dest, &MergeOptions{Prefixes: []string{"enc"}}, source))
+
+		before := readTestZip(t, source, true)
+		after := readTestZip(t, dest, true)
+		assert.Equal(t, before["secret.txt"], after["enc/secret.txt"])
+
+		u, err := NewUnzippy(dest, &UnzippyOptions{Password: "secret"})
+		assert.NoError(t, err)
+		report, err := u.Verify()
+		assert.NoError(t, err)
+		assert.True(t, report.OK())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tempDir, first, _ := createSources(t)
+
+		assert.ErrorIs(t, Merge("", first), ErrEmptyPath)
+		assert.Error(t, Merge(filepath.Join(tempDir, "merged.zip"), first, filepath.Join(tempDir, "missing.zip")))
+	})
+}
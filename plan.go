@@ -0,0 +1,139 @@
+package zippy
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+)
+
+// PlanAction is what an operation would do with an entry.
+type PlanAction uint8
+
+const (
+	PlanAdd     PlanAction = iota + 1 // PlanAdd is an entry added from a file, or a missing directory entry added by [DirEntriesSynthesize].
+	PlanReplace                       // PlanReplace is an entry replaced by a file.
+	PlanDelete                        // PlanDelete is an entry removed from the archive.
+	PlanKeep                          // PlanKeep is an existing entry copied to the archive written.
+	PlanWrite                         // PlanWrite is an entry extracted to a file.
+)
+
+// planActionNames maps plan actions to their names.
+var planActionNames = map[PlanAction]string{
+	PlanAdd:     "add",
+	PlanReplace: "replace",
+	PlanDelete:  "delete",
+	PlanKeep:    "keep",
+	PlanWrite:   "write",
+}
+
+// String returns the name of the plan action.
+func (a PlanAction) String() string {
+	if name, ok := planActionNames[a]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("PlanAction(%d)", uint8(a))
+}
+
+// MarshalText encodes the plan action as its name.
+func (a PlanAction) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// PlanStep is a single entry an operation would write, keep or remove.
+type PlanStep struct {
+	Action   PlanAction `json:"action"`             // What would be done with the entry.
+	Entry    string     `json:"entry"`              // Name of the entry.
+	Path     string     `json:"path,omitempty"`     // File the entry would be read from or extracted to, if any.
+	Conflict bool       `json:"conflict,omitempty"` // Whether Path already exists and would be overwritten, or fail the extraction with [UnzippyOptions.FailExisting].
+}
+
+// Plan lists what an operation run with the DryRun option of [Zippy] or
+// [UnzippyOptions] would do. A plan without steps changes nothing.
+type Plan struct {
+	Archive    string     `json:"archive"`             // Archive that would be read.
+	Dest       string     `json:"dest"`                // Archive or directory that would be written.
+	DestExists bool       `json:"dest_exists"`         // Whether Dest already exists.
+	Steps      []PlanStep `json:"steps"`               // Entries in the order they would be handled.
+	Unmatched  []string   `json:"unmatched,omitempty"` // Patterns that match no entry.
+}
+
+// Count returns the number of steps with the given action.
+func (p *Plan) Count(action PlanAction) int {
+	count := 0
+	for _, step := range p.Steps {
+		if step.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Conflicts returns the steps whose path already exists.
+func (p *Plan) Conflicts() []PlanStep {
+	var conflicts []PlanStep
+	for _, step := range p.Steps {
+		if step.Conflict {
+			conflicts = append(conflicts, step)
+		}
+	}
+
+	return conflicts
+}
+
+// add appends a step to the plan.
+func (p *Plan) add(action PlanAction, entry, path string, conflict bool) {
+	p.Steps = append(p.Steps, PlanStep{Action: action, Entry: entry, Path: path, Conflict: conflict})
+}
+
+// newPlan returns an empty plan of an operation reading archive and writing
+// dest.
+func newPlan(archive, dest string) *Plan {
+	_, err := os.Lstat(dest)
+
+	return &Plan{Archive: archive, Dest: dest, DestExists: err == nil}
+}
+
+// dryRun runs fn while recording the entries it would write in a new plan
+// instead of writing them, then sets [Zippy.Plan] to the plan.
+func (z *Zippy) dryRun(dest string, fn func() error) error {
+	z.plan = newPlan(z.Path, dest)
+	defer func() {
+		z.plan = nil
+		z.existingFiles = make(map[string]*zip.File)
+	}()
+
+	if err := fn(); err != nil {
+		return err
+	}
+
+	z.Plan = z.plan
+
+	return nil
+}
+
+// planAdd records the entries [Zippy.Add] would keep and add.
+func (z *Zippy) planAdd(files ...string) error {
+	zReader, err := zip.OpenReader(z.Path)
+	if err == nil {
+		defer zReader.Close()
+
+		for _, file := range zReader.File {
+			z.existingFiles[file.Name] = file
+			z.plan.add(PlanKeep, file.Name, "", false)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return z.zipFiles(files...)
+}
+
+// planExtract records writing an extracted entry to path, which conflicts
+// with an existing file, or with anything but a directory for directory
+// entries.
+func planExtract(plan *Plan, name, path string, isDir bool) {
+	info, err := os.Lstat(path)
+	plan.add(PlanWrite, name, path, err == nil && !(isDir && info.IsDir()))
+}
@@ -0,0 +1,279 @@
+package zippy
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// planSteps returns the actions of the steps of a plan, keyed by entry name.
+func planSteps(plan *Plan) map[string]PlanAction {
+	steps := make(map[string]PlanAction)
+	for _, step := range plan.Steps {
+		steps[step.Entry] = step.Action
+	}
+
+	return steps
+}
+
+// assertNotWritten checks that the file at path still has the given contents
+// and that no temporary file was left next to it.
+func assertNotWritten(t *testing.T, path string, want []byte) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, want, data)
+
+	temps, err := filepath.Glob(filepath.Join(filepath.Dir(path), "zippy-*"))
+	assert.NoError(t, err)
+	assert.Empty(t, temps)
+}
+
+// Tests for [PlanAction] type.
+func Test_PlanAction(t *testing.T) {
+	assert.Equal(t, "replace", PlanReplace.String())
+	assert.Equal(t, "PlanAction(0)", PlanAction(0).String())
+
+	data, err := json.Marshal(PlanStep{Action: PlanWrite, Entry: "a.txt"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"action": "write", "entry": "a.txt"}`, string(data))
+}
+
+// Tests for [Zippy.DryRun] option.
+func Test_Zippy_DryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "archive.zip")
+	createTestZip(t, zipPath, [][2]string{
+		{"dir/", ""},
+		{"dir/a.txt", "alpha"},
+		{"b.txt", "bravo"},
+	})
+
+	original, err := os.ReadFile(zipPath)
+	assert.NoError(t, err)
+
+	t.Run("delete", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.DryRun = true
+
+		assert.NoError(t, z.Delete("dir/a.txt", "missing.txt"))
+		assertNotWritten(t, zipPath, original)
+
+		if assert.NotNil(t, z.Plan) {
+			assert.Equal(t, zipPath, z.Plan.Dest)
+			assert.Equal(t, map[string]PlanAction{"dir/": PlanKeep, "dir/a.txt": PlanDelete, "b.txt": PlanKeep}, planSteps(z.Plan))
+			assert.Equal(t, []string{"missing.txt"}, z.Plan.Unmatched)
+		}
+	})
+
+	t.Run("delete without match", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.DryRun = true
+
+		assert.NoError(t, z.Delete("missing.txt"))
+		if assert.NotNil(t, z.Plan) {
+			assert.Empty(t, z.Plan.Steps)
+		}
+	})
+
+	t.Run("delete unmatched", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.DryRun = true
+		z.FailUnmatched = true
+
+		assert.ErrorIs(t, z.Delete("missing.txt"), ErrNoMatch)
+		assert.Nil(t, z.Plan)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.DryRun = true
+		dest := filepath.Join(tempDir, "copy", "copy.zip")
+
+		assert.NoError(t, z.Copy(dest, "dir/*"))
+		assert.NoDirExists(t, filepath.Dir(dest))
+
+		if assert.NotNil(t, z.Plan) {
+			assert.Equal(t, dest, z.Plan.Dest)
+			assert.False(t, z.Plan.DestExists)
+			assert.Equal(t, map[string]PlanAction{"dir/": PlanKeep, "dir/a.txt": PlanKeep}, planSteps(z.Plan))
+		}
+
+		assert.NoError(t, z.Copy(zipPath))
+		if assert.NotNil(t, z.Plan) {
+			assert.True(t, z.Plan.DestExists)
+			assert.Equal(t, 3, z.Plan.Count(PlanKeep))
+		}
+	})
+
+	sourceDir := filepath.Join(tempDir, "source")
+	assert.NoError(t, os.MkdirAll(sourceDir, 0755))
+	newPath := filepath.Join(sourceDir, "new.txt")
+	assert.NoError(t, os.WriteFile(newPath, []byte("new"), 0644))
+
+	t.Run("add", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.DryRun = true
+		z.Junk = true
+
+		assert.NoError(t, z.Add(newPath))
+		assertNotWritten(t, zipPath, original)
+
+		if assert.NotNil(t, z.Plan) {
+			assert.Equal(t, map[string]PlanAction{"dir/": PlanKeep, "dir/a.txt": PlanKeep, "b.txt": PlanKeep, "new.txt": PlanAdd}, planSteps(z.Plan))
+			assert.Equal(t, PlanStep{Action: PlanAdd, Entry: "new.txt", Path: newPath}, z.Plan.Steps[3])
+		}
+	})
+
+	t.Run("add new archive", func(t *testing.T) {
+		z := NewZippy(filepath.Join(tempDir, "new", "new.zip"))
+		z.DryRun = true
+		z.Junk = true
+
+		assert.NoError(t, z.Add(newPath))
+		assert.NoDirExists(t, filepath.Join(tempDir, "new"))
+
+		if assert.NotNil(t, z.Plan) {
+			assert.Equal(t, map[string]PlanAction{"new.txt": PlanAdd}, planSteps(z.Plan))
+		}
+	})
+
+	t.Run("sync", func(t *testing.T) {
+		syncPath := filepath.Join(tempDir, "sync.zip")
+		keepPath := filepath.Join(sourceDir, "keep.txt")
+		assert.NoError(t, os.WriteFile(keepPath, []byte("keep"), 0644))
+
+		z := NewZippy(syncPath)
+		_, err := z.Sync(sourceDir)
+		assert.NoError(t, err)
+
+		synced, err := os.ReadFile(syncPath)
+		assert.NoError(t, err)
+
+		assert.NoError(t, os.Remove(keepPath))
+		assert.NoError(t, os.WriteFile(newPath, []byte("changed"), 0644))
+		assert.NoError(t, os.Chtimes(newPath, time.Now(), time.Now().Add(time.Hour)))
+
+		z.DryRun = true
+		report, err := z.Sync(sourceDir)
+		assert.NoError(t, err)
+		assertNotWritten(t, syncPath, synced)
+
+		assert.Equal(t, []string{toZipPath(newPath)}, report.Updated)
+		assert.Equal(t, []string{toZipPath(keepPath)}, report.Removed)
+
+		if assert.NotNil(t, z.Plan) {
+			steps := planSteps(z.Plan)
+			assert.Equal(t, PlanKeep, steps[toZipPath(sourceDir)+"/"])
+			assert.Equal(t, PlanReplace, steps[toZipPath(newPath)])
+			assert.Equal(t, PlanDelete, steps[toZipPath(keepPath)])
+		}
+	})
+}
+
+// Tests for [UnzippyOptions.DryRun] option.
+func Test_Unzippy_DryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "archive.zip")
+	createTestZip(t, zipPath, [][2]string{
+		{"dir/", ""},
+		{"dir/a.txt", "alpha"},
+		{"b.txt", "bravo"},
+	})
+
+	dest := filepath.Join(tempDir, "dest")
+
+	t.Run("new destination", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{DryRun: true})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractFilesTo(dest, "dir/*", "missing.txt")
+		assert.NoError(t, err)
+		assert.Len(t, files, 2)
+		assert.NoDirExists(t, dest)
+
+		if assert.NotNil(t, u.Plan) {
+			assert.False(t, u.Plan.DestExists)
+			assert.Equal(t, []PlanStep{
+				{Action: PlanWrite, Entry: "dir/", Path: filepath.Join(dest, "dir")},
+				{Action: PlanWrite, Entry: "dir/a.txt", Path: filepath.Join(dest, "dir", "a.txt")},
+			}, u.Plan.Steps)
+			assert.Equal(t, []string{"missing.txt"}, u.Plan.Unmatched)
+		}
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dest, "dir"), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dest, "b.txt"), []byte("existing"), 0644))
+
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{DryRun: true, Junk: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(dest)
+		assert.NoError(t, err)
+
+		if assert.NotNil(t, u.Plan) {
+			assert.True(t, u.Plan.DestExists)
+			assert.Equal(t, []PlanStep{{Action: PlanWrite, Entry: "b.txt", Path: filepath.Join(dest, "b.txt"), Conflict: true}}, u.Plan.Conflicts())
+			assert.Equal(t, filepath.Join(dest, "a.txt"), u.Plan.Steps[1].Path)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dest, "b.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "existing", string(data))
+	})
+
+	t.Run("unsafe path", func(t *testing.T) {
+		unsafePath := filepath.Join(tempDir, "unsafe.zip")
+		createTestZip(t, unsafePath, [][2]string{{"../evil.txt", "evil"}})
+
+		u, err := NewUnzippy(unsafePath, &UnzippyOptions{DryRun: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(dest)
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
+
+	t.Run("tar", func(t *testing.T) {
+		tarPath := filepath.Join(tempDir, "archive.tar")
+		createTarFile(t, tarPath, TarUncompressed,
+			tarTestEntry{header: &tar.Header{Name: "dir/", Typeflag: tar.TypeDir}},
+			tarTestEntry{header: &tar.Header{Name: "b.txt", Typeflag: tar.TypeReg}, body: "bravo"},
+		)
+
+		u, err := NewUntarry(tarPath, TarUncompressed, &UnzippyOptions{DryRun: true})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractFilesTo(dest, "*", "missing.txt")
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+
+		if assert.NotNil(t, u.Plan) {
+			assert.Equal(t, []PlanStep{{Action: PlanWrite, Entry: "b.txt", Path: filepath.Join(dest, "b.txt"), Conflict: true}}, u.Plan.Steps)
+			assert.Equal(t, []string{"missing.txt"}, u.Plan.Unmatched)
+		}
+
+		data, err := os.ReadFile(filepath.Join(dest, "b.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "existing", string(data))
+	})
+
+	t.Run("tar refused", func(t *testing.T) {
+		tarPath := filepath.Join(tempDir, "link.tar")
+		createTarFile(t, tarPath, TarUncompressed,
+			tarTestEntry{header: &tar.Header{Name: "link", Typeflag: tar.TypeLink, Linkname: "b.txt"}},
+		)
+
+		u, err := NewUntarry(tarPath, TarUncompressed, &UnzippyOptions{DryRun: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(dest)
+		assert.ErrorIs(t, err, ErrRefusedEntry)
+	})
+}
@@ -0,0 +1,161 @@
+package zippy
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// renameFile changes the name a file will be copied with.
+func renameFile(file *zip.File, name string) {
+	file.Name = name
+
+	// The Unicode path field would override the new name
+	file.Extra = stripExtra(file.Extra, unicodePathExtraID)
+
+	if requiresUTF8(name) {
+		file.Flags |= 0x800
+	}
+}
+
+// renameEntries rewrites the archive with new names for some of its entries.
+// The entry data is copied raw, so it is never decompressed or decrypted.
+//
+// rename returns the new name of an entry and whether the entry was selected
+// to be renamed. It fails with [ErrEntryNotFound] if no entry is selected.
+func (z *Zippy) renameEntries(rename func(name string) (string, bool, error)) error {
+	return z.rewrite(func(zReader *zip.ReadCloser, zWriter *zip.Writer) error {
+		newNames := make([]string, len(zReader.File))
+		owners := make(map[string]string, len(zReader.File))
+		selected := 0
+
+		for i, file := range zReader.File {
+			newName, ok, err := rename(file.Name)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				if !fs.ValidPath(strings.TrimSuffix(newName, "/")) {
+					return fmt.Errorf("failed to rename '%s' to '%s': %w", file.Name, newName, ErrInvalidName)
+				}
+
+				selected++
+			} else {
+				newName = file.Name
+			}
+
+			if owner, exists := owners[newName]; exists {
+				return fmt.Errorf("failed to rename '%s' to '%s': already used by '%s': %w", file.Name, newName, owner, ErrEntryExists)
+			}
+
+			owners[newName] = file.Name
+			newNames[i] = newName
+		}
+
+		if selected == 0 {
+			return ErrEntryNotFound
+		}
+
+		for i, file := range zReader.File {
+			if newNames[i] != file.Name {
+				renameFile(file, newNames[i])
+			}
+
+			if err := copyRaw(zWriter, file); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Renames an entry of the archive. Renaming a directory renames all of its
+// contents.
+//
+// oldName and newName are entry names, using forward slashes. Renaming fails
+// if newName is already used by another entry.
+func (z *Zippy) Rename(oldName, newName string) error {
+	oldName = strings.TrimSuffix(oldName, "/")
+	newName = strings.TrimSuffix(newName, "/")
+
+	if newName == oldName || strings.HasPrefix(newName, oldName+"/") {
+		return fmt.Errorf("failed to rename '%s' to '%s': %w", oldName, newName, ErrInvalidName)
+	}
+
+	// Unlike Move, renaming never merges into an existing directory
+	files, err := Contents(z.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if name := strings.TrimSuffix(file.Name, "/"); name == newName || strings.HasPrefix(name, newName+"/") {
+			return fmt.Errorf("failed to rename '%s' to '%s': %w", oldName, newName, ErrEntryExists)
+		}
+	}
+
+	err = z.renameEntries(func(name string) (string, bool, error) {
+		if name == oldName {
+			return newName, true, nil
+		}
+
+		if rest, ok := strings.CutPrefix(name, oldName+"/"); ok {
+			return newName + "/" + rest, true, nil
+		}
+
+		return "", false, nil
+	})
+	if errors.Is(err, ErrEntryNotFound) {
+		return &EntryNotFoundError{EntryError{Op: "rename", Archive: z.Path, Entry: oldName, Err: err}}
+	}
+
+	return err
+}
+
+// Moves the entries matching a pattern into a directory of the archive,
+// keeping their base names. Moving a directory moves all of its contents.
+//
+// srcPattern is matched against entry names and their parent directories.
+// Glob patterns are supported.
+//
+// destDir is the directory to move the entries to, or "" for the root of the
+// archive.
+func (z *Zippy) Move(srcPattern, destDir string) error {
+	destDir = strings.TrimSuffix(destDir, "/")
+
+	if _, err := filepath.Match(srcPattern, ""); err != nil {
+		return fmt.Errorf("failed to match pattern '%s': %w", srcPattern, err)
+	}
+
+	err := z.renameEntries(func(name string) (string, bool, error) {
+		parts := strings.Split(strings.TrimSuffix(name, "/"), "/")
+
+		// The shallowest matching directory is moved along with its contents
+		for i := 1; i <= len(parts); i++ {
+			prefix := strings.Join(parts[:i], "/")
+			if match, _ := filepath.Match(srcPattern, prefix); !match {
+				continue
+			}
+
+			if destDir == prefix || strings.HasPrefix(destDir, prefix+"/") {
+				return "", false, fmt.Errorf("failed to move '%s' into itself: %w", prefix, ErrInvalidName)
+			}
+
+			newName := path.Join(destDir, parts[i-1]) + strings.TrimPrefix(name, prefix)
+			return newName, true, nil
+		}
+
+		return "", false, nil
+	})
+	if errors.Is(err, ErrEntryNotFound) {
+		return &EntryNotFoundError{EntryError{Op: "move", Archive: z.Path, Entry: srcPattern, Err: err}}
+	}
+
+	return err
+}
@@ -0,0 +1,172 @@
+package zippy
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createRenameTestFile creates a zip archive with a directory tree, where the
+// other/ directory has no entry of its own, and a comment on readme.txt.
+func createRenameTestFile(t *testing.T) string {
+	t.Helper()
+
+	zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+
+	var entries [][2]string
+	for _, name := range []string{"docs/", "docs/a.txt", "docs/sub/b.txt", "readme.txt", "notes.txt", "other/c.txt"} {
+		if strings.HasSuffix(name, "/") {
+			entries = append(entries, [2]string{name, ""})
+		} else {
+			entries = append(entries, [2]string{name, strings.Repeat(name, 20)})
+		}
+	}
+
+	createTestZip(t, zipFilePath, entries)
+	assert.NoError(t, NewZippy(zipFilePath).SetEntryComment("readme.txt", "comment readme.txt"))
+
+	return zipFilePath
+}
+
+// sortedNames returns the keys of a map in order.
+func sortedNames(entries map[string]string) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Tests for [Zippy.Rename] function.
+func Test_Zippy_Rename(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+		before := readTestZip(t, zipFilePath, true)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Rename("readme.txt", "docs/README.md"))
+
+		after := readTestZip(t, zipFilePath, true)
+		assert.NotContains(t, after, "readme.txt")
+		assert.Equal(t, before["readme.txt"], after["docs/README.md"])
+
+		comment, err := z.EntryComment("docs/README.md")
+		assert.NoError(t, err)
+		assert.Equal(t, "comment readme.txt", comment)
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+		report, err := u.Verify()
+		assert.NoError(t, err)
+		assert.True(t, report.OK())
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+		before := readTestZip(t, zipFilePath, true)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Rename("docs/", "manual"))
+
+		after := readTestZip(t, zipFilePath, true)
+		assert.Equal(t, []string{"manual/", "manual/a.txt", "manual/sub/b.txt", "notes.txt", "other/c.txt", "readme.txt"}, sortedNames(after))
+		assert.Equal(t, before["docs/sub/b.txt"], after["manual/sub/b.txt"])
+	})
+
+	t.Run("implicit directory", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Rename("other", "misc"))
+		assert.Contains(t, readTestZip(t, zipFilePath, true), "misc/c.txt")
+	})
+
+	t.Run("encrypted entry", func(t *testing.T) {
+		zipFilePath := createAESTestFile(t, t.TempDir(), AES256, "secret")
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Rename("secret.txt", "hidden.txt"))
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Password: "secret"})
+		assert.NoError(t, err)
+		report, err := u.Verify()
+		assert.NoError(t, err)
+		assert.True(t, report.OK())
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+		before := readTestZip(t, zipFilePath, true)
+
+		z := NewZippy(zipFilePath)
+		assert.ErrorIs(t, z.Rename("readme.txt", "notes.txt"), ErrEntryExists)
+		assert.ErrorIs(t, z.Rename("other", "docs"), ErrEntryExists)
+		assert.Equal(t, before, readTestZip(t, zipFilePath, true))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+
+		z := NewZippy(zipFilePath)
+		assert.ErrorIs(t, z.Rename("missing.txt", "found.txt"), ErrEntryNotFound)
+		assert.ErrorIs(t, z.Rename("docs", "docs/inner"), ErrInvalidName)
+		assert.ErrorIs(t, z.Rename("readme.txt", "../readme.txt"), ErrInvalidName)
+		assert.ErrorIs(t, z.Rename("readme.txt", "/readme.txt"), ErrInvalidName)
+	})
+}
+
+// Tests for [Zippy.Move] function.
+func Test_Zippy_Move(t *testing.T) {
+	t.Run("glob", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+		before := readTestZip(t, zipFilePath, true)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Move("*.txt", "text"))
+
+		after := readTestZip(t, zipFilePath, true)
+		assert.Equal(t, []string{"docs/", "docs/a.txt", "docs/sub/b.txt", "other/c.txt", "text/notes.txt", "text/readme.txt"}, sortedNames(after))
+		assert.Equal(t, before["notes.txt"], after["text/notes.txt"])
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Move("docs/sub", ""))
+
+		assert.Equal(t, []string{"docs/", "docs/a.txt", "notes.txt", "other/c.txt", "readme.txt", "sub/b.txt"}, sortedNames(readTestZip(t, zipFilePath, true)))
+	})
+
+	t.Run("nested pattern", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Move("*/*.txt", "flat"))
+
+		assert.Equal(t, []string{"docs/", "docs/sub/b.txt", "flat/a.txt", "flat/c.txt", "notes.txt", "readme.txt"}, sortedNames(readTestZip(t, zipFilePath, true)))
+	})
+
+	t.Run("collision", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Rename("notes.txt", "docs/sub/a.txt"))
+
+		assert.ErrorIs(t, z.Move("*/*/a.txt", "docs"), ErrEntryExists)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		zipFilePath := createRenameTestFile(t)
+
+		z := NewZippy(zipFilePath)
+		assert.ErrorIs(t, z.Move("*.md", "docs"), ErrEntryNotFound)
+		assert.ErrorIs(t, z.Move("docs", "docs/sub"), ErrInvalidName)
+		assert.ErrorIs(t, z.Move("*.txt", ".."), ErrInvalidName)
+		assert.Error(t, z.Move("[", "docs"))
+	})
+}
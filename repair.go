@@ -0,0 +1,354 @@
+package zippy
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// LostEntry describes an entry that could not be recovered from a damaged
+// archive.
+type LostEntry struct {
+	Name   string // Name of the entry, if known.
+	Offset int64  // Offset of the entry's local header in the damaged archive.
+	Err    error  // Reason the entry could not be recovered.
+}
+
+// RepairReport is the result of repairing a zip archive.
+type RepairReport struct {
+	Recovered  []string     // Names of the entries written to the repaired archive.
+	Unverified []string     // Names of recovered entries whose contents could not be checked, such as encrypted entries.
+	Lost       []*LostEntry // Entries that could not be recovered.
+}
+
+// salvagedEntry is an entry found while scanning a damaged archive.
+type salvagedEntry struct {
+	header   *zip.FileHeader
+	data     *io.SectionReader
+	end      int64
+	verified bool
+}
+
+// scanReadSize is the block size used when scanning for local headers.
+const scanReadSize = 64 * 1024
+
+// Repairs a damaged zip archive, like zip -FF, writing every entry that can be
+// recovered to a new archive. The damaged archive is scanned for local file
+// headers, so archives that are truncated or have a broken central directory
+// can still be salvaged. When the central directory is readable it is used to
+// recover names, sizes and attributes stored only there.
+//
+// dest is the path of the repaired archive. It may be the same as the damaged
+// archive.
+//
+// Stored and deflated entries are only recovered if their CRC-32 matches.
+func (z *Zippy) Repair(dest string) (*RepairReport, error) {
+	archive, err := os.Open(z.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	info, err := archive.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+
+	// The central directory is optional, damaged archives often lack one
+	central := make(map[int64]*rawHeader)
+	if eocd, err := readEOCD(archive, size); err == nil {
+		if headers, err := readCentralDirectory(archive, eocd); err == nil {
+			for _, h := range headers {
+				central[h.Offset] = h
+			}
+		}
+	}
+
+	offsets, err := scanSignature(archive, size, localHeaderSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &RepairReport{}
+	var entries []*salvagedEntry
+	var end int64
+
+	for _, offset := range offsets {
+		// Skip signatures found inside the data of a recovered entry
+		if offset < end {
+			continue
+		}
+
+		entry, name, err := salvageEntry(z.Path, archive, size, offset, central[offset])
+		if err != nil {
+			if name != "" || central[offset] != nil {
+				report.Lost = append(report.Lost, &LostEntry{Name: name, Offset: offset, Err: err})
+			}
+			delete(central, offset)
+			continue
+		}
+
+		delete(central, offset)
+		entries = append(entries, entry)
+		end = entry.end
+
+		report.Recovered = append(report.Recovered, entry.header.Name)
+		if !entry.verified {
+			report.Unverified = append(report.Unverified, entry.header.Name)
+		}
+	}
+
+	// Entries left in the central directory are reported in offset order too
+	for _, offset := range slices.Sorted(maps.Keys(central)) {
+		report.Lost = append(report.Lost, &LostEntry{Name: central[offset].Name, Offset: offset, Err: errors.New("local header not found")})
+	}
+
+	if err := writeSalvaged(dest, z.tempFile, []io.Closer{archive}, entries, z.finish); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// scanSignature returns the offsets of every occurrence of a record signature.
+func scanSignature(r io.ReaderAt, size int64, signature uint32) ([]int64, error) {
+	var sig [4]byte
+	binary.LittleEndian.PutUint32(sig[:], signature)
+
+	var offsets []int64
+	buf := make([]byte, scanReadSize+len(sig)-1)
+
+	for base := int64(0); base < size; base += scanReadSize {
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-base)], base)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		block := buf[:n]
+		for i := 0; ; {
+			j := bytes.Index(block[i:], sig[:])
+			if j < 0 || i+j >= scanReadSize {
+				break
+			}
+
+			offsets = append(offsets, base+int64(i+j))
+			i += j + 1
+		}
+	}
+
+	return offsets, nil
+}
+
+// salvageEntry attempts to recover the entry whose local header is at offset.
+// It returns the name of the entry, when known, along with any error.
+//
+// archive is the path of the damaged archive r reads, reported in errors.
+func salvageEntry(archive string, r io.ReaderAt, size int64, offset int64, central *rawHeader) (*salvagedEntry, string, error) {
+	local, err := readLocalHeader(r, offset)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Prefer the central directory, local sizes are unset with data descriptors
+	h := local
+	if central != nil {
+		h = central
+	} else if local.Flags&0x8 != 0 {
+		local.CompressedSize64 = 0
+		local.UncompressedSize64 = 0
+		local.CRC32 = 0
+	}
+
+	if len(local.Name) == 0 {
+		return nil, "", errors.New("empty entry name")
+	}
+
+	compressedSize := int64(h.CompressedSize64)
+	crc := h.CRC32
+	uncompressedSize := h.UncompressedSize64
+	encrypted := h.Flags&0x1 != 0
+
+	if central == nil && local.Flags&0x8 != 0 {
+		if h.Method == zip.Deflate && !encrypted {
+			compressedSize, err = deflatedLength(r, local.DataOffset, size)
+		} else {
+			compressedSize, err = descriptorLength(r, local.DataOffset, size)
+		}
+
+		if err != nil {
+			return nil, h.Name, err
+		}
+
+		crc, uncompressedSize = readDescriptor(r, local.DataOffset+compressedSize)
+	}
+
+	dataEnd := local.DataOffset + compressedSize
+	if compressedSize < 0 || dataEnd > size {
+		return nil, h.Name, io.ErrUnexpectedEOF
+	}
+
+	entry := &salvagedEntry{
+		data: io.NewSectionReader(r, local.DataOffset, compressedSize),
+		end:  dataEnd + dataDescriptorSize(r, local, dataEnd),
+	}
+
+	if !encrypted && (h.Method == zip.Store || h.Method == zip.Deflate) {
+		if err := checkSalvaged(archive, h.Name, entry.data, h.Method, crc, uncompressedSize); err != nil {
+			return nil, h.Name, err
+		}
+		entry.verified = true
+	}
+
+	entry.header = &zip.FileHeader{
+		Name:               h.Name,
+		Comment:            h.Comment,
+		CreatorVersion:     h.CreatorVersion,
+		ReaderVersion:      local.ReaderVersion,
+		Flags:              h.Flags &^ 0x8,
+		Method:             h.Method,
+		ModifiedTime:       h.ModifiedTime,
+		ModifiedDate:       h.ModifiedDate,
+		CRC32:              crc,
+		CompressedSize64:   uint64(compressedSize),
+		UncompressedSize64: uncompressedSize,
+		Extra:              stripExtra(h.Extra, zip64ExtraID),
+		ExternalAttrs:      h.ExternalAttrs,
+	}
+
+	return entry, h.Name, nil
+}
+
+// byteCounter is a buffered reader that counts the bytes consumed from it.
+// It implements [io.ByteReader] so a flate reader does not read ahead.
+type byteCounter struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *byteCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *byteCounter) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// deflatedLength returns the length of the deflate stream starting at offset.
+func deflatedLength(r io.ReaderAt, offset, size int64) (int64, error) {
+	counter := &byteCounter{r: bufio.NewReader(io.NewSectionReader(r, offset, size-offset))}
+	if _, err := io.Copy(io.Discard, flate.NewReader(counter)); err != nil {
+		return 0, err
+	}
+
+	return counter.n, nil
+}
+
+// descriptorLength finds the data descriptor that follows entry data starting
+// at offset, and returns the length of the data.
+func descriptorLength(r io.ReaderAt, offset, size int64) (int64, error) {
+	candidates, err := scanSignature(io.NewSectionReader(r, offset, size-offset), size-offset, dataDescriptorSignature)
+	if err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, dataDescriptorLen)
+	for _, candidate := range candidates {
+		if _, err := r.ReadAt(buf, offset+candidate); err != nil {
+			continue
+		}
+
+		if int64(binary.LittleEndian.Uint32(buf[8:])) == candidate {
+			return candidate, nil
+		}
+	}
+
+	return 0, errors.New("data descriptor not found")
+}
+
+// readDescriptor reads the CRC-32 and uncompressed size from a data
+// descriptor.
+func readDescriptor(r io.ReaderAt, offset int64) (uint32, uint64) {
+	buf := make([]byte, dataDescriptorLen)
+	if _, err := r.ReadAt(buf, offset); err != nil {
+		return 0, 0
+	}
+
+	if binary.LittleEndian.Uint32(buf) != dataDescriptorSignature {
+		buf = append(make([]byte, 4), buf[:12]...)
+	}
+
+	return binary.LittleEndian.Uint32(buf[4:]), uint64(binary.LittleEndian.Uint32(buf[12:]))
+}
+
+// checkSalvaged decompresses the data of a salvaged entry and checks its
+// CRC-32 and size, failing with a [ChecksumError] or [SizeError], or an
+// [EntryError] if the data cannot be decompressed.
+//
+// archive and name are the archive and entry the data is salvaged from.
+func checkSalvaged(archive, name string, data *io.SectionReader, method uint16, crc uint32, uncompressedSize uint64) error {
+	reader := io.Reader(io.NewSectionReader(data, 0, data.Size()))
+	if method == zip.Deflate {
+		fr := flate.NewReader(reader)
+		defer fr.Close()
+		reader = fr
+	}
+
+	hash := crc32.NewIEEE()
+	written, err := io.Copy(hash, reader)
+	if err != nil {
+		return &EntryError{Op: "repair", Archive: archive, Entry: name, Err: err}
+	}
+
+	if hash.Sum32() != crc {
+		return &ChecksumError{
+			EntryError: EntryError{Op: "repair", Archive: archive, Entry: name},
+			Expected:   crc,
+			Actual:     hash.Sum32(),
+		}
+	}
+
+	if uint64(written) != uncompressedSize {
+		return &SizeError{
+			EntryError: EntryError{Op: "repair", Archive: archive, Entry: name},
+			Expected:   int64(uncompressedSize),
+			Actual:     written,
+		}
+	}
+
+	return nil
+}
+
+// writeSalvaged writes the salvaged entries to a new archive at dest through a
+// temporary file, finished by finish. sources are closed before dest is
+// replaced, as dest may be the damaged archive they are read from.
+func writeSalvaged(dest, pattern string, sources []io.Closer, entries []*salvagedEntry, finish func(file *os.File) error) error {
+	return writeArchive(dest, pattern, sources, finish, func(zWriter *zip.Writer) error {
+		for _, entry := range entries {
+			writer, err := zWriter.CreateRaw(entry.header)
+			if err != nil {
+				return err
+			}
+
+			if _, err := io.Copy(writer, entry.data); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,194 @@
+package zippy
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/engmtcdrm/go-zippy/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [Zippy.Repair] function.
+func Test_Zippy_Repair(t *testing.T) {
+	initZip := func(t *testing.T, files, subdirs int) (string, []byte) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+
+		_, err := testutils.CreateZipFile(zipFilePath, files, subdirs)
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+
+		return zipFilePath, data
+	}
+
+	centralDirectoryOffset := func(t *testing.T, data []byte) int {
+		offsets := centralHeaderOffsets(t, data)
+		assert.NotEmpty(t, offsets)
+		return offsets[0]
+	}
+
+	assertReadable := func(t *testing.T, path string, entries int) {
+		u, err := NewUnzippy(path, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		assert.NoError(t, err)
+		assert.Len(t, report.Entries, entries)
+	}
+
+	t.Run("intact archive", func(t *testing.T) {
+		zipFilePath, _ := initZip(t, 3, 1)
+		dest := filepath.Join(filepath.Dir(zipFilePath), "fixed.zip")
+
+		report, err := NewZippy(zipFilePath).Repair(dest)
+		assert.NoError(t, err)
+		assert.Len(t, report.Recovered, 7)
+		assert.Empty(t, report.Lost)
+		assertReadable(t, dest, 7)
+	})
+
+	t.Run("missing central directory", func(t *testing.T) {
+		zipFilePath, data := initZip(t, 3, 1)
+		dest := filepath.Join(filepath.Dir(zipFilePath), "fixed.zip")
+
+		data = data[:centralDirectoryOffset(t, data)]
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		_, err := zip.OpenReader(zipFilePath)
+		assert.Error(t, err)
+
+		report, err := NewZippy(zipFilePath).Repair(dest)
+		assert.NoError(t, err)
+		assert.Len(t, report.Recovered, 7)
+		assert.Empty(t, report.Lost)
+		assertReadable(t, dest, 7)
+	})
+
+	t.Run("truncated entry", func(t *testing.T) {
+		zipFilePath, data := initZip(t, 3, 0)
+		dest := filepath.Join(filepath.Dir(zipFilePath), "fixed.zip")
+
+		zipReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+		lastName := zipReader.File[2].Name
+		offset, err := zipReader.File[2].DataOffset()
+		assert.NoError(t, err)
+		zipReader.Close()
+
+		data = data[:offset+2]
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		report, err := NewZippy(zipFilePath).Repair(dest)
+		assert.NoError(t, err)
+		assert.Len(t, report.Recovered, 2)
+		assert.Len(t, report.Lost, 1)
+		assert.Equal(t, lastName, report.Lost[0].Name)
+		assertReadable(t, dest, 2)
+	})
+
+	t.Run("missing local headers", func(t *testing.T) {
+		zipFilePath, data := initZip(t, 3, 1)
+		dest := filepath.Join(filepath.Dir(zipFilePath), "fixed.zip")
+
+		cdOffset := centralDirectoryOffset(t, data)
+		localSignature := []byte("PK\x03\x04")
+		for i := bytes.Index(data[:cdOffset], localSignature); i >= 0; i = bytes.Index(data[:cdOffset], localSignature) {
+			data[i] = 0
+		}
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		report, err := NewZippy(zipFilePath).Repair(dest)
+		assert.NoError(t, err)
+		assert.Empty(t, report.Recovered)
+
+		if assert.Len(t, report.Lost, 7) {
+			for i := 1; i < len(report.Lost); i++ {
+				assert.Less(t, report.Lost[i-1].Offset, report.Lost[i].Offset)
+			}
+		}
+	})
+
+	t.Run("corrupted entry", func(t *testing.T) {
+		zipFilePath, data := initZip(t, 3, 0)
+
+		zipReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+		firstName := zipReader.File[0].Name
+		offset, err := zipReader.File[0].DataOffset()
+		assert.NoError(t, err)
+		zipReader.Close()
+
+		data[offset+1] ^= 0xff
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		report, err := NewZippy(zipFilePath).Repair(zipFilePath)
+		assert.NoError(t, err)
+		assert.Len(t, report.Recovered, 2)
+		assert.Len(t, report.Lost, 1)
+		assert.Equal(t, firstName, report.Lost[0].Name)
+		assert.NotContains(t, report.Recovered, firstName)
+
+		var entryErr *EntryError
+		if assert.ErrorAs(t, report.Lost[0].Err, &entryErr) {
+			assert.Equal(t, zipFilePath, entryErr.Archive)
+			assert.Equal(t, firstName, entryErr.Entry)
+		}
+		assertReadable(t, zipFilePath, 2)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+		createRawZip(t, zipFilePath, &zip.FileHeader{Name: "bad.txt", Method: zip.Store, CRC32: 1}, "data")
+
+		report, err := NewZippy(zipFilePath).Repair(zipFilePath)
+		assert.NoError(t, err)
+		assert.Empty(t, report.Recovered)
+
+		var checksumErr *ChecksumError
+		if assert.Len(t, report.Lost, 1) && assert.ErrorAs(t, report.Lost[0].Err, &checksumErr) {
+			assert.Equal(t, zipFilePath, checksumErr.Archive)
+			assert.Equal(t, "bad.txt", checksumErr.Entry)
+			assert.Equal(t, uint32(1), checksumErr.Expected)
+		}
+	})
+
+	t.Run("encrypted entries are unverified", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := createAESTestFile(t, tempDir, AES256, "secret")
+		dest := filepath.Join(tempDir, "fixed.zip")
+
+		report, err := NewZippy(zipFilePath).Repair(dest)
+		assert.NoError(t, err)
+		assert.Len(t, report.Recovered, 2)
+		assert.Equal(t, []string{"secret.txt"}, report.Unverified)
+
+		u, err := NewUnzippy(dest, &UnzippyOptions{Password: "secret"})
+		assert.NoError(t, err)
+		_, err = u.Verify()
+		assert.NoError(t, err)
+	})
+
+	t.Run("not a zip file", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, "not-a-zip.txt")
+		dest := filepath.Join(tempDir, "fixed.zip")
+		assert.NoError(t, os.WriteFile(zipFilePath, []byte("this is not a zip file"), 0644))
+
+		report, err := NewZippy(zipFilePath).Repair(dest)
+		assert.NoError(t, err)
+		assert.Empty(t, report.Recovered)
+		assert.Empty(t, report.Lost)
+	})
+
+	t.Run("zip does not exist", func(t *testing.T) {
+		tempDir := t.TempDir()
+
+		report, err := NewZippy(filepath.Join(tempDir, testZipFileName)).Repair(filepath.Join(tempDir, "fixed.zip"))
+		assert.Error(t, err)
+		assert.Nil(t, report)
+	})
+}
@@ -0,0 +1,104 @@
+package zippy
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// NameSanitizer selects how entry names that cannot be created on Windows are
+// rewritten when extracting. Names are rewritten on every platform, so the
+// result is the same wherever an archive is extracted.
+type NameSanitizer uint8
+
+const (
+	SanitizeNone     NameSanitizer = iota // SanitizeNone keeps names as they are stored.
+	SanitizeStrict                        // SanitizeStrict rewrites what Windows refuses: reserved device names like aux.txt, the characters <>:"\|?* and control characters, and trailing dots and spaces.
+	SanitizePortable                      // SanitizePortable rewrites names like SanitizeStrict and also replaces characters outside of the POSIX portable set A-Z a-z 0-9 . _ -.
+)
+
+// reservedNames are the device names Windows reserves, whatever their case and
+// extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"COM¹": true, "COM²": true, "COM³": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	"LPT¹": true, "LPT²": true, "LPT³": true,
+}
+
+// sanitizeName returns name with each of its path elements rewritten to a
+// safe equivalent. Illegal characters and trailing dots and spaces are
+// replaced with underscores, and an underscore is added after reserved device
+// names, so aux.txt becomes aux_.txt.
+func sanitizeName(name string, sanitizer NameSanitizer) string {
+	if sanitizer == SanitizeNone {
+		return name
+	}
+
+	elements := strings.Split(name, "/")
+	for i, element := range elements {
+		// Relative elements are left for safeJoin to refuse
+		if element == "" || element == "." || element == ".." {
+			continue
+		}
+
+		elements[i] = sanitizeElement(element, sanitizer)
+	}
+
+	return strings.Join(elements, "/")
+}
+
+// sanitizeElement rewrites a single path element of a name.
+func sanitizeElement(element string, sanitizer NameSanitizer) string {
+	element = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"\|?*`, r) {
+			return '_'
+		}
+
+		if sanitizer == SanitizePortable && !isPortable(r) {
+			return '_'
+		}
+
+		return r
+	}, element)
+
+	// Windows drops trailing dots and spaces, so they are replaced
+	if trimmed := strings.TrimRight(element, ". "); len(trimmed) < len(element) {
+		element = trimmed + strings.Repeat("_", len(element)-len(trimmed))
+	}
+
+	stem, _, _ := strings.Cut(element, ".")
+	stem = strings.TrimRight(stem, " ")
+	if reservedNames[strings.ToUpper(stem)] {
+		element = stem + "_" + element[len(stem):]
+	}
+
+	return element
+}
+
+// isPortable reports whether r is in the POSIX portable filename character
+// set.
+func isPortable(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-'
+}
+
+// newRenamed returns the map of renamed entries of an extraction with the
+// options, or nil if names are not sanitized.
+func newRenamed(options *UnzippyOptions) map[string]string {
+	if options.Sanitize == SanitizeNone {
+		return nil
+	}
+
+	return make(map[string]string)
+}
+
+// extractName returns the name an entry is extracted under, with its path
+// junked and sanitized as the options say, and whether sanitizing changed it.
+func (o *UnzippyOptions) extractName(name string) (string, bool) {
+	if o.Junk {
+		name = filepath.Base(name)
+	}
+
+	sanitized := sanitizeName(name, o.Sanitize)
+	return sanitized, sanitized != name
+}
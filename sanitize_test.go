@@ -0,0 +1,163 @@
+package zippy
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [sanitizeName] function.
+func Test_sanitizeName(t *testing.T) {
+	tests := []struct {
+		name      string
+		sanitizer NameSanitizer
+		want      string
+	}{
+		{"aux.txt", SanitizeNone, "aux.txt"},
+		{"aux.txt", SanitizeStrict, "aux_.txt"},
+		{"con", SanitizeStrict, "con_"},
+		{"CON.tar.gz", SanitizeStrict, "CON_.tar.gz"},
+		{"con .txt", SanitizeStrict, "con_ .txt"},
+		{"Com1", SanitizeStrict, "Com1_"},
+		{"lpt¹.txt", SanitizeStrict, "lpt¹_.txt"},
+		{"console.txt", SanitizeStrict, "console.txt"},
+		{"com10", SanitizeStrict, "com10"},
+		{"a:b", SanitizeStrict, "a_b"},
+		{`a<b>c"d|e?f*g\h`, SanitizeStrict, "a_b_c_d_e_f_g_h"},
+		{"tab\there", SanitizeStrict, "tab_here"},
+		{"name.", SanitizeStrict, "name_"},
+		{"name. ", SanitizeStrict, "name__"},
+		{"...", SanitizeStrict, "___"},
+		{"dir./nul/file?.txt", SanitizeStrict, "dir_/nul_/file_.txt"},
+		{"dir/", SanitizeStrict, "dir/"},
+		{"../aux", SanitizeStrict, "../aux_"},
+		{"日本 語.txt", SanitizeStrict, "日本 語.txt"},
+		{"日本 語.txt", SanitizePortable, "____.txt"},
+		{"my-file_1.TXT", SanitizePortable, "my-file_1.TXT"},
+		{"prn.txt", SanitizePortable, "prn_.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sanitizeName(tt.name, tt.sanitizer))
+		})
+	}
+}
+
+// Tests for [UnzippyOptions.Sanitize] option.
+func Test_Unzippy_Sanitize(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "archive.zip")
+	createTestZip(t, zipPath, [][2]string{
+		{"aux.txt", "device"},
+		{"dir:1/", ""},
+		{"dir:1/file?.txt", "question"},
+		{"plain.txt", "plain"},
+	})
+
+	wantRenamed := map[string]string{
+		"aux.txt":         "aux_.txt",
+		"dir:1/":          "dir_1/",
+		"dir:1/file?.txt": "dir_1/file_.txt",
+	}
+
+	t.Run("extract", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "dest")
+
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{Sanitize: SanitizeStrict})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractTo(dest)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"aux.txt", "dir:1/", "dir:1/file?.txt", "plain.txt"}, zipFileNames(files))
+		assert.Equal(t, wantRenamed, u.Renamed)
+
+		data, err := os.ReadFile(filepath.Join(dest, "dir_1", "file_.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "question", string(data))
+		assert.FileExists(t, filepath.Join(dest, "aux_.txt"))
+	})
+
+	t.Run("junk", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{Sanitize: SanitizeStrict, Junk: true, DryRun: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractFilesTo(tempDir, "dir:1/*")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"dir:1/": "dir_1", "dir:1/file?.txt": "file_.txt"}, u.Renamed)
+	})
+
+	t.Run("junk extract", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{Sanitize: SanitizeStrict, Junk: true})
+		assert.NoError(t, err)
+
+		// Renamed is keyed by the names of the returned files
+		files, err := u.ExtractFilesTo(filepath.Join(t.TempDir(), "dest"), "dir:1/file?.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"file?.txt"}, zipFileNames(files))
+		assert.Equal(t, map[string]string{"file?.txt": "file_.txt"}, u.Renamed)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		dest := filepath.Join(tempDir, "dry")
+
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{Sanitize: SanitizeStrict, DryRun: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractFilesTo(dest, "aux.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"aux.txt": "aux_.txt"}, u.Renamed)
+
+		if assert.NotNil(t, u.Plan) {
+			assert.Equal(t, []PlanStep{{Action: PlanWrite, Entry: "aux.txt", Path: filepath.Join(dest, "aux_.txt")}}, u.Plan.Steps)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, &UnzippyOptions{DryRun: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(tempDir)
+		assert.NoError(t, err)
+		assert.Nil(t, u.Renamed)
+	})
+
+	t.Run("collisions", func(t *testing.T) {
+		collidingPath := filepath.Join(tempDir, "colliding.zip")
+		createTestZip(t, collidingPath, [][2]string{{"a:b", "colon"}, {"a_b", "underscore"}})
+
+		u, err := NewUnzippy(collidingPath, &UnzippyOptions{Sanitize: SanitizeStrict, Collisions: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(t.TempDir(), "dest"))
+		var collisionErr *CollisionError
+		if assert.ErrorAs(t, err, &collisionErr) {
+			assert.Equal(t, [][]string{{"a:b", "a_b"}}, collisionErr.Names)
+		}
+	})
+
+	t.Run("tar", func(t *testing.T) {
+		tarPath := filepath.Join(tempDir, "archive.tar")
+		createTarFile(t, tarPath, TarUncompressed,
+			tarTestEntry{header: &tar.Header{Name: "con.txt", Typeflag: tar.TypeReg, Mode: 0644}, body: "console"},
+			tarTestEntry{header: &tar.Header{Name: "link.", Typeflag: tar.TypeLink, Linkname: "con.txt"}},
+		)
+
+		dest := filepath.Join(t.TempDir(), "dest")
+
+		u, err := NewUntarry(tarPath, TarUncompressed, &UnzippyOptions{Sanitize: SanitizeStrict, HardLinks: true})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractTo(dest)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"con.txt", "link."}, zipFileNames(files))
+		assert.Equal(t, map[string]string{"con.txt": "con_.txt", "link.": "link_"}, u.Renamed)
+
+		data, err := os.ReadFile(filepath.Join(dest, "link_"))
+		assert.NoError(t, err)
+		assert.Equal(t, "console", string(data))
+	})
+}
@@ -0,0 +1,92 @@
+package zippy
+
+import "os"
+
+// shiftBlockSize is the size of the blocks moved at a time by shiftData.
+const shiftBlockSize = 1 << 20
+
+// prependStub prepends the executable at stub to the zip archive in file,
+// making it a self-extracting archive that can be run by whoever can read it.
+// The central directory is rewritten with offsets from the start of the stub,
+// like zip -A does, so readers that do not look for prepended data still find
+// the entries. zip64 forces ZIP64 records, as [Zippy.ForceZip64] does.
+func prependStub(file *os.File, stub string, zip64 bool) error {
+	data, err := os.ReadFile(stub)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	eocd, err := readEOCD(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	headers, err := readCentralDirectory(file, eocd)
+	if err != nil {
+		return err
+	}
+
+	shift := int64(len(data))
+	cdStart := eocd.baseOffset + int64(eocd.cdOffset)
+
+	var buf []byte
+	for _, h := range headers {
+		buf = appendCentralHeader(buf, h, uint64(h.Offset+shift), 0, zip64)
+	}
+
+	end := &endOfCentralDirectory{
+		diskEntries: uint64(len(headers)),
+		entries:     uint64(len(headers)),
+		cdSize:      uint64(len(buf)),
+		cdOffset:    uint64(cdStart + shift),
+		comment:     eocd.comment,
+	}
+	buf = appendEndRecords(buf, end, end.cdOffset+end.cdSize, zip64)
+
+	if err := shiftData(file, cdStart, shift); err != nil {
+		return err
+	}
+
+	if _, err := file.WriteAt(data, 0); err != nil {
+		return err
+	}
+
+	if err := file.Truncate(cdStart + shift); err != nil {
+		return err
+	}
+
+	if _, err := file.WriteAt(buf, cdStart+shift); err != nil {
+		return err
+	}
+
+	mode := info.Mode().Perm()
+	return file.Chmod(mode | mode&0444>>2)
+}
+
+// shiftData moves the first n bytes of file forward by shift bytes. Blocks are
+// moved from the end, so none is overwritten before it is moved.
+func shiftData(file *os.File, n, shift int64) error {
+	block := make([]byte, min(n, shiftBlockSize))
+
+	for end := n; end > 0; {
+		start := max(end-shiftBlockSize, 0)
+		chunk := block[:end-start]
+
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return err
+		}
+
+		if _, err := file.WriteAt(chunk, start+shift); err != nil {
+			return err
+		}
+
+		end = start
+	}
+
+	return nil
+}
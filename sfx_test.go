@@ -0,0 +1,221 @@
+package zippy
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertStub checks that the zip archive at path starts with stub, that its
+// central directory offsets count from the start of the stub, and that the
+// archive verifies.
+func assertStub(t *testing.T, path string, stub []byte) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, bytes.HasPrefix(data, stub), "archive starts with the stub")
+	assert.False(t, bytes.HasPrefix(data[len(stub):], stub), "stub is prepended once")
+
+	eocd, err := readEOCD(bytes.NewReader(data), int64(len(data)))
+	if assert.NoError(t, err) {
+		assert.Zero(t, eocd.baseOffset)
+	}
+
+	u, err := NewUnzippy(path, nil)
+	assert.NoError(t, err)
+
+	report, err := u.Verify()
+	if assert.NoError(t, err) {
+		assert.True(t, report.OK(), "%v", report.Failed())
+	}
+}
+
+// Tests for [Zippy.Stub] option.
+func Test_Zippy_Stub(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	assert.NoError(t, os.MkdirAll(sourceDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.txt"), []byte("alpha"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(sourceDir, "b.txt"), []byte("bravo"), 0644))
+
+	stub := []byte("#!/bin/sh\necho self-extracting\nexit 0\n")
+	stubPath := filepath.Join(tempDir, "stub.sh")
+	assert.NoError(t, os.WriteFile(stubPath, stub, 0755))
+
+	zipPath := filepath.Join(tempDir, "bundle.zip")
+	z := NewZippy(zipPath)
+	z.Stub = stubPath
+
+	t.Run("add", func(t *testing.T) {
+		assert.NoError(t, z.Add(filepath.Join(sourceDir, "a.txt")))
+		assertStub(t, zipPath, stub)
+
+		zReader, err := zip.OpenReader(zipPath)
+		if assert.NoError(t, err) {
+			assert.Len(t, zReader.File, 1)
+			zReader.Close()
+		}
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(zipPath)
+			assert.NoError(t, err)
+			assert.NotZero(t, info.Mode().Perm()&0100, "archive is executable")
+		}
+	})
+
+	t.Run("add to existing", func(t *testing.T) {
+		assert.NoError(t, z.Add(filepath.Join(sourceDir, "b.txt")))
+		assertStub(t, zipPath, stub)
+
+		dest := filepath.Join(tempDir, "extracted")
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		files, err := u.ExtractTo(dest)
+		assert.NoError(t, err)
+		assert.Len(t, files, 2)
+
+		data, err := os.ReadFile(filepath.Join(dest, toZipPath(sourceDir), "b.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "bravo", string(data))
+	})
+
+	t.Run("comment", func(t *testing.T) {
+		assert.NoError(t, z.SetComment("bundle"))
+		assertStub(t, zipPath, stub)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		dest := filepath.Join(tempDir, "copy.zip")
+		assert.NoError(t, z.Copy(dest))
+		assertStub(t, dest, stub)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, z.Delete(toZipPath(filepath.Join(sourceDir, "a.txt"))))
+		assertStub(t, zipPath, stub)
+	})
+
+	t.Run("force zip64", func(t *testing.T) {
+		forced := NewZippy(filepath.Join(tempDir, "forced.zip"))
+		forced.Stub = stubPath
+		forced.ForceZip64 = true
+
+		assert.NoError(t, forced.Add(sourceDir))
+		assertStub(t, forced.Path, stub)
+		assertZip64(t, forced.Path, true)
+	})
+
+	t.Run("dropped without stub", func(t *testing.T) {
+		plain := NewZippy(zipPath)
+		assert.NoError(t, plain.SetComment("plain"))
+
+		data, err := os.ReadFile(zipPath)
+		assert.NoError(t, err)
+		assert.False(t, bytes.HasPrefix(data, stub))
+	})
+
+	t.Run("missing stub", func(t *testing.T) {
+		missing := NewZippy(filepath.Join(tempDir, "missing.zip"))
+		missing.Stub = filepath.Join(tempDir, "missing.sh")
+
+		err := missing.Add(sourceDir)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+// Tests for reading archives with prepended data.
+func Test_Unzippy_prepended(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "plain.zip")
+	contents := createSplitSource(t, zipPath)
+
+	archive, err := os.ReadFile(zipPath)
+	assert.NoError(t, err)
+
+	// Offsets in the central directory do not count the prepended data
+	prefix := bytes.Repeat([]byte("\x7fELF stub "), 1000)
+	sfxPath := filepath.Join(tempDir, "bundle.bin")
+	assert.NoError(t, os.WriteFile(sfxPath, append(prefix, archive...), 0755))
+
+	t.Run("extract", func(t *testing.T) {
+		u, err := NewUnzippy(sfxPath, nil)
+		assert.NoError(t, err)
+
+		dest := filepath.Join(tempDir, "extracted")
+		_, err = u.ExtractTo(dest)
+		assert.NoError(t, err)
+
+		for name, want := range contents {
+			data, err := os.ReadFile(filepath.Join(dest, name))
+			if assert.NoError(t, err) {
+				assert.True(t, bytes.Equal(want, data), name)
+			}
+		}
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		u, err := NewUnzippy(sfxPath, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		if assert.NoError(t, err) {
+			assert.True(t, report.OK(), "%v", report.Failed())
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		listing, err := List(sfxPath)
+		if assert.NoError(t, err) {
+			assert.Equal(t, len(contents), listing.Files)
+		}
+	})
+
+	t.Run("open", func(t *testing.T) {
+		extractor, err := Open(sfxPath, nil)
+		assert.NoError(t, err)
+		assert.IsType(t, &Unzippy{}, extractor)
+	})
+
+	t.Run("add stub", func(t *testing.T) {
+		z := NewZippy(sfxPath)
+		z.Stub = filepath.Join(tempDir, "stub")
+		assert.NoError(t, os.WriteFile(z.Stub, prefix, 0755))
+
+		assert.NoError(t, z.SetComment("fixed"))
+		assertStub(t, sfxPath, prefix)
+	})
+}
+
+// Tests for [shiftData] function.
+func Test_shiftData(t *testing.T) {
+	data := make([]byte, shiftBlockSize*2+123)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+
+	path := filepath.Join(t.TempDir(), "data")
+	assert.NoError(t, os.WriteFile(path, data, 0644))
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+
+	assert.NoError(t, shiftData(file, int64(len(data)), 1000))
+
+	shifted, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data)+1000, len(shifted))
+	assert.True(t, bytes.Equal(data, shifted[1000:]))
+}
@@ -0,0 +1,449 @@
+package zippy
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MinSegmentSize is the smallest segment size of a split archive.
+const MinSegmentSize = 64 << 10
+
+// splitSignature starts the first segment of a split archive.
+const splitSignature = dataDescriptorSignature
+
+// segmentName returns the path of a segment of the split archive whose final
+// segment is path. Segments are numbered from 1, like "archive.z01".
+func segmentName(path string, number int) string {
+	return fmt.Sprintf("%s.z%02d", strings.TrimSuffix(path, filepath.Ext(path)), number)
+}
+
+// segmentWriter writes a split archive to segments of at most size bytes,
+// through temporary files that replace the segments once all are written.
+type segmentWriter struct {
+	dest     string
+	size     int64
+	pattern  string
+	segments []*os.File
+	written  int64 // Bytes written to the current segment.
+}
+
+// next starts a new segment.
+func (w *segmentWriter) next() error {
+	segment, err := os.CreateTemp(filepath.Dir(w.dest), w.pattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	w.segments = append(w.segments, segment)
+	w.written = 0
+
+	return nil
+}
+
+// reserve starts a new segment unless a record of n bytes fits in the current
+// one, as records may not span segments.
+func (w *segmentWriter) reserve(n int64) error {
+	if n > w.size {
+		return fmt.Errorf("record of %d bytes is larger than the segments: %w", n, ErrSegmentSize)
+	}
+
+	if len(w.segments) == 0 || w.written+n > w.size {
+		return w.next()
+	}
+
+	return nil
+}
+
+// position returns the disk number and offset of the next byte written.
+func (w *segmentWriter) position() (uint32, uint64) {
+	return uint32(len(w.segments) - 1), uint64(w.written)
+}
+
+// Write writes p, starting new segments as the current one fills up.
+func (w *segmentWriter) Write(p []byte) (int, error) {
+	total := 0
+
+	for len(p) > 0 {
+		if len(w.segments) == 0 || w.written == w.size {
+			if err := w.next(); err != nil {
+				return total, err
+			}
+		}
+
+		n, err := w.segments[len(w.segments)-1].Write(p[:min(int64(len(p)), w.size-w.written)])
+		total += n
+		w.written += int64(n)
+		p = p[n:]
+
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+// commit closes the segments and renames them to their final names, returning
+// the names.
+func (w *segmentWriter) commit() ([]string, error) {
+	names := make([]string, len(w.segments))
+	for i, segment := range w.segments {
+		if err := segment.Close(); err != nil {
+			return nil, err
+		}
+
+		names[i] = segmentName(w.dest, i+1)
+		if i == len(w.segments)-1 {
+			names[i] = w.dest
+		}
+	}
+
+	for i, segment := range w.segments {
+		if err := os.Rename(segment.Name(), names[i]); err != nil {
+			return nil, fmt.Errorf("failed to rename temporary file: %w", err)
+		}
+	}
+
+	return names, nil
+}
+
+// abort closes and removes the segments.
+func (w *segmentWriter) abort() {
+	for _, segment := range w.segments {
+		segment.Close()
+		os.Remove(segment.Name())
+	}
+}
+
+// Splits the zip archive into a split archive like zip -s does, with segments
+// of at most size bytes. The final segment is written to dest, and the others
+// next to it as ".z01", ".z02" and so on. An archive that fits in a single
+// segment is copied to dest unsplit.
+//
+// Returns the paths of the segments, in order. Split archives are read by
+// [Unzippy] from their final segment.
+func (z *Zippy) Split(dest string, size int64) (segments []string, err error) {
+	if dest == "" {
+		return nil, ErrEmptyPath
+	}
+
+	if size < MinSegmentSize {
+		return nil, fmt.Errorf("segment size %d is smaller than %d bytes: %w", size, MinSegmentSize, ErrSegmentSize)
+	}
+
+	source, err := os.Open(z.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	eocd, err := readEOCD(source, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	if eocd.disk > 0 {
+		return nil, fmt.Errorf("failed to split '%s': already split: %w", z.Path, ErrUnsupportedFormat)
+	}
+
+	headers, err := readCentralDirectory(source, eocd)
+	if err != nil {
+		return nil, err
+	}
+
+	cdStart := eocd.baseOffset + int64(eocd.cdOffset)
+	if info.Size()-eocd.baseOffset <= size || len(headers) == 0 {
+		return []string{dest}, writeTemp(dest, z.tempFile, []io.Closer{source}, func(file *os.File) error {
+			_, err := io.Copy(file, io.NewSectionReader(source, eocd.baseOffset, info.Size()-eocd.baseOffset))
+			return err
+		})
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	w := &segmentWriter{dest: dest, size: size, pattern: z.tempFile}
+	defer func() {
+		if err != nil {
+			w.abort()
+		}
+	}()
+
+	if err := binary.Write(w, binary.LittleEndian, uint32(splitSignature)); err != nil {
+		return nil, err
+	}
+
+	// Entries are copied in the order of their local headers, each up to the
+	// next one, so their data descriptors come along
+	byOffset := slices.Clone(headers)
+	slices.SortFunc(byOffset, func(a, b *rawHeader) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+
+	type location struct {
+		disk   uint32
+		offset uint64
+	}
+	locations := make(map[*rawHeader]location, len(headers))
+
+	for i, h := range byOffset {
+		end := cdStart
+		if i+1 < len(byOffset) {
+			end = byOffset[i+1].Offset
+		}
+
+		local, err := readLocalHeader(source, h.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to split '%s': %w", h.Name, err)
+		}
+
+		if err := w.reserve(local.DataOffset - local.Offset); err != nil {
+			return nil, err
+		}
+
+		disk, offset := w.position()
+		locations[h] = location{disk, offset}
+
+		if _, err := io.Copy(w, io.NewSectionReader(source, h.Offset, end-h.Offset)); err != nil {
+			return nil, err
+		}
+	}
+
+	end := &endOfCentralDirectory{
+		entries: uint64(len(headers)),
+		comment: eocd.comment,
+	}
+
+	for i, h := range headers {
+		loc := locations[h]
+		record := appendCentralHeader(nil, h, loc.offset, loc.disk, z.ForceZip64)
+		if err := w.reserve(int64(len(record))); err != nil {
+			return nil, err
+		}
+
+		disk, offset := w.position()
+		if i == 0 {
+			end.cdDisk, end.cdOffset = disk, offset
+		}
+
+		if disk != end.disk {
+			end.disk, end.diskEntries = disk, 0
+		}
+
+		end.diskEntries++
+		end.cdSize += uint64(len(record))
+
+		if _, err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	// The end records may need to start a new segment, which then holds no
+	// central directory entries
+	records := appendEndRecords(nil, end, 0, z.ForceZip64)
+	if err := w.reserve(int64(len(records))); err != nil {
+		return nil, err
+	}
+
+	disk, offset := w.position()
+	if disk != end.disk {
+		end.disk, end.diskEntries = disk, 0
+	}
+
+	if _, err := w.Write(appendEndRecords(nil, end, offset, z.ForceZip64)); err != nil {
+		return nil, err
+	}
+
+	source.Close()
+
+	return w.commit()
+}
+
+// multiReaderAt reads several readers as if they were concatenated.
+type multiReaderAt struct {
+	parts  []io.ReaderAt
+	starts []int64 // Offset of each part, followed by the total size.
+}
+
+// newMultiReaderAt concatenates readers of the given sizes.
+func newMultiReaderAt(parts []io.ReaderAt, sizes []int64) *multiReaderAt {
+	starts := make([]int64, len(parts)+1)
+	for i, size := range sizes {
+		starts[i+1] = starts[i] + size
+	}
+
+	return &multiReaderAt{parts: parts, starts: starts}
+}
+
+// Size returns the total size of the readers.
+func (m *multiReaderAt) Size() int64 {
+	return m.starts[len(m.starts)-1]
+}
+
+// ReadAt reads len(p) bytes at off, across the readers it spans.
+func (m *multiReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	i := sort.Search(len(m.parts), func(i int) bool {
+		return m.starts[i+1] > off
+	})
+
+	n := 0
+	for ; n < len(p) && i < len(m.parts); i++ {
+		partOffset := off + int64(n) - m.starts[i]
+		want := int(min(int64(len(p)-n), m.starts[i+1]-m.starts[i]-partOffset))
+
+		read, err := m.parts[i].ReadAt(p[n:n+want], partOffset)
+		n += read
+
+		if read < want {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return n, err
+		}
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// zipArchive is a zip archive open for reading. The segments of split
+// archives are read as a single archive.
+type zipArchive struct {
+	*zip.Reader
+	r     io.ReaderAt // Contents of the archive.
+	size  int64       // Size of the contents.
+	files []*os.File
+}
+
+// openZip opens the zip archive at path for reading. Split archives are
+// opened from their final segment.
+func openZip(path string) (*zipArchive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	archive := &zipArchive{r: file, size: info.Size(), files: []*os.File{file}}
+
+	if eocd, err := readEOCD(file, info.Size()); err == nil && eocd.disk > 0 {
+		if err := archive.stitch(path, eocd); err != nil {
+			archive.Close()
+			return nil, err
+		}
+	}
+
+	archive.Reader, err = zip.NewReader(archive.r, archive.size)
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+
+	return archive, nil
+}
+
+// stitch opens the other segments of a split archive whose final segment is
+// already open, and reads them as one archive.
+//
+// The offsets in the central directory are relative to the segments, so the
+// central directory is replaced with one using offsets into the stitched
+// segments.
+func (a *zipArchive) stitch(path string, eocd *endOfCentralDirectory) error {
+	parts := make([]io.ReaderAt, 0, eocd.disk+1)
+	sizes := make([]int64, 0, eocd.disk+1)
+
+	for number := 1; number <= int(eocd.disk); number++ {
+		segment, err := os.Open(segmentName(path, number))
+		if err != nil {
+			return fmt.Errorf("failed to open segment %d of '%s': %w", number, path, err)
+		}
+		a.files = append(a.files, segment)
+
+		info, err := segment.Stat()
+		if err != nil {
+			return err
+		}
+
+		parts = append(parts, segment)
+		sizes = append(sizes, info.Size())
+	}
+
+	parts = append(parts, a.files[0])
+	sizes = append(sizes, a.size)
+
+	segments := newMultiReaderAt(parts, sizes)
+	if eocd.cdDisk > eocd.disk {
+		return fmt.Errorf("failed to open '%s': %w", path, zip.ErrFormat)
+	}
+
+	cdStart := segments.starts[eocd.cdDisk] + int64(eocd.cdOffset)
+	headers, err := readCentralDirectory(segments, &endOfCentralDirectory{cdSize: eocd.cdSize, cdOffset: uint64(cdStart)})
+	if err != nil {
+		return err
+	}
+
+	var cd []byte
+	for _, h := range headers {
+		if h.Disk > eocd.disk {
+			return fmt.Errorf("failed to open '%s': %w", path, zip.ErrFormat)
+		}
+
+		cd = appendCentralHeader(cd, h, uint64(segments.starts[h.Disk]+h.Offset), 0, false)
+	}
+
+	end := &endOfCentralDirectory{
+		diskEntries: uint64(len(headers)),
+		entries:     uint64(len(headers)),
+		cdSize:      uint64(len(cd)),
+		cdOffset:    uint64(cdStart),
+		comment:     eocd.comment,
+	}
+	tail := appendEndRecords(cd, end, uint64(cdStart)+uint64(len(cd)), false)
+
+	stitched := newMultiReaderAt(
+		[]io.ReaderAt{io.NewSectionReader(segments, 0, cdStart), bytes.NewReader(tail)},
+		[]int64{cdStart, int64(len(tail))},
+	)
+	a.r, a.size = stitched, stitched.Size()
+
+	return nil
+}
+
+// Close closes the files of the archive.
+func (a *zipArchive) Close() error {
+	var errs []error
+	for _, file := range a.files {
+		errs = append(errs, file.Close())
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,217 @@
+package zippy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createSplitSource writes a zip archive with a small entry and several
+// entries of random data, returning the contents of each entry by name.
+func createSplitSource(t *testing.T, path string) map[string][]byte {
+	t.Helper()
+
+	random := rand.New(rand.NewSource(1))
+	contents := map[string][]byte{"small.txt": []byte("hello")}
+	for _, name := range []string{"a.bin", "b.bin", "c.bin"} {
+		data := make([]byte, 100000)
+		random.Read(data)
+		contents[name] = data
+	}
+
+	file, err := os.Create(path)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer file.Close()
+
+	zWriter := zip.NewWriter(file)
+	for _, name := range []string{"small.txt", "a.bin", "b.bin", "c.bin"} {
+		writer, err := zWriter.Create(name)
+		assert.NoError(t, err)
+
+		_, err = writer.Write(contents[name])
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, zWriter.SetComment("split"))
+	assert.NoError(t, zWriter.Close())
+
+	return contents
+}
+
+// Tests for [Zippy.Split] function.
+func Test_Zippy_Split(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "source.zip")
+	contents := createSplitSource(t, zipPath)
+
+	dest := filepath.Join(tempDir, "split", "archive.zip")
+	segments, err := NewZippy(zipPath).Split(dest, MinSegmentSize)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("segments", func(t *testing.T) {
+		assert.Equal(t, []string{
+			filepath.Join(tempDir, "split", "archive.z01"),
+			filepath.Join(tempDir, "split", "archive.z02"),
+			filepath.Join(tempDir, "split", "archive.z03"),
+			filepath.Join(tempDir, "split", "archive.z04"),
+			dest,
+		}, segments)
+
+		for _, segment := range segments {
+			info, err := os.Stat(segment)
+			if assert.NoError(t, err) {
+				assert.LessOrEqual(t, info.Size(), int64(MinSegmentSize))
+			}
+		}
+
+		data, err := os.ReadFile(segments[0])
+		if assert.NoError(t, err) {
+			assert.Equal(t, uint32(splitSignature), binary.LittleEndian.Uint32(data))
+		}
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		u, err := NewUnzippy(dest, nil)
+		assert.NoError(t, err)
+
+		extractDir := filepath.Join(tempDir, "extracted")
+		files, err := u.ExtractTo(extractDir)
+		assert.NoError(t, err)
+		assert.Len(t, files, len(contents))
+
+		for name, want := range contents {
+			data, err := os.ReadFile(filepath.Join(extractDir, name))
+			if assert.NoError(t, err) {
+				assert.True(t, bytes.Equal(want, data), name)
+			}
+		}
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		u, err := NewUnzippy(dest, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		if assert.NoError(t, err) {
+			assert.True(t, report.OK(), "%v", report.Failed())
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		listing, err := List(dest)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "split", listing.Comment)
+			assert.Equal(t, 4, listing.Files)
+		}
+	})
+
+	t.Run("open", func(t *testing.T) {
+		extractor, err := Open(dest, nil)
+		assert.NoError(t, err)
+		assert.IsType(t, &Unzippy{}, extractor)
+	})
+
+	t.Run("already split", func(t *testing.T) {
+		_, err := NewZippy(dest).Split(filepath.Join(tempDir, "again.zip"), MinSegmentSize)
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+
+	t.Run("missing segment", func(t *testing.T) {
+		assert.NoError(t, os.Rename(segments[2], segments[2]+".bak"))
+		defer os.Rename(segments[2]+".bak", segments[2])
+
+		_, err := List(dest)
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("force zip64", func(t *testing.T) {
+		z := NewZippy(zipPath)
+		z.ForceZip64 = true
+
+		forced := filepath.Join(tempDir, "forced", "archive.zip")
+		_, err := z.Split(forced, MinSegmentSize)
+		assert.NoError(t, err)
+
+		headers, zip64 := centralHeaders(t, forced)
+		assert.True(t, zip64)
+		for _, h := range headers {
+			_, ok := findExtra(h.Extra, zip64ExtraID)
+			assert.True(t, ok, h.Name)
+		}
+
+		u, err := NewUnzippy(forced, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		if assert.NoError(t, err) {
+			assert.True(t, report.OK(), "%v", report.Failed())
+		}
+	})
+
+	t.Run("single segment", func(t *testing.T) {
+		single := filepath.Join(tempDir, "single.zip")
+		segments, err := NewZippy(zipPath).Split(single, 1<<20)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{single}, segments)
+
+		want, err := os.ReadFile(zipPath)
+		assert.NoError(t, err)
+		got, err := os.ReadFile(single)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("segment too small", func(t *testing.T) {
+		_, err := NewZippy(zipPath).Split(filepath.Join(tempDir, "small.zip"), MinSegmentSize-1)
+		assert.ErrorIs(t, err, ErrSegmentSize)
+	})
+
+	t.Run("empty path", func(t *testing.T) {
+		_, err := NewZippy(zipPath).Split("", MinSegmentSize)
+		assert.ErrorIs(t, err, ErrEmptyPath)
+	})
+}
+
+// Tests for [multiReaderAt] type.
+func Test_multiReaderAt(t *testing.T) {
+	m := newMultiReaderAt(
+		[]io.ReaderAt{bytes.NewReader([]byte("abc")), bytes.NewReader(nil), bytes.NewReader([]byte("defgh"))},
+		[]int64{3, 0, 5},
+	)
+	assert.Equal(t, int64(8), m.Size())
+
+	tests := []struct {
+		name   string
+		offset int64
+		length int
+		want   string
+		err    error
+	}{
+		{"within a part", 0, 2, "ab", nil},
+		{"across parts", 1, 4, "bcde", nil},
+		{"to the end", 5, 3, "fgh", nil},
+		{"past the end", 6, 4, "gh", io.EOF},
+		{"at the end", 8, 1, "", io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := make([]byte, tt.length)
+			n, err := m.ReadAt(p, tt.offset)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.want, string(p[:n]))
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package zippy
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SymlinkPolicy controls how symbolic links are handled when archiving.
+type SymlinkPolicy uint8
+
+const (
+	SymlinkFollow SymlinkPolicy = iota // SymlinkFollow archives the file or directory a link points to.
+	SymlinkStore                       // SymlinkStore archives the link itself, storing its target as the entry contents.
+	SymlinkSkip                        // SymlinkSkip leaves links out of the archive.
+)
+
+// ExtractSymlinkPolicy controls how symbolic link entries are handled when
+// extracting.
+type ExtractSymlinkPolicy uint8
+
+const (
+	ExtractSymlinkAsFile ExtractSymlinkPolicy = iota // ExtractSymlinkAsFile writes a regular file containing the link target.
+	ExtractSymlinkCreate                             // ExtractSymlinkCreate creates a symbolic link.
+	ExtractSymlinkSkip                               // ExtractSymlinkSkip does not extract link entries.
+)
+
+// isSymlink reports whether a zip entry is a symbolic link.
+func isSymlink(file *zip.File) bool {
+	return file.Mode()&fs.ModeSymlink != 0
+}
+
+// stat returns the file info for a path to archive, following symbolic links
+// only when the policy is to follow them.
+func (z *Zippy) stat(path string) (fs.FileInfo, error) {
+	return statPolicy(path, z.Symlinks)
+}
+
+// statPolicy returns the file info for a path to archive, following symbolic
+// links only when the policy is to follow them.
+func statPolicy(path string, symlinks SymlinkPolicy) (fs.FileInfo, error) {
+	if symlinks == SymlinkFollow {
+		return os.Stat(path)
+	}
+
+	return os.Lstat(path)
+}
+
+// walk adds a file or directory and all of its contents to the archive.
+//
+// ancestors are the resolved paths of the directories being walked, used to
+// detect symbolic link loops.
+func (z *Zippy) walk(path string, ancestors []string) error {
+	return walkPath(z.Path, path, z.Symlinks, ancestors, z.zipFile)
+}
+
+// walkPath calls fn for a file or directory and all of its contents, parents
+// before their contents.
+//
+// archive is the path of the archive the files are added to, reported in a
+// [SymlinkLoopError]. ancestors are the resolved paths of the directories
+// being walked, used to detect symbolic link loops.
+func walkPath(archive, path string, symlinks SymlinkPolicy, ancestors []string, fn func(path string) error) error {
+	info, err := statPolicy(path, symlinks)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(path); err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	if symlinks == SymlinkFollow {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return err
+		}
+
+		if slices.Contains(ancestors, resolved) {
+			return &SymlinkLoopError{
+				EntryError: EntryError{Op: "add", Archive: archive, Entry: path, Err: ErrSymlinkLoop},
+				Path:       resolved,
+			}
+		}
+
+		ancestors = append(ancestors, resolved)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := walkPath(archive, filepath.Join(path, entry.Name()), symlinks, ancestors, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// zipSymlink adds a symbolic link to the archive, storing its target as the
+// entry contents.
+func (z *Zippy) zipSymlink(path string, header *zip.FileHeader) error {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return err
+	}
+
+	header.Method = zip.Store
+	setNameEncoding(header)
+
+	writer, err := z.zWriter.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	setCentralExtra(header)
+
+	_, err = io.WriteString(writer, filepath.ToSlash(target))
+	return err
+}
+
+// readSymlinkTarget reads the link target stored as the contents of a
+// symbolic link entry.
+func (u *Unzippy) readSymlinkTarget(file *zip.File) (string, error) {
+	reader, err := u.openFile(file)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	target, err := io.ReadAll(io.LimitReader(reader, 4096))
+	if err != nil {
+		return "", err
+	}
+
+	return string(target), nil
+}
+
+// within reports whether path is dest or inside dest.
+func within(dest, path string) bool {
+	rel, err := filepath.Rel(dest, path)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || filepath.IsLocal(rel)
+}
+
+// safeJoin joins an entry name of the archive at path archive to the
+// destination directory, refusing names that would be written outside of it.
+func safeJoin(archive, dest, name string) (string, error) {
+	path := filepath.Join(dest, name)
+	if !within(dest, path) {
+		return "", &UnsafePathError{
+			EntryError: EntryError{Op: "extract", Archive: archive, Entry: name, Err: ErrUnsafePath},
+			Path:       path,
+		}
+	}
+
+	return path, nil
+}
+
+// checkLinkFree refuses paths that pass through a symbolic link created
+// earlier in the same extraction. name is the entry name used in errors.
+func checkLinkFree(archive, name, dest, path string, links map[string]string) error {
+	for p := path; within(dest, p) && p != dest; p = filepath.Dir(p) {
+		if _, ok := links[p]; ok {
+			return &UnsafePathError{
+				EntryError: EntryError{
+					Op:      "extract",
+					Archive: archive,
+					Entry:   name,
+					Err:     fmt.Errorf("path passes through symbolic link '%s': %w", p, ErrUnsafePath),
+				},
+				Path: path,
+			}
+		}
+	}
+
+	return nil
+}
+
+// createSymlink creates a symbolic link entry at path, refusing targets that
+// point outside of dest.
+func (u *Unzippy) createSymlink(file *zip.File, dest, path string, links map[string]string) error {
+	target, err := u.readSymlinkTarget(file)
+	if err != nil {
+		return err
+	}
+
+	return linkSymlink(u.Path, file.Name, target, dest, path, links, u.Options.FailExisting)
+}
+
+// linkSymlink creates a symbolic link to target at path, refusing targets that
+// point outside of dest. links holds the symbolic links created so far and
+// their targets. Targets passing through one of them are refused, as the
+// link would resolve elsewhere than its text says, and so is a link that an
+// earlier target passes through. The new link is added to links. An existing
+// file at path is replaced, unless failExisting is set. archive and name are
+// the archive and entry name used in errors.
+func linkSymlink(archive, name, target, dest, path string, links map[string]string, failExisting bool) error {
+	// unsafe returns the error refusing the link, with the reason given.
+	unsafe := func(format string, args ...any) error {
+		return &UnsafePathError{
+			EntryError: EntryError{
+				Op:      "extract",
+				Archive: archive,
+				Entry:   name,
+				Err:     fmt.Errorf(format+": %w", append(args, ErrUnsafePath)...),
+			},
+			Path: target,
+		}
+	}
+
+	localTarget := filepath.FromSlash(target)
+	if filepath.IsAbs(localTarget) || filepath.VolumeName(localTarget) != "" || !within(dest, filepath.Join(filepath.Dir(path), localTarget)) {
+		return unsafe("link target '%s' is outside of the destination", target)
+	}
+
+	for _, dir := range linkTraversal(path, localTarget) {
+		if _, ok := links[dir]; ok {
+			return unsafe("link target '%s' passes through symbolic link '%s'", target, dir)
+		}
+	}
+
+	for link, linkTarget := range links {
+		if slices.Contains(linkTraversal(link, linkTarget), path) {
+			return unsafe("symbolic link '%s' passes through the link", link)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(path); err == nil && !info.IsDir() && !failExisting {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Symlink(localTarget, path); err != nil {
+		return err
+	}
+
+	links[path] = localTarget
+	return nil
+}
+
+// linkTraversal returns the paths that a symbolic link at path looks up as
+// directories to reach target, like dir/a for the target a/../b.
+func linkTraversal(path, target string) []string {
+	elements := strings.Split(filepath.ToSlash(target), "/")
+
+	var dirs []string
+	p := filepath.Dir(path)
+	for _, element := range elements[:len(elements)-1] {
+		p = filepath.Join(p, element)
+		dirs = append(dirs, p)
+	}
+
+	return dirs
+}
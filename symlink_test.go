@@ -0,0 +1,271 @@
+package zippy
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// skipWithoutSymlinks skips tests that need to create symbolic links.
+func skipWithoutSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping symbolic link test on Windows OS")
+	}
+}
+
+// createSymlinkTestDir creates a directory with a file, a link to the file
+// and a link to a subdirectory.
+func createSymlinkTestDir(t *testing.T) string {
+	t.Helper()
+
+	srcDir := filepath.Join(t.TempDir(), "src")
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "sub"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "file.txt"), []byte("file"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "sub", "inner.txt"), []byte("inner"), 0644))
+	assert.NoError(t, os.Symlink("file.txt", filepath.Join(srcDir, "link.txt")))
+	assert.NoError(t, os.Symlink("sub", filepath.Join(srcDir, "linkdir")))
+
+	return srcDir
+}
+
+// Tests for [Zippy.Add] with symbolic links.
+func Test_Zippy_Add_Symlinks(t *testing.T) {
+	skipWithoutSymlinks(t)
+
+	t.Run("follow", func(t *testing.T) {
+		srcDir := createSymlinkTestDir(t)
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+
+		z := NewZippy(zipFilePath)
+		assert.NoError(t, z.Add(srcDir))
+
+		names := readTestZipFiles(t, zipFilePath)
+		link := names[toZipPath(filepath.Join(srcDir, "link.txt"))]
+		assert.NotNil(t, link)
+		assert.False(t, isSymlink(link))
+		assert.Equal(t, uint64(4), link.UncompressedSize64)
+		assert.Contains(t, names, toZipPath(filepath.Join(srcDir, "linkdir", "inner.txt")))
+	})
+
+	t.Run("store", func(t *testing.T) {
+		srcDir := createSymlinkTestDir(t)
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+
+		z := NewZippy(zipFilePath)
+		z.Symlinks = SymlinkStore
+		assert.NoError(t, z.Add(srcDir))
+
+		names := readTestZipFiles(t, zipFilePath)
+		for _, name := range []string{"link.txt", "linkdir"} {
+			link := names[toZipPath(filepath.Join(srcDir, name))]
+			assert.NotNil(t, link)
+			assert.True(t, isSymlink(link))
+		}
+		assert.NotContains(t, names, toZipPath(filepath.Join(srcDir, "linkdir", "inner.txt")))
+
+		reader, err := names[toZipPath(filepath.Join(srcDir, "link.txt"))].Open()
+		assert.NoError(t, err)
+		defer reader.Close()
+		target, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, "file.txt", string(target))
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		srcDir := createSymlinkTestDir(t)
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+
+		z := NewZippy(zipFilePath)
+		z.Symlinks = SymlinkSkip
+		assert.NoError(t, z.Add(srcDir))
+
+		names := readTestZipFiles(t, zipFilePath)
+		assert.NotContains(t, names, toZipPath(filepath.Join(srcDir, "link.txt")))
+		assert.NotContains(t, names, toZipPath(filepath.Join(srcDir, "linkdir"))+"/")
+		assert.Contains(t, names, toZipPath(filepath.Join(srcDir, "file.txt")))
+	})
+
+	t.Run("follow loop", func(t *testing.T) {
+		srcDir := createSymlinkTestDir(t)
+		assert.NoError(t, os.Symlink("..", filepath.Join(srcDir, "sub", "loop")))
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+
+		z := NewZippy(zipFilePath)
+		err := z.Add(srcDir)
+		assert.ErrorIs(t, err, ErrSymlinkLoop)
+
+		var loopErr *SymlinkLoopError
+		if assert.ErrorAs(t, err, &loopErr) {
+			resolved, err := filepath.EvalSymlinks(srcDir)
+			assert.NoError(t, err)
+
+			assert.Equal(t, zipFilePath, loopErr.Archive)
+			assert.Equal(t, "loop", filepath.Base(loopErr.Entry))
+			assert.Equal(t, resolved, loopErr.Path)
+		}
+	})
+}
+
+// Tests for [Unzippy.ExtractTo] with symbolic links.
+func Test_Unzippy_ExtractTo_Symlinks(t *testing.T) {
+	skipWithoutSymlinks(t)
+
+	entries := [][2]string{{"file.txt", "content"}, {"link.txt", "file.txt"}}
+
+	t.Run("as file", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		dest := filepath.Join(tempDir, "output")
+		createTestZip(t, zipFilePath, entries, "link.txt")
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		files, err := u.ExtractTo(dest)
+		assert.NoError(t, err)
+		assert.Len(t, files, 2)
+
+		info, err := os.Lstat(filepath.Join(dest, "link.txt"))
+		assert.NoError(t, err)
+		assert.True(t, info.Mode().IsRegular())
+	})
+
+	t.Run("create", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		dest := filepath.Join(tempDir, "output")
+		createTestZip(t, zipFilePath, entries, "link.txt")
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Symlinks: ExtractSymlinkCreate})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(dest)
+		assert.NoError(t, err)
+
+		target, err := os.Readlink(filepath.Join(dest, "link.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "file.txt", target)
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		dest := filepath.Join(tempDir, "output")
+		createTestZip(t, zipFilePath, entries, "link.txt")
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Symlinks: ExtractSymlinkSkip})
+		assert.NoError(t, err)
+
+		files, err := u.ExtractTo(dest)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+
+		_, err = os.Lstat(filepath.Join(dest, "link.txt"))
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("existing file", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		dest := filepath.Join(tempDir, "output")
+		createTestZip(t, zipFilePath, [][2]string{{"link.txt", "file.txt"}}, "link.txt")
+
+		existing := filepath.Join(dest, "link.txt")
+		assert.NoError(t, os.MkdirAll(dest, os.ModePerm))
+		assert.NoError(t, os.WriteFile(existing, []byte("local changes"), 0644))
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Symlinks: ExtractSymlinkCreate, FailExisting: true})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(dest)
+		assert.ErrorIs(t, err, fs.ErrExist)
+
+		contents, err := os.ReadFile(existing)
+		assert.NoError(t, err)
+		assert.Equal(t, "local changes", string(contents))
+
+		u.Options.FailExisting = false
+		_, err = u.ExtractTo(dest)
+		assert.NoError(t, err)
+
+		target, err := os.Readlink(existing)
+		assert.NoError(t, err)
+		assert.Equal(t, "file.txt", target)
+	})
+
+	t.Run("target escapes destination", func(t *testing.T) {
+		for _, target := range []string{"../outside", "/etc/passwd", "sub/../../outside"} {
+			tempDir := t.TempDir()
+			zipFilePath := filepath.Join(tempDir, testZipFileName)
+			dest := filepath.Join(tempDir, "output")
+			createTestZip(t, zipFilePath, [][2]string{{"link", target}}, "link")
+
+			u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Symlinks: ExtractSymlinkCreate})
+			assert.NoError(t, err)
+
+			_, err = u.ExtractTo(dest)
+			assert.ErrorIs(t, err, ErrUnsafePath, target)
+
+			_, err = os.Lstat(filepath.Join(dest, "link"))
+			assert.True(t, os.IsNotExist(err))
+		}
+	})
+
+	t.Run("write through earlier link", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		dest := filepath.Join(tempDir, "output")
+		createTestZip(t, zipFilePath, [][2]string{{"sub/file.txt", "content"}, {"link", "sub"}, {"link/file.txt", "content"}}, "link")
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Symlinks: ExtractSymlinkCreate})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(dest)
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
+
+	t.Run("target through earlier link", func(t *testing.T) {
+		// The targets read as inside of the destination, but x/a resolves to x
+		for _, entries := range [][][2]string{
+			{{"x/a", "."}, {"x/b", "a/../.."}},
+			{{"x/b", "a/../.."}, {"x/a", "."}},
+		} {
+			tempDir := t.TempDir()
+			zipFilePath := filepath.Join(tempDir, testZipFileName)
+			dest := filepath.Join(tempDir, "output")
+			createTestZip(t, zipFilePath, entries, "x/a", "x/b")
+
+			u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Symlinks: ExtractSymlinkCreate})
+			assert.NoError(t, err)
+
+			_, err = u.ExtractTo(dest)
+			assert.ErrorIs(t, err, ErrUnsafePath, entries[0][0])
+
+			resolved, err := filepath.EvalSymlinks(filepath.Join(dest, "x", "b"))
+			if err == nil {
+				assert.True(t, within(dest, resolved), resolved)
+			}
+		}
+	})
+
+	t.Run("entry name escapes destination", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		dest := filepath.Join(tempDir, "output")
+		createTestZip(t, zipFilePath, [][2]string{{"../evil.txt", "content"}})
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(dest)
+		assert.ErrorIs(t, err, ErrUnsafePath)
+
+		_, err = os.Stat(filepath.Join(tempDir, "evil.txt"))
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -0,0 +1,270 @@
+package zippy
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SyncReport lists the entries changed by [Zippy.Sync].
+type SyncReport struct {
+	Added     []string // Added holds the entries for new files.
+	Updated   []string // Updated holds the entries replaced by newer files.
+	Removed   []string // Removed holds the entries whose files were deleted.
+	Unchanged []string // Unchanged holds the entries carried over as they were.
+}
+
+// Changed reports whether the archive was changed.
+func (r *SyncReport) Changed() bool {
+	return len(r.Added) > 0 || len(r.Updated) > 0 || len(r.Removed) > 0
+}
+
+// syncMode selects which files replace the entries of an archive.
+type syncMode uint8
+
+const (
+	syncMirror  syncMode = iota // Add new and changed files, and remove the entries of deleted files.
+	syncUpdate                  // Add new files and replace entries with newer files.
+	syncFreshen                 // Only replace entries with newer files.
+)
+
+// syncState tracks the progress of a running Sync, Update or Freshen.
+type syncState struct {
+	mode       syncMode
+	report     *SyncReport
+	seen       map[string]bool // Entries written to the new archive.
+	archiveDir string          // Absolute directory of the archive and its temp file.
+	archive    string          // Absolute path of the archive.
+}
+
+// isUnchanged reports whether a file matches its existing entry, comparing
+// size and modification time like zip -FS.
+func isUnchanged(existing *zip.File, info fs.FileInfo) bool {
+	if info.IsDir() {
+		return existing.FileInfo().IsDir()
+	}
+
+	// Zip modification times are only precise to two seconds
+	return existing.UncompressedSize64 == uint64(info.Size()) &&
+		existing.Modified.Sub(info.ModTime()).Abs() < 2*time.Second
+}
+
+// isNewer reports whether a file was modified after its existing entry.
+func isNewer(modified time.Time, info fs.FileInfo) bool {
+	if info.IsDir() {
+		return false
+	}
+
+	// Zip modification times are only precise to two seconds
+	return info.ModTime().Sub(modified) >= 2*time.Second
+}
+
+// syncEntry decides how a file is written to the archive being synced. It
+// reports done if the file was handled and must not be added.
+func (z *Zippy) syncEntry(path, name string, info fs.FileInfo) (bool, error) {
+	// Leave out the archive and its temp file when they are in the directory
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+
+	if filepath.Dir(absPath) == z.sync.archiveDir {
+		if match, _ := filepath.Match(z.tempFile, filepath.Base(absPath)); match || absPath == z.sync.archive {
+			return true, nil
+		}
+	}
+
+	if z.sync.seen[name] {
+		return true, nil
+	}
+
+	z.sync.seen[name] = true
+
+	existing, ok := z.existingFiles[name]
+	if !ok {
+		if z.sync.mode == syncFreshen {
+			return true, nil
+		}
+
+		z.sync.report.Added = append(z.sync.report.Added, name)
+		return false, nil
+	}
+
+	changed := isNewer(existing.Modified, info)
+	if z.sync.mode == syncMirror {
+		changed = !isUnchanged(existing, info)
+	}
+
+	if changed {
+		z.sync.report.Updated = append(z.sync.report.Updated, name)
+		return false, nil
+	}
+
+	z.sync.report.Unchanged = append(z.sync.report.Unchanged, name)
+	return true, z.keepFile(existing)
+}
+
+// Sync makes the archive mirror a directory, like zip -FS. Files not in the
+// archive are added and files newer than their entries are replaced, while
+// entries whose files were deleted are removed. Unchanged entries are copied
+// raw. Entries are named like [Zippy.Add] names them.
+//
+// The archive is created if it does not exist, and is only replaced once the
+// new archive is complete. It is left untouched when no entry changes.
+func (z *Zippy) Sync(dir string) (*SyncReport, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "sync", Path: dir, Err: errors.New("not a directory")}
+	}
+
+	return z.syncFiles(syncMirror, func() error {
+		return z.walk(filepath.Clean(dir), nil)
+	})
+}
+
+// Updates files in a zip archive, like zip -u. Files not in the archive are
+// added and entries are replaced by files modified after them. Other entries
+// are copied raw.
+//
+// files are the files or directories to update. Glob patterns are supported.
+func (z *Zippy) Update(files ...string) (err error) {
+	_, err = z.syncFiles(syncUpdate, func() error {
+		return z.zipFiles(files...)
+	})
+
+	return err
+}
+
+// Freshens files in a zip archive, like zip -f. Entries are replaced by files
+// modified after them, but files not in the archive are not added.
+//
+// files are the files or directories to freshen. Glob patterns are supported.
+func (z *Zippy) Freshen(files ...string) (err error) {
+	_, err = z.syncFiles(syncFreshen, func() error {
+		return z.zipFiles(files...)
+	})
+
+	return err
+}
+
+// syncFiles rewrites the archive through a temporary file with the files
+// added by walk, keeping or replacing existing entries according to the mode.
+// An existing archive is left untouched if no entry would change.
+func (z *Zippy) syncFiles(mode syncMode, walk func() error) (*SyncReport, error) {
+	absArchive, err := filepath.Abs(z.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var comment string
+	var existing []*zip.File
+	var sources []io.Closer
+
+	zReader, err := zip.OpenReader(z.Path)
+	if err == nil {
+		defer zReader.Close()
+
+		comment = zReader.Comment
+		existing = zReader.File
+		sources = append(sources, zReader)
+	} else if !os.IsNotExist(err) || mode == syncFreshen {
+		return nil, err
+	}
+
+	defer func() {
+		z.sync = nil
+		z.existingFiles = make(map[string]*zip.File)
+		z.zWriter = nil
+	}()
+
+	// run walks the files once, writing the new archive with write
+	run := func(write func(fn func(zWriter *zip.Writer) error) error) (*SyncReport, error) {
+		z.existingFiles = make(map[string]*zip.File)
+		for _, file := range existing {
+			z.existingFiles[file.Name] = file
+		}
+
+		z.sync = &syncState{
+			mode:       mode,
+			report:     &SyncReport{},
+			seen:       make(map[string]bool),
+			archiveDir: filepath.Dir(absArchive),
+			archive:    absArchive,
+		}
+		report := z.sync.report
+
+		err := write(func(zWriter *zip.Writer) error {
+			z.zWriter = zWriter
+
+			if zWriter != nil {
+				if err := zWriter.SetComment(comment); err != nil {
+					return err
+				}
+			}
+
+			if err := walk(); err != nil {
+				return err
+			}
+
+			for _, file := range existing {
+				if z.sync.seen[file.Name] {
+					continue
+				}
+
+				if mode == syncMirror {
+					report.Removed = append(report.Removed, file.Name)
+					if z.plan != nil {
+						z.plan.add(PlanDelete, file.Name, "", false)
+					}
+
+					continue
+				}
+
+				report.Unchanged = append(report.Unchanged, file.Name)
+				if err := z.keepFile(file); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return report, nil
+	}
+
+	if z.DryRun {
+		return run(func(fn func(zWriter *zip.Writer) error) error {
+			return z.dryRun(z.Path, func() error {
+				return fn(nil)
+			})
+		})
+	}
+
+	// Plan the changes first, so an unchanged archive is not rewritten
+	if zReader != nil {
+		report, err := run(func(fn func(zWriter *zip.Writer) error) error {
+			z.plan = newPlan(z.Path, z.Path)
+			defer func() { z.plan = nil }()
+
+			return fn(nil)
+		})
+		if err != nil || !report.Changed() {
+			return report, err
+		}
+	}
+
+	return run(func(fn func(zWriter *zip.Writer) error) error {
+		return writeArchive(z.Path, z.tempFile, sources, z.finish, fn)
+	})
+}
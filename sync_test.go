@@ -0,0 +1,233 @@
+package zippy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Tests for [Zippy.Sync] function.
+func Test_Zippy_Sync(t *testing.T) {
+	createSyncDir := func(t *testing.T) (string, string) {
+		tempDir := t.TempDir()
+		srcDir := filepath.Join(tempDir, "config")
+		assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "sub"), os.ModePerm))
+
+		for _, name := range []string{"keep.txt", "change.txt", "delete.txt", filepath.Join("sub", "nested.txt")} {
+			assert.NoError(t, os.WriteFile(filepath.Join(srcDir, name), []byte("original "+name), 0644))
+		}
+
+		return tempDir, srcDir
+	}
+
+	name := func(path string) string {
+		return toZipPath(path)
+	}
+
+	t.Run("mirror", func(t *testing.T) {
+		tempDir, srcDir := createSyncDir(t)
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+		z := NewZippy(zipFilePath)
+		report, err := z.Sync(srcDir)
+		assert.NoError(t, err)
+		assert.Len(t, report.Added, 6)
+		assert.True(t, report.Changed())
+
+		assert.NoError(t, z.SetComment("snapshot"))
+		before := readTestZip(t, zipFilePath, true)
+
+		// Change the directory
+		changed := filepath.Join(srcDir, "change.txt")
+		assert.NoError(t, os.WriteFile(changed, []byte("changed contents"), 0644))
+		assert.NoError(t, os.Chtimes(changed, time.Now(), time.Now().Add(time.Hour)))
+		assert.NoError(t, os.Remove(filepath.Join(srcDir, "delete.txt")))
+		assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "new.txt"), []byte("new"), 0644))
+
+		report, err = z.Sync(srcDir)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{name(filepath.Join(srcDir, "new.txt"))}, report.Added)
+		assert.Equal(t, []string{name(changed)}, report.Updated)
+		assert.Equal(t, []string{name(filepath.Join(srcDir, "delete.txt"))}, report.Removed)
+		assert.Len(t, report.Unchanged, 4)
+
+		after := readTestZip(t, zipFilePath, true)
+		assert.Equal(t, before[name(filepath.Join(srcDir, "keep.txt"))], after[name(filepath.Join(srcDir, "keep.txt"))])
+		assert.NotContains(t, after, name(filepath.Join(srcDir, "delete.txt")))
+
+		comment, err := z.Comment()
+		assert.NoError(t, err)
+		assert.Equal(t, "snapshot", comment)
+
+		// The archive now matches the directory, so it is not rewritten
+		modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+		assert.NoError(t, os.Chtimes(zipFilePath, modified, modified))
+
+		report, err = z.Sync(srcDir)
+		assert.NoError(t, err)
+		assert.False(t, report.Changed())
+		assert.Len(t, report.Unchanged, 6)
+
+		info, err := os.Stat(zipFilePath)
+		assert.NoError(t, err)
+		assert.True(t, modified.Equal(info.ModTime()), info.ModTime())
+
+		dest := filepath.Join(tempDir, "output")
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+		_, err = u.ExtractTo(dest)
+		assert.NoError(t, err)
+
+		contents, err := os.ReadFile(filepath.Join(dest, name(changed)))
+		assert.NoError(t, err)
+		assert.Equal(t, "changed contents", string(contents))
+	})
+
+	t.Run("archive inside directory", func(t *testing.T) {
+		_, srcDir := createSyncDir(t)
+		zipFilePath := filepath.Join(srcDir, testZipFileName)
+
+		z := NewZippy(zipFilePath)
+		_, err := z.Sync(srcDir)
+		assert.NoError(t, err)
+
+		report, err := z.Sync(srcDir)
+		assert.NoError(t, err)
+		assert.False(t, report.Changed())
+		assert.NotContains(t, readTestZip(t, zipFilePath, true), name(zipFilePath))
+	})
+
+	t.Run("errors leave archive untouched", func(t *testing.T) {
+		tempDir, srcDir := createSyncDir(t)
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+		z := NewZippy(zipFilePath)
+		_, err := z.Sync(srcDir)
+		assert.NoError(t, err)
+
+		before, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+
+		_, err = z.Sync(filepath.Join(tempDir, "missing"))
+		assert.Error(t, err)
+
+		_, err = z.Sync(filepath.Join(srcDir, "keep.txt"))
+		assert.Error(t, err)
+
+		after, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+}
+
+// Tests for [Zippy.Update] function.
+func Test_Zippy_Update(t *testing.T) {
+	tempDir := t.TempDir()
+	zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+	oldFile := filepath.Join(tempDir, "old.txt")
+	newerFile := filepath.Join(tempDir, "newer.txt")
+	assert.NoError(t, os.WriteFile(oldFile, []byte("old"), 0644))
+	assert.NoError(t, os.WriteFile(newerFile, []byte("original"), 0644))
+
+	z := NewZippy(zipFilePath)
+	assert.NoError(t, z.Add(oldFile, newerFile))
+	assert.NoError(t, z.SetComment("kept"))
+	before := readTestZip(t, zipFilePath, true)
+
+	// Only newer files replace their entries
+	assert.NoError(t, os.WriteFile(newerFile, []byte("changed"), 0644))
+	assert.NoError(t, os.Chtimes(newerFile, time.Now(), time.Now().Add(time.Hour)))
+	assert.NoError(t, os.WriteFile(oldFile, []byte("not newer"), 0644))
+	assert.NoError(t, os.Chtimes(oldFile, time.Now(), time.Now().Add(-time.Hour)))
+
+	addedFile := filepath.Join(tempDir, "added.txt")
+	assert.NoError(t, os.WriteFile(addedFile, []byte("added"), 0644))
+
+	assert.NoError(t, z.Update(filepath.Join(tempDir, "*.txt")))
+
+	after := readTestZip(t, zipFilePath, true)
+	assert.Len(t, after, 3)
+	assert.Contains(t, after, toZipPath(addedFile))
+	assert.Equal(t, before[toZipPath(oldFile)], after[toZipPath(oldFile)])
+	assert.NotEqual(t, before[toZipPath(newerFile)], after[toZipPath(newerFile)])
+
+	comment, err := z.Comment()
+	assert.NoError(t, err)
+	assert.Equal(t, "kept", comment)
+
+	t.Run("encrypts written entries only", func(t *testing.T) {
+		z := NewZippy(filepath.Join(t.TempDir(), testZipFileName))
+		assert.NoError(t, z.Add(oldFile, newerFile))
+
+		assert.NoError(t, os.Chtimes(newerFile, time.Now(), time.Now().Add(2*time.Hour)))
+
+		z.Encryption = &Encryption{Method: AES256, Password: "secret"}
+		assert.NoError(t, z.Update(oldFile, newerFile))
+
+		files := readTestZipFiles(t, z.Path)
+		assert.Equal(t, NoEncryption, EntryEncryption(files[toZipPath(oldFile)]))
+		assert.Equal(t, AES256, EntryEncryption(files[toZipPath(newerFile)]))
+	})
+
+	t.Run("creates missing archive", func(t *testing.T) {
+		z := NewZippy(filepath.Join(t.TempDir(), testZipFileName))
+		assert.NoError(t, z.Update(oldFile))
+
+		files, err := Contents(z.Path)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+}
+
+// Tests for [Zippy.Freshen] function.
+func Test_Zippy_Freshen(t *testing.T) {
+	tempDir := t.TempDir()
+	zipFilePath := filepath.Join(tempDir, testZipFileName)
+
+	file := filepath.Join(tempDir, "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("original"), 0644))
+
+	z := NewZippy(zipFilePath)
+	assert.NoError(t, z.Add(file))
+	before := readTestZip(t, zipFilePath, true)
+
+	assert.NoError(t, os.WriteFile(file, []byte("changed"), 0644))
+	assert.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Hour)))
+
+	// New files are never added
+	newFile := filepath.Join(tempDir, "new.txt")
+	assert.NoError(t, os.WriteFile(newFile, []byte("new"), 0644))
+
+	assert.NoError(t, z.Freshen(file, newFile))
+
+	after := readTestZip(t, zipFilePath, true)
+	assert.Equal(t, []string{toZipPath(file)}, sortedNames(after))
+	assert.NotEqual(t, before[toZipPath(file)], after[toZipPath(file)])
+
+	t.Run("missing archive", func(t *testing.T) {
+		z := NewZippy(filepath.Join(t.TempDir(), testZipFileName))
+		assert.ErrorIs(t, z.Freshen(file), os.ErrNotExist)
+	})
+}
+
+// Tests for [Zippy.Exclude] field.
+func Test_Zippy_Exclude(t *testing.T) {
+	tempDir := t.TempDir()
+	srcDir := filepath.Join(tempDir, "src")
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "build"), os.ModePerm))
+
+	for _, name := range []string{"main.go", "main.o", filepath.Join("build", "out.txt")} {
+		assert.NoError(t, os.WriteFile(filepath.Join(srcDir, name), []byte(name), 0644))
+	}
+
+	z := NewZippy(filepath.Join(tempDir, testZipFileName))
+	z.Exclude = []string{"*.o", toZipPath(filepath.Join(srcDir, "build"))}
+	assert.NoError(t, z.Add(srcDir))
+
+	entries := readTestZip(t, z.Path, true)
+	assert.Equal(t, []string{toZipPath(srcDir) + "/", toZipPath(filepath.Join(srcDir, "main.go"))}, sortedNames(entries))
+}
@@ -0,0 +1,507 @@
+package zippy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// TarCompression is the compression applied to a tar archive.
+type TarCompression uint8
+
+const (
+	TarUncompressed TarCompression = iota // TarUncompressed is a plain tar archive.
+	TarGzip                               // TarGzip is a gzip compressed tar archive.
+	TarZstd                               // TarZstd is a Zstandard compressed tar archive.
+	TarXz                                 // TarXz is an xz compressed tar archive.
+)
+
+// String returns the name of the compression.
+func (c TarCompression) String() string {
+	switch c {
+	case TarUncompressed:
+		return "none"
+	case TarGzip:
+		return "gzip"
+	case TarZstd:
+		return "zstd"
+	case TarXz:
+		return "xz"
+	default:
+		return "unknown"
+	}
+}
+
+// compressWriter returns a writer compressing to w.
+func compressWriter(w io.Writer, compression TarCompression) (io.WriteCloser, error) {
+	switch compression {
+	case TarUncompressed:
+		return nopWriteCloser{w}, nil
+	case TarGzip:
+		return gzip.NewWriter(w), nil
+	case TarZstd:
+		return zstd.NewWriter(w)
+	case TarXz:
+		return xz.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("tar compression %d: %w", compression, ErrUnsupportedFormat)
+	}
+}
+
+// decompressReader returns a reader decompressing r.
+func decompressReader(r io.Reader, compression TarCompression) (io.ReadCloser, error) {
+	switch compression {
+	case TarUncompressed:
+		return io.NopCloser(r), nil
+	case TarGzip:
+		return gzip.NewReader(r)
+	case TarZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	case TarXz:
+		reader, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return io.NopCloser(reader), nil
+	default:
+		return nil, fmt.Errorf("tar compression %d: %w", compression, ErrUnsupportedFormat)
+	}
+}
+
+// tarArchive is a tar archive open for reading.
+type tarArchive struct {
+	*tar.Reader
+	file         *os.File
+	decompressor io.ReadCloser
+}
+
+// openTar opens a tar archive for reading.
+func openTar(path string, compression TarCompression) (*tarArchive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	decompressor, err := decompressReader(file, compression)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &tarArchive{Reader: tar.NewReader(decompressor), file: file, decompressor: decompressor}, nil
+}
+
+// Close closes the archive.
+func (a *tarArchive) Close() error {
+	a.decompressor.Close()
+	return a.file.Close()
+}
+
+// copyEntries copies the entries of the archive selected by keep to tw. Global
+// PAX headers are always copied, as they apply to the entries after them.
+func (a *tarArchive) copyEntries(tw *tar.Writer, keep func(header *tar.Header, name string) (bool, error)) error {
+	for {
+		header, err := a.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			// The writer refuses global headers with anything but their records
+			header = &tar.Header{Typeflag: tar.TypeXGlobalHeader, PAXRecords: header.PAXRecords}
+		} else {
+			ok, err := keep(header, tarEntryName(header))
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				continue
+			}
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(tw, a); err != nil {
+			return err
+		}
+	}
+}
+
+// writeTar writes a new tar archive at dest through a temporary file, like
+// [writeArchive] does for zip archives.
+func writeTar(dest, pattern string, compression TarCompression, sources []io.Closer, fn func(tw *tar.Writer) error) error {
+	return writeTemp(dest, pattern, sources, func(file *os.File) error {
+		compressor, err := compressWriter(file, compression)
+		if err != nil {
+			return err
+		}
+
+		tw := tar.NewWriter(compressor)
+
+		if err := fn(tw); err != nil {
+			compressor.Close()
+			return err
+		}
+
+		if err := tw.Close(); err != nil {
+			compressor.Close()
+			return err
+		}
+
+		return compressor.Close()
+	})
+}
+
+// tarLinkName returns a tar entry or link target name without a leading "./"
+// or "/", like the names of zip entries.
+func tarLinkName(name string) string {
+	name = strings.TrimLeft(name, "/")
+	for strings.HasPrefix(name, "./") {
+		name = strings.TrimLeft(name[2:], "/")
+	}
+
+	return name
+}
+
+// tarEntryName returns the name of a tar entry as a zip entry name.
+// Directories end with a slash.
+func tarEntryName(header *tar.Header) string {
+	name := tarLinkName(header.Name)
+	if header.Typeflag == tar.TypeDir && name != "" && !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+
+	return name
+}
+
+// tarZipFile describes a tar entry as a zip file, so that tar archives are
+// used through the same interfaces as zip archives. The file holds no data.
+func tarZipFile(header *tar.Header, name string) (*zip.File, error) {
+	fileHeader, err := zip.FileInfoHeader(header.FileInfo())
+	if err != nil {
+		return nil, err
+	}
+
+	fileHeader.Name = name
+	fileHeader.Method = zip.Store
+
+	setUnixExtra(fileHeader, unixAttrs{Atime: header.AccessTime, UID: header.Uid, GID: header.Gid, HasOwner: true})
+	fileHeader.Modified = header.ModTime
+
+	return &zip.File{FileHeader: *fileHeader}, nil
+}
+
+// tarChange selects which files are written to an existing tar archive.
+type tarChange uint8
+
+const (
+	tarAdd     tarChange = iota // Add new files, keeping existing entries.
+	tarUpdate                   // Add new files and replace entries with newer files.
+	tarFreshen                  // Only replace entries with newer files.
+)
+
+// tarSource is a file to write to a tar archive.
+type tarSource struct {
+	path string
+	name string
+	info fs.FileInfo
+}
+
+// Tarry creates and changes tar archives, optionally compressed. It
+// implements [ZippyInterface] with the same globbing, junking and symbolic
+// link rules as [Zippy].
+//
+// Tar archives cannot be changed in place, so every change rewrites the
+// archive through a temporary file.
+type Tarry struct {
+	Path        string         // The path, including the file name, to the tar archive.
+	Compression TarCompression // Compression of the tar archive.
+	Junk        bool           // Specifies whether to junk the path when archiving.
+	Symlinks    SymlinkPolicy  // Specifies how symbolic links are archived.
+	Exclude     []string       // Glob patterns of entry names to leave out when archiving. A matching directory leaves out its contents.
+	Devices     bool           // Specifies whether to archive device files and named pipes. Otherwise they are refused with [ErrRefusedEntry].
+	tempFile    string         // Temp file name when working with tar archives.
+}
+
+// NewTarry creates a new Tarry instance.
+func NewTarry(path string, compression TarCompression) *Tarry {
+	return &Tarry{
+		Path:        filepath.Clean(path),
+		Compression: compression,
+		tempFile:    "zippy-*",
+	}
+}
+
+// collect walks the files to archive and returns them with their entry names.
+func (t *Tarry) collect(files ...string) ([]*tarSource, error) {
+	absArchive, err := filepath.Abs(t.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := expandGlobs(files...)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []*tarSource
+	seen := make(map[string]bool)
+
+	for _, path := range paths {
+		err := walkPath(t.Path, path, t.Symlinks, nil, func(path string) error {
+			info, err := statPolicy(path, t.Symlinks)
+			if err != nil {
+				return err
+			}
+
+			if info.Mode()&fs.ModeSymlink != 0 && t.Symlinks == SymlinkSkip {
+				return nil
+			}
+
+			// Never archive the archive itself
+			if absPath, err := filepath.Abs(path); err != nil || absPath == absArchive {
+				return err
+			}
+
+			name := toZipPath(path)
+			if skip, err := excluded(name, t.Exclude, false); skip || err != nil {
+				return err
+			}
+
+			if t.Junk {
+				name = filepath.Base(name)
+			}
+
+			if info.IsDir() {
+				name += "/"
+			}
+
+			if !seen[name] {
+				seen[name] = true
+				sources = append(sources, &tarSource{path: path, name: name, info: info})
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sources, nil
+}
+
+// writeSource writes a file to the archive.
+func (t *Tarry) writeSource(tw *tar.Writer, source *tarSource) error {
+	mode := source.info.Mode()
+	if mode&(fs.ModeDevice|fs.ModeNamedPipe) != 0 && !t.Devices {
+		return fmt.Errorf("failed to add '%s': %w", source.path, ErrRefusedEntry)
+	}
+
+	var link string
+	if mode&fs.ModeSymlink != 0 {
+		target, err := os.Readlink(source.path)
+		if err != nil {
+			return err
+		}
+
+		link = filepath.ToSlash(target)
+	}
+
+	header, err := tar.FileInfoHeader(source.info, link)
+	if err != nil {
+		return err
+	}
+
+	header.Name = source.name
+
+	if !mode.IsRegular() {
+		return tw.WriteHeader(header)
+	}
+
+	file, err := os.Open(source.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	written, err := io.Copy(tw, file)
+	if err != nil {
+		return err
+	}
+
+	return validateCopy(t.Path, header.Name, source.path, written, header.Size)
+}
+
+// change rewrites the archive with the files, keeping or replacing existing
+// entries as selected by change.
+func (t *Tarry) change(change tarChange, files ...string) error {
+	sources, err := t.collect(files...)
+	if err != nil {
+		return err
+	}
+
+	var closers []io.Closer
+
+	archive, err := openTar(t.Path, t.Compression)
+	if err == nil {
+		defer archive.Close()
+		closers = append(closers, archive)
+	} else if !errors.Is(err, fs.ErrNotExist) || change == tarFreshen {
+		return err
+	}
+
+	byName := make(map[string]*tarSource, len(sources))
+	for _, source := range sources {
+		byName[source.name] = source
+	}
+
+	// Files already in the archive are either skipped or replace their entry
+	skipped := make(map[string]bool)
+	replaced := make(map[string]bool)
+
+	return writeTar(t.Path, t.tempFile, t.Compression, closers, func(tw *tar.Writer) error {
+		if archive != nil {
+			err := archive.copyEntries(tw, func(header *tar.Header, name string) (bool, error) {
+				source, ok := byName[name]
+				if !ok {
+					return true, nil
+				}
+
+				if change != tarAdd && isNewer(header.ModTime, source.info) {
+					replaced[name] = true
+					return false, nil
+				}
+
+				skipped[name] = true
+				return true, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, source := range sources {
+			if skipped[source.name] || (change == tarFreshen && !replaced[source.name]) {
+				continue
+			}
+
+			if err := t.writeSource(tw, source); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Adds files or directories to a tar archive. Files already in the archive
+// are skipped.
+//
+// files are the files or directories to archive. Glob patterns are supported.
+func (t *Tarry) Add(files ...string) (err error) {
+	return t.change(tarAdd, files...)
+}
+
+// Deletes entries from an existing tar archive.
+//
+// files are the entries to delete. Glob patterns are supported.
+func (t *Tarry) Delete(files ...string) (err error) {
+	patterns := toZipPaths(slices.Clone(files)...)
+	if _, err := nameMatches("", false, patterns...); err != nil {
+		return err
+	}
+
+	archive, err := openTar(t.Path, t.Compression)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	return writeTar(t.Path, t.tempFile, t.Compression, []io.Closer{archive}, func(tw *tar.Writer) error {
+		return archive.copyEntries(tw, func(header *tar.Header, name string) (bool, error) {
+			match, err := nameMatches(name, false, patterns...)
+			return !match, err
+		})
+	})
+}
+
+// Updates files in a tar archive, like tar -u. Files not in the archive are
+// added and entries are replaced by files modified after them.
+//
+// files are the files or directories to update. Glob patterns are supported.
+func (t *Tarry) Update(files ...string) (err error) {
+	return t.change(tarUpdate, files...)
+}
+
+// Freshens files in a tar archive. Entries are replaced by files modified
+// after them, but files not in the archive are not added.
+//
+// files are the files or directories to freshen. Glob patterns are supported.
+func (t *Tarry) Freshen(files ...string) (err error) {
+	return t.change(tarFreshen, files...)
+}
+
+// Copies entries from an existing tar archive to a new tar archive. The new
+// archive is compressed as its file extension says, or like the existing
+// archive if the extension is not known.
+//
+// dest is the new tar archive path.
+//
+// files are the entries to copy. Glob patterns are supported. If no files are
+// provided, all entries will be copied.
+func (t *Tarry) Copy(dest string, files ...string) (err error) {
+	patterns := toZipPaths(slices.Clone(files)...)
+	if _, err := nameMatches("", false, patterns...); err != nil {
+		return err
+	}
+
+	compression, ok := tarCompressionFor(dest)
+	if !ok {
+		compression = t.Compression
+	}
+
+	archive, err := openTar(t.Path, t.Compression)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	return writeTar(dest, t.tempFile, compression, nil, func(tw *tar.Writer) error {
+		return archive.copyEntries(tw, func(header *tar.Header, name string) (bool, error) {
+			if len(patterns) == 0 {
+				return true, nil
+			}
+
+			return nameMatches(name, false, patterns...)
+		})
+	})
+}
@@ -0,0 +1,387 @@
+package zippy
+
+import (
+	"archive/tar"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tarTestEntry is an entry written by createTarFile.
+type tarTestEntry struct {
+	header *tar.Header
+	body   string
+}
+
+// createTarFile writes a tar archive with the given entries.
+func createTarFile(t *testing.T, path string, compression TarCompression, entries ...tarTestEntry) {
+	t.Helper()
+
+	err := writeTar(path, "zippy-*", compression, nil, func(tw *tar.Writer) error {
+		for _, entry := range entries {
+			if entry.header.Typeflag == tar.TypeReg {
+				entry.header.Size = int64(len(entry.body))
+			}
+
+			if entry.header.Mode == 0 && entry.header.Typeflag != tar.TypeXGlobalHeader {
+				entry.header.Mode = 0644
+			}
+
+			if err := tw.WriteHeader(entry.header); err != nil {
+				return err
+			}
+
+			if _, err := tw.Write([]byte(entry.body)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+// tarEntries returns the contents of each entry of a tar archive, keyed by
+// entry name.
+func tarEntries(t *testing.T, path string, compression TarCompression) map[string]string {
+	t.Helper()
+
+	archive, err := openTar(path, compression)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer archive.Close()
+
+	entries := make(map[string]string)
+	for {
+		header, err := archive.Next()
+		if err != nil {
+			break
+		}
+
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		data, err := io.ReadAll(archive)
+		assert.NoError(t, err)
+
+		entries[header.Name] = string(data)
+	}
+
+	return entries
+}
+
+// createTarSourceDir creates a directory of files to archive.
+func createTarSourceDir(t *testing.T) string {
+	t.Helper()
+
+	srcDir := filepath.Join(t.TempDir(), "src")
+	assert.NoError(t, os.MkdirAll(filepath.Join(srcDir, "sub"), os.ModePerm))
+
+	for _, name := range []string{"a.txt", "b.o", filepath.Join("sub", "c.txt")} {
+		assert.NoError(t, os.WriteFile(filepath.Join(srcDir, name), []byte("contents of "+name), 0644))
+	}
+
+	return srcDir
+}
+
+// Tests for [Tarry] and [Untarry] with each compression.
+func Test_Tarry_compressions(t *testing.T) {
+	for _, compression := range []TarCompression{TarUncompressed, TarGzip, TarZstd, TarXz} {
+		t.Run(compression.String(), func(t *testing.T) {
+			srcDir := createTarSourceDir(t)
+			tarPath := filepath.Join(t.TempDir(), "test.tar")
+
+			tr := NewTarry(tarPath, compression)
+			assert.NoError(t, tr.Add(srcDir))
+
+			entries := tarEntries(t, tarPath, compression)
+			assert.Len(t, entries, 5)
+			assert.Equal(t, "contents of a.txt", entries[toZipPath(filepath.Join(srcDir, "a.txt"))])
+
+			u, err := NewUntarry(tarPath, compression, nil)
+			assert.NoError(t, err)
+
+			dest := filepath.Join(t.TempDir(), "output")
+			files, err := u.ExtractTo(dest)
+			assert.NoError(t, err)
+			assert.Len(t, files, 5)
+
+			contents, err := os.ReadFile(filepath.Join(dest, toZipPath(filepath.Join(srcDir, "sub", "c.txt"))))
+			assert.NoError(t, err)
+			assert.Equal(t, "contents of sub/c.txt", strings.ReplaceAll(string(contents), "\\", "/"))
+		})
+	}
+}
+
+// Tests for [Tarry.Add] function.
+func Test_Tarry_Add(t *testing.T) {
+	t.Run("existing entries are kept", func(t *testing.T) {
+		srcDir := createTarSourceDir(t)
+		tarPath := filepath.Join(t.TempDir(), "test.tar.gz")
+		file := filepath.Join(srcDir, "a.txt")
+
+		tr := NewTarry(tarPath, TarGzip)
+		assert.NoError(t, tr.Add(file))
+
+		assert.NoError(t, os.WriteFile(file, []byte("changed"), 0644))
+		assert.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Hour)))
+		assert.NoError(t, tr.Add(file, filepath.Join(srcDir, "b.o")))
+
+		entries := tarEntries(t, tarPath, TarGzip)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "contents of a.txt", entries[toZipPath(file)])
+	})
+
+	t.Run("junk and exclude", func(t *testing.T) {
+		srcDir := createTarSourceDir(t)
+		tarPath := filepath.Join(t.TempDir(), "test.tar")
+
+		tr := NewTarry(tarPath, TarUncompressed)
+		tr.Junk = true
+		tr.Exclude = []string{"*.o", "sub"}
+		assert.NoError(t, tr.Add(filepath.Join(srcDir, "*")))
+
+		assert.Equal(t, map[string]string{"a.txt": "contents of a.txt"}, tarEntries(t, tarPath, TarUncompressed))
+	})
+
+	t.Run("long names", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), strings.Repeat("long-directory-name/", 8))
+		assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+		file := filepath.Join(dir, strings.Repeat("x", 120)+".txt")
+		assert.NoError(t, os.WriteFile(file, []byte("long"), 0644))
+
+		tarPath := filepath.Join(t.TempDir(), "test.tar")
+		assert.NoError(t, NewTarry(tarPath, TarUncompressed).Add(file))
+		assert.Equal(t, "long", tarEntries(t, tarPath, TarUncompressed)[toZipPath(file)])
+	})
+
+	t.Run("archive in the archived directory", func(t *testing.T) {
+		srcDir := createTarSourceDir(t)
+		tarPath := filepath.Join(srcDir, "test.tar")
+
+		tr := NewTarry(tarPath, TarUncompressed)
+		assert.NoError(t, tr.Add(srcDir))
+		assert.NoError(t, tr.Update(srcDir))
+		assert.NotContains(t, tarEntries(t, tarPath, TarUncompressed), toZipPath(tarPath))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		tr := NewTarry(filepath.Join(t.TempDir(), "test.tar"), TarUncompressed)
+		assert.ErrorIs(t, tr.Add("missing.txt"), fs.ErrNotExist)
+	})
+}
+
+// Tests for [Tarry.Update] and [Tarry.Freshen] functions.
+func Test_Tarry_Update_Freshen(t *testing.T) {
+	srcDir := createTarSourceDir(t)
+	tarPath := filepath.Join(t.TempDir(), "test.tar.zst")
+	changed := filepath.Join(srcDir, "a.txt")
+	added := filepath.Join(srcDir, "b.o")
+
+	tr := NewTarry(tarPath, TarZstd)
+	assert.NoError(t, tr.Add(changed))
+
+	assert.NoError(t, os.WriteFile(changed, []byte("changed"), 0644))
+	assert.NoError(t, os.Chtimes(changed, time.Now(), time.Now().Add(time.Hour)))
+
+	// Freshening replaces newer entries only
+	assert.NoError(t, tr.Freshen(changed, added))
+	assert.Equal(t, map[string]string{toZipPath(changed): "changed"}, tarEntries(t, tarPath, TarZstd))
+
+	assert.NoError(t, tr.Update(changed, added))
+	assert.Len(t, tarEntries(t, tarPath, TarZstd), 2)
+
+	missing := NewTarry(filepath.Join(t.TempDir(), "missing.tar"), TarUncompressed)
+	assert.ErrorIs(t, missing.Freshen(changed), fs.ErrNotExist)
+}
+
+// Tests for [Tarry.Delete] and [Tarry.Copy] functions.
+func Test_Tarry_Delete_Copy(t *testing.T) {
+	tempDir := t.TempDir()
+	tarPath := filepath.Join(tempDir, "test.tar.xz")
+
+	createTarFile(t, tarPath, TarXz,
+		tarTestEntry{header: &tar.Header{Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "global"}}},
+		tarTestEntry{header: &tar.Header{Typeflag: tar.TypeDir, Name: "dir/"}},
+		tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: "dir/a.txt"}, body: "a"},
+		tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: "dir/b.o"}, body: "b"},
+	)
+
+	tr := NewTarry(tarPath, TarXz)
+
+	// Copying to a gzip archive recompresses the entries
+	copyPath := filepath.Join(tempDir, "copy.tgz")
+	assert.NoError(t, tr.Copy(copyPath, "dir/*.txt"))
+	assert.Equal(t, map[string]string{"dir/a.txt": "a"}, tarEntries(t, copyPath, TarGzip))
+
+	// Patterns are converted to entry paths like they are when deleting
+	assert.NoError(t, tr.Copy(copyPath, "/dir/b.o"))
+	assert.Equal(t, map[string]string{"dir/b.o": "b"}, tarEntries(t, copyPath, TarGzip))
+
+	assert.NoError(t, tr.Delete("*/*.o"))
+	assert.Equal(t, map[string]string{"dir/": "", "dir/a.txt": "a"}, tarEntries(t, tarPath, TarXz))
+
+	assert.Error(t, tr.Delete("["))
+}
+
+// Tests for [Untarry.ExtractFilesTo] function.
+func Test_Untarry_ExtractFilesTo(t *testing.T) {
+	extract := func(t *testing.T, options *UnzippyOptions, entries ...tarTestEntry) (string, []string, error) {
+		tempDir := t.TempDir()
+		tarPath := filepath.Join(tempDir, "test.tar")
+		createTarFile(t, tarPath, TarUncompressed, entries...)
+
+		u, err := NewUntarry(tarPath, TarUncompressed, options)
+		assert.NoError(t, err)
+
+		dest := filepath.Join(tempDir, "output")
+		files, err := u.ExtractFilesTo(dest)
+
+		var names []string
+		for _, file := range files {
+			names = append(names, file.Name)
+		}
+
+		sort.Strings(names)
+		return dest, names, err
+	}
+
+	t.Run("pax and long names", func(t *testing.T) {
+		longName := strings.Repeat("d/", 60) + "file.txt"
+		modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		dest, names, err := extract(t, nil,
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "global"}}},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: longName, ModTime: modified, Format: tar.FormatPAX}, body: "pax"},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: "gnu/" + strings.Repeat("g", 150), Format: tar.FormatGNU}, body: "gnu"},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: "./dot/héllo.txt", Format: tar.FormatPAX}, body: "unicode"},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"d/" + strings.Repeat("d/", 59) + "file.txt", "dot/héllo.txt", "gnu/" + strings.Repeat("g", 150)}, names)
+
+		info, err := os.Stat(filepath.Join(dest, filepath.FromSlash(longName)))
+		assert.NoError(t, err)
+		assert.True(t, info.ModTime().Equal(modified))
+
+		contents, err := os.ReadFile(filepath.Join(dest, "dot", "héllo.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "unicode", string(contents))
+	})
+
+	t.Run("zip slip", func(t *testing.T) {
+		_, _, err := extract(t, nil, tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: "../evil.txt"}, body: "evil"})
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
+
+	t.Run("symbolic link outside of the destination", func(t *testing.T) {
+		skipWithoutSymlinks(t)
+
+		_, _, err := extract(t, &UnzippyOptions{Symlinks: ExtractSymlinkCreate},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "../outside"}})
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
+
+	t.Run("write through symbolic link", func(t *testing.T) {
+		skipWithoutSymlinks(t)
+
+		_, _, err := extract(t, &UnzippyOptions{Symlinks: ExtractSymlinkCreate},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeDir, Name: "dir/"}},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "dir"}},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: "link/file.txt"}, body: "through"})
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
+
+	t.Run("symbolic link target through earlier link", func(t *testing.T) {
+		skipWithoutSymlinks(t)
+
+		dot := tarTestEntry{header: &tar.Header{Typeflag: tar.TypeSymlink, Name: "x/a", Linkname: "."}}
+		up := tarTestEntry{header: &tar.Header{Typeflag: tar.TypeSymlink, Name: "x/b", Linkname: "a/../.."}}
+		dir := tarTestEntry{header: &tar.Header{Typeflag: tar.TypeDir, Name: "x/"}}
+
+		_, _, err := extract(t, &UnzippyOptions{Symlinks: ExtractSymlinkCreate}, dir, dot, up)
+		assert.ErrorIs(t, err, ErrUnsafePath)
+
+		_, _, err = extract(t, &UnzippyOptions{Symlinks: ExtractSymlinkCreate}, dir, up, dot)
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
+
+	t.Run("hard links are refused by default", func(t *testing.T) {
+		entries := []tarTestEntry{
+			{header: &tar.Header{Typeflag: tar.TypeReg, Name: "target.txt"}, body: "target"},
+			{header: &tar.Header{Typeflag: tar.TypeLink, Name: "link.txt", Linkname: "target.txt"}},
+		}
+
+		_, _, err := extract(t, nil, entries...)
+		assert.ErrorIs(t, err, ErrRefusedEntry)
+
+		var refusedErr *RefusedEntryError
+		if assert.ErrorAs(t, err, &refusedErr) {
+			assert.Equal(t, "link.txt", refusedErr.Entry)
+			assert.Equal(t, "hard link", refusedErr.Type)
+		}
+
+		dest, names, err := extract(t, &UnzippyOptions{HardLinks: true}, entries...)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"link.txt", "target.txt"}, names)
+
+		contents, err := os.ReadFile(filepath.Join(dest, "link.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "target", string(contents))
+
+		_, _, err = extract(t, &UnzippyOptions{HardLinks: true},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeLink, Name: "link.txt", Linkname: "../../etc/passwd"}})
+		assert.ErrorIs(t, err, ErrUnsafePath)
+	})
+
+	t.Run("devices are refused by default", func(t *testing.T) {
+		_, _, err := extract(t, nil, tarTestEntry{header: &tar.Header{Typeflag: tar.TypeChar, Name: "null", Devmajor: 1, Devminor: 3}})
+		assert.ErrorIs(t, err, ErrRefusedEntry)
+
+		if runtime.GOOS == "windows" {
+			t.Skip("named pipes are not supported on Windows")
+		}
+
+		dest, _, err := extract(t, &UnzippyOptions{Devices: true}, tarTestEntry{header: &tar.Header{Typeflag: tar.TypeFifo, Name: "fifo"}})
+		assert.NoError(t, err)
+
+		info, err := os.Lstat(filepath.Join(dest, "fifo"))
+		assert.NoError(t, err)
+		assert.NotZero(t, info.Mode()&fs.ModeNamedPipe)
+	})
+
+	t.Run("junk, exclude and overwrite", func(t *testing.T) {
+		tempDir := t.TempDir()
+		tarPath := filepath.Join(tempDir, "test.tar")
+		createTarFile(t, tarPath, TarUncompressed,
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: "dir/a.txt"}, body: "a"},
+			tarTestEntry{header: &tar.Header{Typeflag: tar.TypeReg, Name: "dir/b.o"}, body: "b"},
+		)
+
+		u, err := NewUntarry(tarPath, TarUncompressed, &UnzippyOptions{Junk: true, Exclude: []string{"*.o"}})
+		assert.NoError(t, err)
+
+		dest := filepath.Join(tempDir, "output")
+		files, err := u.ExtractFilesTo(dest, "dir/*")
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+		assert.FileExists(t, filepath.Join(dest, "a.txt"))
+
+		u.Options.FailExisting = true
+		_, err = u.ExtractFilesTo(dest)
+		assert.ErrorIs(t, err, fs.ErrExist)
+
+		u.Options.FailExisting = false
+		_, err = u.ExtractFilesTo(dest)
+		assert.NoError(t, err)
+	})
+}
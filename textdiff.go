@@ -0,0 +1,175 @@
+package zippy
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// diffContext is the number of unchanged lines shown around each change of a
+// unified diff.
+const diffContext = 3
+
+// lineOp is a single line of an edit script: an unchanged (' '), removed ('-')
+// or added ('+') line.
+type lineOp struct {
+	kind    byte
+	text    string
+	oldLine int // Number of old lines before this one.
+	newLine int // Number of new lines before this one.
+}
+
+// isText reports whether data looks like text rather than binary data.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && !bytes.Contains(data, []byte{0})
+}
+
+// splitLines splits text into lines, without their line endings.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script turning a into b, using the
+// Myers difference algorithm.
+func diffLines(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	if n+m == 0 {
+		return nil
+	}
+
+	offset := n + m
+	v := make([]int, 2*offset+2)
+
+	// trace holds the furthest reaching paths found before each step
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		trace = append(trace, slices.Clone(v))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace back from the end to recover the edits
+	var ops []lineOp
+	x, y := n, m
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		prevK := k - 1
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, lineOp{kind: ' ', text: a[x], oldLine: x, newLine: y})
+		}
+
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, lineOp{kind: '+', text: b[y], oldLine: x, newLine: y})
+			} else {
+				x--
+				ops = append(ops, lineOp{kind: '-', text: a[x], oldLine: x, newLine: y})
+			}
+		}
+	}
+
+	slices.Reverse(ops)
+	return ops
+}
+
+// unifiedDiff returns the differences between two texts in the unified diff
+// format, or "" if they are equal.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var b strings.Builder
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are close enough to share context
+		last := i
+		for j := i; j < len(ops) && j-last <= 2*diffContext; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+
+		start := max(i-diffContext, 0)
+		stop := min(last+diffContext+1, len(ops))
+		hunk := ops[start:stop]
+
+		oldCount, newCount := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(hunk[0].oldLine, oldCount), hunkRange(hunk[0].newLine, newCount))
+		for _, op := range hunk {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.text)
+		}
+
+		i = stop
+	}
+
+	return b.String()
+}
+
+// hunkRange formats the line range of a unified diff hunk.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,376 @@
+package zippy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Untarry extracts tar archives, optionally compressed. It implements
+// [UnzippyInterface] with the same globbing, junking, overwrite and path
+// safety rules as [Unzippy].
+//
+// The extracted entries are returned as [zip.File] values describing them.
+// They hold no archive data, so they cannot be opened.
+type Untarry struct {
+	Path        string            // Path to the tar archive.
+	Compression TarCompression    // Compression of the tar archive.
+	Options     *UnzippyOptions   // Options to use when extracting files.
+	Plan        *Plan             // Plan of the last extraction run with [UnzippyOptions.DryRun].
+	Renamed     map[string]string // Names written by the last extraction for the entries renamed by [UnzippyOptions.Sanitize], keyed by the names of the returned files.
+}
+
+// NewUntarry creates a new Untarry instance.
+func NewUntarry(path string, compression TarCompression, options *UnzippyOptions) (*Untarry, error) {
+	if path == "" {
+		return nil, ErrEmptyPath
+	}
+
+	if options == nil {
+		options = &UnzippyOptions{}
+	}
+
+	return &Untarry{
+		Path:        path,
+		Compression: compression,
+		Options:     options,
+	}, nil
+}
+
+// Extract all files from tar archive to the same directory as the archive.
+func (u *Untarry) Extract() ([]*zip.File, error) {
+	return u.ExtractFiles()
+}
+
+// Extracts the specified files from the tar archive. If no files are specified,
+// all files will be extracted. Glob patterns are supported.
+func (u *Untarry) ExtractFiles(files ...string) ([]*zip.File, error) {
+	return u.ExtractFilesTo(filepath.Dir(u.Path), files...)
+}
+
+// Extracts all files from the tar archive to a destination directory.
+func (u *Untarry) ExtractTo(dest string) ([]*zip.File, error) {
+	return u.ExtractFilesTo(dest)
+}
+
+// Extracts the specified files from the tar archive to a destination
+// directory. The destination directory will be created if it does not exist.
+// The file modification times will be preserved. If no files are specified,
+// all files will be extracted. Glob patterns are supported.
+//
+// Hard links and device files are refused with [ErrRefusedEntry] unless the
+// options allow them.
+func (u *Untarry) ExtractFilesTo(dest string, files ...string) ([]*zip.File, error) {
+	u.Renamed = newRenamed(u.Options)
+
+	if u.Options.FailUnmatched && len(files) > 0 {
+		if err := u.checkUnmatched(files); err != nil {
+			return nil, err
+		}
+	}
+
+	var plan *Plan
+	if u.Options.DryRun {
+		plan = newPlan(u.Path, dest)
+	} else if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	archive, err := openTar(u.Path, u.Compression)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	// Attributes are restored like they are for zip entries
+	unzippy := &Unzippy{Path: u.Path, Options: u.Options}
+
+	links := make(map[string]string)
+	dirs := make(map[string]*zip.File)
+	extracted := []*zip.File{}
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		name := tarEntryName(header)
+		if header.Typeflag == tar.TypeXGlobalHeader || name == "" {
+			continue
+		}
+
+		file, err := tarZipFile(header, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok, err := u.selected(header, file, files); !ok || err != nil {
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if u.Options.Junk {
+			file.Name = filepath.Base(file.Name)
+		}
+
+		written, sanitized := u.Options.extractName(file.Name)
+		if sanitized {
+			u.Renamed[file.Name] = written
+		}
+
+		path, err := safeJoin(u.Path, dest, written)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkLinkFree(u.Path, file.Name, dest, path, links); err != nil {
+			return nil, err
+		}
+
+		if err := u.refuse(header); err != nil {
+			return nil, err
+		}
+
+		if plan != nil {
+			planExtract(plan, name, path, header.Typeflag == tar.TypeDir)
+
+			if header.Typeflag == tar.TypeSymlink && u.Options.Symlinks == ExtractSymlinkCreate {
+				links[path] = ""
+			}
+
+			extracted = append(extracted, file)
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.ModePerm); err != nil {
+				return nil, err
+			}
+
+			dirs[path] = file
+			extracted = append(extracted, file)
+			continue
+		case tar.TypeReg:
+			err = u.writeFile(unzippy, header.Name, path, file.Mode(), archive, header.Size)
+		case tar.TypeSymlink:
+			if u.Options.Symlinks == ExtractSymlinkCreate {
+				if err := linkSymlink(u.Path, header.Name, header.Linkname, dest, path, links, u.Options.FailExisting); err != nil {
+					return nil, err
+				}
+
+				// Setting the times would follow the link to its target
+				extracted = append(extracted, file)
+				continue
+			}
+
+			err = u.writeFile(unzippy, header.Name, path, file.Mode(), strings.NewReader(header.Linkname), int64(len(header.Linkname)))
+		case tar.TypeLink:
+			if err := u.createHardLink(dest, path, header, links); err != nil {
+				return nil, err
+			}
+
+			// The link shares the attributes of its target
+			extracted = append(extracted, file)
+			continue
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			err = u.createDevice(path, header)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := unzippy.restoreAttrs(file, path, nil); err != nil {
+			return nil, err
+		}
+
+		extracted = append(extracted, file)
+	}
+
+	if plan != nil {
+		if len(files) > 0 {
+			if plan.Unmatched, err = u.unmatched(files); err != nil {
+				return nil, err
+			}
+		}
+
+		u.Plan = plan
+		return extracted, nil
+	}
+
+	if err := unzippy.restoreDirAttrs(dirs, nil); err != nil {
+		return nil, err
+	}
+
+	return extracted, nil
+}
+
+// checkUnmatched fails with an [UnmatchedError] if any of the glob patterns
+// matches no entry of the archive.
+func (u *Untarry) checkUnmatched(patterns []string) error {
+	unmatched, err := u.unmatched(patterns)
+	if err != nil {
+		return err
+	}
+
+	if len(unmatched) > 0 {
+		return &UnmatchedError{Archive: u.Path, Patterns: unmatched}
+	}
+
+	return nil
+}
+
+// unmatched returns the glob patterns that match no entry of the archive. The
+// archive is read once more, as entries are extracted while it is read.
+func (u *Untarry) unmatched(patterns []string) ([]string, error) {
+	archive, err := openTar(u.Path, u.Compression)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var names []string
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if name := tarEntryName(header); header.Typeflag != tar.TypeXGlobalHeader && name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return unmatchedPatterns(names, patterns, u.Options.IgnoreCase)
+}
+
+// selected reports whether an entry matches the files to extract and is not
+// excluded by the options.
+func (u *Untarry) selected(header *tar.Header, file *zip.File, files []string) (bool, error) {
+	if len(files) > 0 {
+		if match, err := fileFound(file, u.Options.IgnoreCase, files...); !match || err != nil {
+			return false, err
+		}
+	}
+
+	if header.Typeflag == tar.TypeSymlink && u.Options.Symlinks == ExtractSymlinkSkip {
+		return false, nil
+	}
+
+	skip, err := excluded(file.Name, u.Options.Exclude, u.Options.IgnoreCase)
+	return !skip, err
+}
+
+// writeFile writes the contents of the named entry to path, checking that all
+// of it was written.
+func (u *Untarry) writeFile(unzippy *Unzippy, name, path string, mode fs.FileMode, r io.Reader, size int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	destFile, err := unzippy.createFile(path, mode)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	written, err := io.Copy(destFile, r)
+	if err != nil {
+		return err
+	}
+
+	if err := validateCopy(u.Path, name, path, written, size); err != nil {
+		return err
+	}
+
+	return destFile.Close()
+}
+
+// refuse returns a [RefusedEntryError] if the type of an entry is not
+// extracted with the options.
+func (u *Untarry) refuse(header *tar.Header) error {
+	var kind string
+	switch header.Typeflag {
+	case tar.TypeDir, tar.TypeReg, tar.TypeSymlink:
+		return nil
+	case tar.TypeLink:
+		if u.Options.HardLinks {
+			return nil
+		}
+		kind = "hard link"
+	case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+		if u.Options.Devices {
+			return nil
+		}
+		kind = "device file"
+	default:
+		kind = fmt.Sprintf("type '%c'", header.Typeflag)
+	}
+
+	return &RefusedEntryError{
+		EntryError: EntryError{Op: "extract", Archive: u.Path, Entry: header.Name, Err: ErrRefusedEntry},
+		Type:       kind,
+	}
+}
+
+// createHardLink creates a hard link entry at path, refusing targets outside
+// of dest or reached through a symbolic link created by the extraction.
+func (u *Untarry) createHardLink(dest, path string, header *tar.Header, links map[string]string) error {
+	targetName, _ := u.Options.extractName(tarLinkName(header.Linkname))
+
+	target, err := safeJoin(u.Path, dest, targetName)
+	if err != nil {
+		return err
+	}
+
+	if err := checkLinkFree(u.Path, header.Name, dest, target, links); err != nil {
+		return err
+	}
+
+	if err := u.prepare(path); err != nil {
+		return err
+	}
+
+	return os.Link(target, path)
+}
+
+// createDevice creates a device file or named pipe entry at path.
+func (u *Untarry) createDevice(path string, header *tar.Header) error {
+	if err := u.prepare(path); err != nil {
+		return err
+	}
+
+	return mknod(path, header.FileInfo().Mode(), header.Devmajor, header.Devminor)
+}
+
+// prepare creates the directory of path and removes an existing file at path
+// if it may be overwritten.
+func (u *Untarry) prepare(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(path); err == nil && !info.IsDir() && !u.Options.FailExisting {
+		return os.Remove(path)
+	}
+
+	return nil
+}
@@ -2,11 +2,13 @@ package zippy
 
 import (
 	"archive/zip"
-	"fmt"
+	"errors"
 	"hash/crc32"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 )
 
 type UnzippyInterface interface {
@@ -17,13 +19,33 @@ type UnzippyInterface interface {
 }
 
 type UnzippyOptions struct {
-	Junk      bool // Junk specifies whether to junk the path of files when extracting.
-	Overwrite bool // Overwrite specifies whether to overwrite files when extracting.
+	Junk          bool                              // Junk specifies whether to junk the path of files when extracting.
+	Overwrite     bool                              // Deprecated: existing files are overwritten unless FailExisting is set.
+	FailExisting  bool                              // FailExisting specifies whether extracting fails with an error wrapping [fs.ErrExist] instead of overwriting existing files.
+	Password      string                            // Password specifies the password used to decrypt encrypted entries.
+	PasswordFunc  func(name string) (string, error) // PasswordFunc is called for each encrypted entry to get its password. It takes precedence over Password.
+	Symlinks      ExtractSymlinkPolicy              // Symlinks specifies how symbolic link entries are extracted.
+	Permissions   bool                              // Permissions specifies whether to restore the exact permission bits, ignoring the umask.
+	SpecialBits   bool                              // SpecialBits specifies whether to restore the setuid, setgid and sticky bits. Requires Permissions.
+	AccessTimes   bool                              // AccessTimes specifies whether to restore access times from the extended timestamp extra field.
+	Ownership     bool                              // Ownership specifies whether to restore the owner and group when running as root.
+	Exclude       []string                          // Exclude holds glob patterns of entries not to extract. A matching directory excludes its contents.
+	HardLinks     bool                              // HardLinks specifies whether to create the hard link entries of tar archives. Otherwise they are refused with [ErrRefusedEntry].
+	Devices       bool                              // Devices specifies whether to create the device file and named pipe entries of tar archives. Otherwise they are refused with [ErrRefusedEntry].
+	Encoding      NameEncoding                      // Encoding specifies how names stored without the UTF-8 flag are decoded.
+	DryRun        bool                              // DryRun specifies whether to set the Plan of the extractor to the files extraction would write instead of writing them.
+	FailUnmatched bool                              // FailUnmatched specifies whether to fail with an [UnmatchedError] when a pattern matches no entry, before anything is extracted or verified.
+	IgnoreCase    bool                              // IgnoreCase specifies whether patterns, including Exclude, match entry names case-insensitively, like zip -ic.
+	Collisions    bool                              // Collisions specifies whether to fail with a [CollisionError] before extracting zip entries that would be written to the same file.
+	Folding       NameFolding                       // Folding specifies which names are treated as the same file when checking Collisions. Identical names always are.
+	Sanitize      NameSanitizer                     // Sanitize specifies how names that cannot be created on Windows are rewritten when extracting.
 }
 
 type Unzippy struct {
-	Path    string          // Path to the zip archive.
-	Options *UnzippyOptions // Options to use when extracting files.
+	Path    string            // Path to the zip archive.
+	Options *UnzippyOptions   // Options to use when extracting files.
+	Plan    *Plan             // Plan of the last extraction run with [UnzippyOptions.DryRun].
+	Renamed map[string]string // Names written by the last extraction for the entries renamed by [UnzippyOptions.Sanitize], keyed by the names of the returned files.
 }
 
 // NewUnzippy creates a new Unzippy instance.
@@ -58,22 +80,67 @@ func (u *Unzippy) ExtractFiles(files ...string) ([]*zip.File, error) {
 // The file modification times will be preserved. If no files are specified, all
 // files will be extracted. Glob patterns are supported.
 func (u *Unzippy) ExtractFilesTo(dest string, files ...string) ([]*zip.File, error) {
-	if err := os.MkdirAll(dest, os.ModePerm); err != nil {
-		return nil, err
+	u.Renamed = newRenamed(u.Options)
+
+	if !u.Options.DryRun {
+		if err := os.MkdirAll(dest, os.ModePerm); err != nil {
+			return nil, err
+		}
 	}
 
-	zipReader, err := zip.OpenReader(u.Path)
+	zipReader, err := openZip(u.Path)
 	if err != nil {
 		return nil, err
 	}
 	defer zipReader.Close()
 
-	extFiles, err := filterFiles(zipReader.File, files...)
+	decodeNames(zipReader.File, u.Options.Encoding)
+
+	if u.Options.FailUnmatched {
+		if err := checkUnmatched(u.Path, zipReader.File, files, u.Options.IgnoreCase); err != nil {
+			return nil, err
+		}
+	}
+
+	extFiles, err := filterFiles(zipReader.File, u.Options.IgnoreCase, files...)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := u.unzipFiles(dest, extFiles...); err != nil {
+	if u.Options.Symlinks == ExtractSymlinkSkip {
+		extFiles = slices.DeleteFunc(extFiles, isSymlink)
+	}
+
+	if u.Options.Exclude != nil {
+		if extFiles, err = excludeFiles(extFiles, u.Options.Exclude, u.Options.IgnoreCase); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.Options.Collisions {
+		if err := u.checkCollisions(extFiles); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.Options.DryRun {
+		plan, err := u.planFiles(dest, files, zipReader.File, extFiles)
+		if err != nil {
+			return nil, err
+		}
+
+		u.Plan = plan
+		return extFiles, nil
+	}
+
+	var extras map[*zip.File][]byte
+	if u.Options.AccessTimes {
+		if extras, err = localExtras(zipReader, extFiles); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.unzipFiles(dest, extras, extFiles...); err != nil {
 		return nil, err
 	}
 
@@ -87,34 +154,151 @@ func (u *Unzippy) ExtractTo(dest string) ([]*zip.File, error) {
 	return u.ExtractFilesTo(dest)
 }
 
+// planFiles returns the plan of extracting files to dest, with the same
+// paths and checks as [Unzippy.unzipFiles]. zipFiles are all the files of the
+// archive, matched against patterns.
+func (u *Unzippy) planFiles(dest string, patterns []string, zipFiles []*zip.File, files []*zip.File) (*Plan, error) {
+	plan := newPlan(u.Path, dest)
+
+	unmatched, err := unmatchedPatterns(zipFileNames(zipFiles), patterns, u.Options.IgnoreCase)
+	if err != nil {
+		return nil, err
+	}
+	plan.Unmatched = unmatched
+
+	// Symbolic links that would be created, which later entries must not
+	// write through. Their targets are not read in a dry run
+	links := make(map[string]string)
+
+	for _, file := range files {
+		name, sanitized := u.Options.extractName(file.Name)
+		if sanitized {
+			u.Renamed[file.Name] = name
+		}
+
+		filePath, err := safeJoin(u.Path, dest, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := checkLinkFree(u.Path, name, dest, filePath, links); err != nil {
+			return nil, err
+		}
+
+		planExtract(plan, file.Name, filePath, file.FileInfo().IsDir())
+
+		if isSymlink(file) && u.Options.Symlinks == ExtractSymlinkCreate {
+			links[filePath] = ""
+		}
+	}
+
+	return plan, nil
+}
+
 // copyAndValidate copies the contents of a zipped file to the output file and
 // validates the copy by checking the CRC32 checksum and the number of bytes
 // written.
 func (u *Unzippy) copyAndValidate(zippedFileReader io.Reader, zipFile *zip.File, dest string, destFile *os.File) error {
-	hash := crc32.NewIEEE()
-
-	// Copy the zipped file to the output file and calculate the checksum
-	// using a TeeReader to read from the zipped file and write to the hash
-	// at the same time.
-	written, err := io.Copy(destFile, io.TeeReader(zippedFileReader, hash))
+	written, err := copyWithChecksum(destFile, zippedFileReader, u.Path, zipFile)
 	if err != nil {
 		return err
 	}
 
+	return validateCopy(u.Path, zipFile.Name, dest, written, int64(zipFile.UncompressedSize64))
+}
+
+// copyWithChecksum copies the contents of a zipped file of the archive at
+// path archive to w and verifies the CRC32 checksum of the copied data. It
+// returns the number of bytes copied.
+func copyWithChecksum(w io.Writer, zippedFileReader io.Reader, archive string, zipFile *zip.File) (int64, error) {
+	hash := crc32.NewIEEE()
+
+	// Copy the zipped file to the output and calculate the checksum using a
+	// TeeReader to read from the zipped file and write to the hash at the
+	// same time.
+	written, err := io.Copy(w, io.TeeReader(zippedFileReader, hash))
+
+	// The zip reader checks the checksum itself once it reaches the end
+	if errors.Is(err, zip.ErrChecksum) {
+		return written, &ChecksumError{
+			EntryError: EntryError{Op: "copy", Archive: archive, Entry: zipFile.Name, Err: err},
+			Expected:   zipFile.CRC32,
+			Actual:     hash.Sum32(),
+		}
+	} else if err != nil {
+		return written, err
+	}
+
 	checksum := hash.Sum32()
 
-	// Verify the checksum of the extracted file against the expected checksum
+	// Verify the checksum of the copied data against the expected checksum
 	// from the zip file.
-	if checksum != zipFile.CRC32 {
-		return fmt.Errorf("failed to copy '%s': expected '%08x' checksum, got '%08x' checksum", dest, zipFile.CRC32, checksum)
+	if hasCRC(zipFile) && checksum != zipFile.CRC32 {
+		return written, &ChecksumError{
+			EntryError: EntryError{Op: "copy", Archive: archive, Entry: zipFile.Name},
+			Expected:   zipFile.CRC32,
+			Actual:     checksum,
+		}
+	}
+
+	return written, nil
+}
+
+// password returns the password to use to decrypt the named entry.
+func (u *Unzippy) password(name string) (string, error) {
+	if u.Options.PasswordFunc != nil {
+		return u.Options.PasswordFunc(name)
 	}
 
-	return validateCopy(dest, written, int64(zipFile.UncompressedSize64))
+	return u.Options.Password, nil
+}
+
+// openFile opens a single file from a zip archive, decrypting it if it is
+// encrypted.
+func (u *Unzippy) openFile(zipFile *zip.File) (io.ReadCloser, error) {
+	method := EntryEncryption(zipFile)
+	if method == NoEncryption {
+		reader, err := zipFile.Open()
+		if errors.Is(err, zip.ErrAlgorithm) {
+			return nil, &UnsupportedMethodError{
+				EntryError: EntryError{Op: "open", Archive: u.Path, Entry: zipFile.Name, Err: err},
+				Method:     zipFile.Method,
+			}
+		}
+
+		return reader, err
+	}
+
+	// Strong encryption is not supported
+	if zipFile.Flags&0x40 != 0 {
+		return nil, &EncryptedEntryError{
+			EntryError: EntryError{Op: "open", Archive: u.Path, Entry: zipFile.Name, Err: ErrUnsupportedEncryption},
+			Method:     method,
+		}
+	}
+
+	password, err := u.password(zipFile.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if password == "" {
+		return nil, &EncryptedEntryError{
+			EntryError: EntryError{Op: "open", Archive: u.Path, Entry: zipFile.Name, Err: ErrPasswordRequired},
+			Method:     method,
+		}
+	}
+
+	if method == ZipCrypto {
+		return openZipCrypto(u.Path, zipFile, password)
+	}
+
+	return openAES(u.Path, zipFile, password)
 }
 
 // unzipFile extracts a single file from a zip archive.
 func (u *Unzippy) unzipFile(zipFile *zip.File, dest string) error {
-	zippedFile, err := zipFile.Open()
+	zippedFile, err := u.openFile(zipFile)
 	if err != nil {
 		return err
 	}
@@ -126,29 +310,81 @@ func (u *Unzippy) unzipFile(zipFile *zip.File, dest string) error {
 		return err
 	}
 
-	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, zipFile.Mode())
+	destFile, err := u.createFile(dest, zipFile.Mode())
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
 
-	return u.copyAndValidate(zippedFile, zipFile, dest, destFile)
+	// Data failing its checks, like an unauthenticated entry, is not kept
+	if err := u.copyAndValidate(zippedFile, zipFile, dest, destFile); err != nil {
+		destFile.Close()
+		os.Remove(dest)
+		return err
+	}
+
+	return destFile.Close()
+}
+
+// createFile creates a file to extract an entry to, failing if it exists and
+// FailExisting is set.
+func (u *Unzippy) createFile(path string, mode fs.FileMode) (*os.File, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if u.Options.FailExisting {
+		flags |= os.O_EXCL
+	}
+
+	// Special bits are only set by restoreAttrs, when asked to
+	return os.OpenFile(path, flags, mode&os.ModePerm)
 }
 
 // unzipFiles extracts the specified files from the zip archive to a destination
 // directory.
-func (u *Unzippy) unzipFiles(dest string, files ...*zip.File) error {
+//
+// extras are the local header extra fields of the files, if they were read.
+func (u *Unzippy) unzipFiles(dest string, extras map[*zip.File][]byte, files ...*zip.File) error {
+	// Symbolic links created so far and their targets, which later entries
+	// must not write through
+	links := make(map[string]string)
+
+	// Directories have their attributes restored once their contents are
+	// written, since writing the contents changes their modification time
+	dirs := make(map[string]*zip.File)
+
 	for _, file := range files {
 		if u.Options.Junk {
 			file.Name = filepath.Base(file.Name)
 		}
 
-		filePath := filepath.Join(dest, file.Name)
+		name, sanitized := u.Options.extractName(file.Name)
+		if sanitized {
+			u.Renamed[file.Name] = name
+		}
+
+		filePath, err := safeJoin(u.Path, dest, name)
+		if err != nil {
+			return err
+		}
+
+		if err := checkLinkFree(u.Path, file.Name, dest, filePath, links); err != nil {
+			return err
+		}
 
 		if file.FileInfo().IsDir() {
 			if err := os.MkdirAll(filePath, os.ModePerm); err != nil {
 				return err
 			}
+
+			dirs[filePath] = file
+			continue
+		} else if isSymlink(file) && u.Options.Symlinks == ExtractSymlinkSkip {
+			continue
+		} else if isSymlink(file) && u.Options.Symlinks == ExtractSymlinkCreate {
+			if err := u.createSymlink(file, dest, filePath, links); err != nil {
+				return err
+			}
+
+			// Setting the times would follow the link to its target
+			continue
 		} else {
 			if err := u.unzipFile(file, filePath); err != nil {
 				return err
@@ -156,10 +392,10 @@ func (u *Unzippy) unzipFiles(dest string, files ...*zip.File) error {
 		}
 
 		// Preserve the file modification date
-		if err := os.Chtimes(filePath, file.Modified, file.Modified); err != nil {
+		if err := u.restoreAttrs(file, filePath, extras[file]); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return u.restoreDirAttrs(dirs, extras)
 }
@@ -3,6 +3,7 @@ package zippy
 import (
 	"archive/zip"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
@@ -110,6 +111,42 @@ func Test_Unzippy_ExtractTo(t *testing.T) {
 		assert.Len(t, files, 10)
 	})
 
+	t.Run("existing files are kept with FailExisting", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		dest := filepath.Join(tempDir, "output")
+
+		_, err := testutils.CreateZipFile(zipFilePath, 1, 0)
+		assert.NoError(t, err)
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		files, err := u.ExtractTo(dest)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+
+		existing := filepath.Join(dest, files[0].Name)
+		assert.NoError(t, os.WriteFile(existing, []byte("local changes"), 0644))
+
+		u.Options.FailExisting = true
+		_, err = u.ExtractTo(dest)
+		assert.ErrorIs(t, err, fs.ErrExist)
+
+		contents, err := os.ReadFile(existing)
+		assert.NoError(t, err)
+		assert.Equal(t, "local changes", string(contents))
+
+		// Existing files are overwritten by default
+		u.Options.FailExisting = false
+		_, err = u.ExtractTo(dest)
+		assert.NoError(t, err)
+
+		contents, err = os.ReadFile(existing)
+		assert.NoError(t, err)
+		assert.NotEqual(t, "local changes", string(contents))
+	})
+
 	t.Run("empty zip exists", func(t *testing.T) {
 		tempDir := t.TempDir()
 		zipFilePath := filepath.Join(tempDir, testZipFileName)
@@ -463,7 +500,7 @@ func Test_Unzippy_unzipFiles(t *testing.T) {
 		assert.NotNil(t, zipReader)
 		defer zipReader.Close()
 
-		err = u.unzipFiles(tempDir, zipReader.File...)
+		err = u.unzipFiles(tempDir, nil, zipReader.File...)
 		assert.NoError(t, err)
 	})
 
@@ -483,7 +520,7 @@ func Test_Unzippy_unzipFiles(t *testing.T) {
 		assert.NotNil(t, zipReader)
 		defer zipReader.Close()
 
-		err = u.unzipFiles(tempDir, zipReader.File...)
+		err = u.unzipFiles(tempDir, nil, zipReader.File...)
 		assert.NoError(t, err)
 	})
 }
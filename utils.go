@@ -3,15 +3,35 @@ package zippy
 import (
 	"archive/zip"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
+const (
+	uint16max = (1 << 16) - 1
+	uint32max = (1 << 32) - 1
+)
+
+// nopWriteCloser adds a no-op Close method to an [io.Writer].
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
 // fileFound checks if a zip file matches any of the provided glob patterns.
-func fileFound(zipFile *zip.File, files ...string) (bool, error) {
+func fileFound(zipFile *zip.File, ignoreCase bool, files ...string) (bool, error) {
+	return nameMatches(zipFile.Name, ignoreCase, files...)
+}
+
+// nameMatches checks if an entry name matches any of the provided glob
+// patterns.
+func nameMatches(name string, ignoreCase bool, files ...string) (bool, error) {
 	for _, f := range files {
-		match, err := filepath.Match(f, zipFile.Name)
+		match, err := matchName(f, name, ignoreCase)
 		if err != nil {
 			return false, err
 		}
@@ -24,21 +44,31 @@ func fileFound(zipFile *zip.File, files ...string) (bool, error) {
 	return false, nil
 }
 
+// matchName reports whether an entry name matches a glob pattern. With
+// ignoreCase, both are case folded first, like zip -ic.
+func matchName(pattern, name string, ignoreCase bool) (bool, error) {
+	if ignoreCase {
+		pattern, name = FoldCase.fold(pattern), FoldCase.fold(name)
+	}
+
+	return filepath.Match(pattern, name)
+}
+
 // filterFiles filters the zip files based on the provided glob patterns. If no
 // patterns are provided, all files are returned.
-func filterFiles(zipFiles []*zip.File, files ...string) ([]*zip.File, error) {
+func filterFiles(zipFiles []*zip.File, ignoreCase bool, files ...string) ([]*zip.File, error) {
 	if zipFiles == nil {
 		return nil, nil
 	}
 
 	// If we have files to extract, filter the files to extract
-	if files == nil {
+	if len(files) == 0 {
 		return zipFiles, nil
 	}
 
 	extFiles := []*zip.File{}
 	for _, file := range zipFiles {
-		match, err := fileFound(file, files...)
+		match, err := fileFound(file, ignoreCase, files...)
 		if err != nil {
 			return nil, err
 		}
@@ -52,6 +82,136 @@ func filterFiles(zipFiles []*zip.File, files ...string) ([]*zip.File, error) {
 	return extFiles, nil
 }
 
+// unmatchedPatterns returns the glob patterns that match none of the names,
+// in the order given.
+func unmatchedPatterns(names []string, patterns []string, ignoreCase bool) ([]string, error) {
+	var unmatched []string
+
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+
+		found := false
+		for _, name := range names {
+			if match, _ := matchName(pattern, name, ignoreCase); match {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	return unmatched, nil
+}
+
+// checkUnmatched fails with an [UnmatchedError] if any of the glob patterns
+// matches none of the zip files of the archive at path archive.
+func checkUnmatched(archive string, zipFiles []*zip.File, patterns []string, ignoreCase bool) error {
+	unmatched, err := unmatchedPatterns(zipFileNames(zipFiles), patterns, ignoreCase)
+	if err != nil {
+		return err
+	}
+
+	if len(unmatched) > 0 {
+		return &UnmatchedError{Archive: archive, Patterns: unmatched}
+	}
+
+	return nil
+}
+
+// zipFileNames returns the names of the zip files.
+func zipFileNames(zipFiles []*zip.File) []string {
+	names := make([]string, len(zipFiles))
+	for i, file := range zipFiles {
+		names[i] = file.Name
+	}
+
+	return names
+}
+
+// excluded reports whether an entry name, or one of the directories it is in,
+// matches any of the glob patterns. Patterns without a slash match a base name
+// in any directory, like zip -x "*.o".
+func excluded(name string, patterns []string, ignoreCase bool) (bool, error) {
+	name = strings.TrimSuffix(name, "/")
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(pattern, "/")
+		baseOnly := !strings.Contains(pattern, "/")
+
+		for prefix := name; prefix != ""; {
+			subject := prefix
+			if baseOnly {
+				subject = path.Base(prefix)
+			}
+
+			match, err := matchName(pattern, subject, ignoreCase)
+			if err != nil {
+				return false, err
+			}
+
+			if match {
+				return true, nil
+			}
+
+			i := strings.LastIndex(prefix, "/")
+			if i < 0 {
+				break
+			}
+
+			prefix = prefix[:i]
+		}
+	}
+
+	return false, nil
+}
+
+// excludeFiles returns the zip files that do not match any of the glob
+// patterns, as checked by [excluded].
+func excludeFiles(zipFiles []*zip.File, patterns []string, ignoreCase bool) ([]*zip.File, error) {
+	kept := []*zip.File{}
+	for _, file := range zipFiles {
+		skip, err := excluded(file.Name, patterns, ignoreCase)
+		if err != nil {
+			return nil, err
+		}
+
+		if !skip {
+			kept = append(kept, file)
+		}
+	}
+
+	return kept, nil
+}
+
+// expandGlobs returns the paths matching each glob pattern. Patterns without
+// matches are kept as literal paths.
+func expandGlobs(files ...string) ([]string, error) {
+	var paths []string
+
+	for _, file := range files {
+		fileMatches, err := filepath.Glob(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to glob pattern '%s': %v", file, err)
+		}
+
+		// If no matches found, treat file as a literal path
+		if len(fileMatches) == 0 {
+			fileMatches = append(fileMatches, file)
+		}
+
+		for _, fileMatch := range fileMatches {
+			paths = append(paths, filepath.Clean(fileMatch))
+		}
+	}
+
+	return paths, nil
+}
+
 // Removes the drive letter and colon from a Windows path.
 func removeDriveLetter(path string) string {
 	return strings.TrimPrefix(path, filepath.VolumeName(path))
@@ -74,19 +234,20 @@ func toZipPaths(paths ...string) []string {
 
 // Validates the number of bytes written during a copy operation with
 // expected number of bytes.
-func validateCopy(path string, written int64, expected int64) error {
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		return err
-	}
-
-	_, err = os.Stat(path)
-	if err != nil {
+//
+// archive and entry are the archive and entry the file at path was copied to
+// or from, reported in a [SizeError].
+func validateCopy(archive, entry, path string, written int64, expected int64) error {
+	if _, err := os.Stat(path); err != nil {
 		return err
 	}
 
 	if written != expected {
-		return fmt.Errorf("failed to copy '%s': expected %d bytes, got %d bytes", absPath, expected, written)
+		return &SizeError{
+			EntryError: EntryError{Op: "copy", Archive: archive, Entry: entry},
+			Expected:   expected,
+			Actual:     written,
+		}
 	}
 
 	return nil
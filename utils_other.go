@@ -0,0 +1,32 @@
+//go:build !linux && !darwin && !windows
+// +build !linux,!darwin,!windows
+
+package zippy
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// fileAttrs returns no access time or ownership, as they are only read on
+// Linux, macOS and Windows. It still fails if the file cannot be stat'd,
+// following a symbolic link only if follow is set.
+func fileAttrs(path string, follow bool) (unixAttrs, error) {
+	statFunc := os.Lstat
+	if follow {
+		statFunc = os.Stat
+	}
+
+	if _, err := statFunc(path); err != nil {
+		return unixAttrs{}, err
+	}
+
+	return unixAttrs{}, nil
+}
+
+// mknod fails, as device files and named pipes are only created on Linux and
+// macOS.
+func mknod(path string, mode fs.FileMode, major, minor int64) error {
+	return &fs.PathError{Op: "mknod", Path: path, Err: errors.ErrUnsupported}
+}
@@ -16,19 +16,19 @@ func Test_fileFound(t *testing.T) {
 	zipFile.Name = "test.txt"
 
 	t.Run("matching file found", func(t *testing.T) {
-		match, err := fileFound(zipFile, "test.txt")
+		match, err := fileFound(zipFile, false, "test.txt")
 		assert.NoError(t, err)
 		assert.True(t, match)
 	})
 
 	t.Run("no matching file found", func(t *testing.T) {
-		match, err := fileFound(zipFile, "other.txt")
+		match, err := fileFound(zipFile, false, "other.txt")
 		assert.NoError(t, err)
 		assert.False(t, match)
 	})
 
 	t.Run("bad glob pattern", func(t *testing.T) {
-		_, err := fileFound(zipFile, "[")
+		_, err := fileFound(zipFile, false, "[")
 		assert.Error(t, err)
 	})
 }
@@ -42,20 +42,42 @@ func Test_filterFiles(t *testing.T) {
 	zipFiles := []*zip.File{zipFile1, zipFile2}
 
 	t.Run("filter with matching files", func(t *testing.T) {
-		filteredFiles, err := filterFiles(zipFiles, "test1.txt")
+		filteredFiles, err := filterFiles(zipFiles, false, "test1.txt")
 		assert.NoError(t, err)
 		assert.Len(t, filteredFiles, 1)
 		assert.Equal(t, "test1.txt", filteredFiles[0].Name)
 	})
 
 	t.Run("zipFiles is nil", func(t *testing.T) {
-		filteredFiles, err := filterFiles(nil, "test1.txt")
+		filteredFiles, err := filterFiles(nil, false, "test1.txt")
 		assert.NoError(t, err)
 		assert.Nil(t, filteredFiles)
 	})
 
 	t.Run("bad glob pattern", func(t *testing.T) {
-		_, err := filterFiles(zipFiles, "[")
+		_, err := filterFiles(zipFiles, false, "[")
+		assert.Error(t, err)
+	})
+}
+
+// Tests for [unmatchedPatterns] function.
+func Test_unmatchedPatterns(t *testing.T) {
+	names := []string{"dir/", "dir/a.txt", "b.txt"}
+
+	t.Run("all matched", func(t *testing.T) {
+		unmatched, err := unmatchedPatterns(names, []string{"dir/*", "b.txt"}, false)
+		assert.NoError(t, err)
+		assert.Nil(t, unmatched)
+	})
+
+	t.Run("unmatched in order", func(t *testing.T) {
+		unmatched, err := unmatchedPatterns(names, []string{"z*", "b.txt", "a.txt"}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"z*", "a.txt"}, unmatched)
+	})
+
+	t.Run("bad glob pattern", func(t *testing.T) {
+		_, err := unmatchedPatterns(nil, []string{"["}, false)
 		assert.Error(t, err)
 	})
 }
@@ -105,20 +127,20 @@ func Test_validateCopy(t *testing.T) {
 	t.Run("valid copy", func(t *testing.T) {
 		validPath := makeValidFile(t)
 
-		err := validateCopy(validPath, 100, 100)
+		err := validateCopy(testZipFileName, "valid", validPath, 100, 100)
 		assert.NoError(t, err)
 	})
 
 	t.Run("invalid path", func(t *testing.T) {
 		invalidPath := "/invalid/path\0001"
-		err := validateCopy(invalidPath, 0, 0)
+		err := validateCopy(testZipFileName, "invalid", invalidPath, 0, 0)
 		assert.Error(t, err)
 	})
 
 	t.Run("mismatched bytes", func(t *testing.T) {
 		validPath := makeValidFile(t)
 
-		err := validateCopy(validPath, 100, 200)
+		err := validateCopy(testZipFileName, "valid", validPath, 100, 200)
 		assert.Error(t, err)
 	})
 
@@ -148,7 +170,39 @@ func Test_validateCopy(t *testing.T) {
 			assert.Error(t, err)
 		}
 
-		err = validateCopy("relative/path", 100, 100)
+		err = validateCopy(testZipFileName, "relative/path", "relative/path", 100, 100)
+		assert.Error(t, err)
+	})
+}
+
+// Tests for [excluded] function.
+func Test_excluded(t *testing.T) {
+	t.Run("name matches", func(t *testing.T) {
+		skip, err := excluded("dir/file.o", []string{"*.txt", "dir/*.o"}, false)
+		assert.NoError(t, err)
+		assert.True(t, skip)
+	})
+
+	t.Run("parent directory matches", func(t *testing.T) {
+		skip, err := excluded("build/sub/file.txt", []string{"build/"}, false)
+		assert.NoError(t, err)
+		assert.True(t, skip)
+	})
+
+	t.Run("base name pattern matches in any directory", func(t *testing.T) {
+		skip, err := excluded("src/obj/main.o", []string{"*.o"}, false)
+		assert.NoError(t, err)
+		assert.True(t, skip)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		skip, err := excluded("src/file.txt", []string{"build", "*.o"}, false)
+		assert.NoError(t, err)
+		assert.False(t, skip)
+	})
+
+	t.Run("bad glob pattern", func(t *testing.T) {
+		_, err := excluded("file.txt", []string{"["}, false)
 		assert.Error(t, err)
 	})
 }
@@ -4,7 +4,9 @@
 package zippy
 
 import (
+	"io/fs"
 	"os"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
@@ -25,3 +27,39 @@ func isCrossDevice(path1, path2 string) (bool, error) {
 	stat2 := info2.Sys().(*unix.Stat_t)
 	return stat1.Dev != stat2.Dev, nil
 }
+
+// fileAttrs returns the access time and ownership of a file, following a
+// symbolic link only if follow is set.
+func fileAttrs(path string, follow bool) (unixAttrs, error) {
+	var stat unix.Stat_t
+
+	statFunc := unix.Lstat
+	if follow {
+		statFunc = unix.Stat
+	}
+
+	if err := statFunc(path, &stat); err != nil {
+		return unixAttrs{}, err
+	}
+
+	return unixAttrs{
+		Atime:    time.Unix(stat.Atim.Unix()),
+		UID:      int(stat.Uid),
+		GID:      int(stat.Gid),
+		HasOwner: true,
+	}, nil
+}
+
+// mknod creates a device file or named pipe at path.
+func mknod(path string, mode fs.FileMode, major, minor int64) error {
+	perm := uint32(mode & fs.ModePerm)
+
+	switch {
+	case mode&fs.ModeNamedPipe != 0:
+		return unix.Mkfifo(path, perm)
+	case mode&fs.ModeCharDevice != 0:
+		return unix.Mknod(path, unix.S_IFCHR|perm, int(unix.Mkdev(uint32(major), uint32(minor))))
+	default:
+		return unix.Mknod(path, unix.S_IFBLK|perm, int(unix.Mkdev(uint32(major), uint32(minor))))
+	}
+}
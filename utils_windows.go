@@ -4,8 +4,12 @@
 package zippy
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"syscall"
+	"time"
 )
 
 // getVolumeName retrieves the volume name for the given path.
@@ -31,3 +35,30 @@ func isCrossDevice(path1, path2 string) (bool, error) {
 
 	return volume1 != volume2, nil
 }
+
+// fileAttrs returns the access time of a file. Windows files have no Unix
+// ownership.
+func fileAttrs(path string, follow bool) (unixAttrs, error) {
+	stat := os.Lstat
+	if follow {
+		stat = os.Stat
+	}
+
+	info, err := stat(path)
+	if err != nil {
+		return unixAttrs{}, err
+	}
+
+	var attrs unixAttrs
+	if data, ok := info.Sys().(*syscall.Win32FileAttributeData); ok {
+		attrs.Atime = time.Unix(0, data.LastAccessTime.Nanoseconds())
+	}
+
+	return attrs, nil
+}
+
+// mknod fails, as Windows has no device files or named pipes in the file
+// system.
+func mknod(path string, mode fs.FileMode, major, minor int64) error {
+	return &fs.PathError{Op: "mknod", Path: path, Err: errors.ErrUnsupported}
+}
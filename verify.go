@@ -0,0 +1,243 @@
+package zippy
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+)
+
+// EntryReport is the result of verifying a single entry of a zip archive.
+type EntryReport struct {
+	Name     string  // Name of the entry.
+	Problems []error // Problems found with the entry. Empty if the entry is intact.
+}
+
+// OK reports whether no problems were found with the entry.
+func (e *EntryReport) OK() bool {
+	return len(e.Problems) == 0
+}
+
+// VerifyReport is the result of verifying a zip archive.
+type VerifyReport struct {
+	Entries       []*EntryReport // Entries holds a report for each verified entry.
+	TrailingBytes int64          // TrailingBytes is the number of bytes found after the end of the archive.
+}
+
+// OK reports whether no problems were found with the archive.
+func (r *VerifyReport) OK() bool {
+	if r.TrailingBytes > 0 {
+		return false
+	}
+
+	for _, entry := range r.Entries {
+		if !entry.OK() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Failed returns the reports of the entries that have problems.
+func (r *VerifyReport) Failed() []*EntryReport {
+	var failed []*EntryReport
+	for _, entry := range r.Entries {
+		if !entry.OK() {
+			failed = append(failed, entry)
+		}
+	}
+
+	return failed
+}
+
+// entrySpan is the byte range occupied by an entry in an archive.
+type entrySpan struct {
+	index int
+	start int64
+	end   int64
+}
+
+// Verifies the integrity of the zip archive without writing anything to disk,
+// like zip -T. Every entry is decompressed and its checksum and size are
+// checked, and its local header is compared with the central directory.
+// Overlapping entries, names appearing more than once in the central directory
+// and data after the end of the archive are reported.
+//
+// files are the entries to verify. Glob patterns are supported. If no files are
+// specified, all entries are verified.
+//
+// All checks are run even when one fails. If any problem is found the report
+// is returned along with [ErrVerificationFailed].
+func (u *Unzippy) Verify(files ...string) (*VerifyReport, error) {
+	zipReader, err := openZip(u.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer zipReader.Close()
+
+	eocd, err := readEOCD(zipReader.r, zipReader.size)
+	if err != nil {
+		return nil, err
+	}
+
+	headers, err := readCentralDirectory(zipReader.r, eocd)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Options.FailUnmatched {
+		if err := checkUnmatched(u.Path, zipReader.File, files, u.Options.IgnoreCase); err != nil {
+			return nil, err
+		}
+	}
+
+	verifyFiles, err := filterFiles(zipReader.File, u.Options.IgnoreCase, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := u.verifyLayout(zipReader.r, eocd, headers)
+
+	indexes := make(map[*zip.File]int, len(zipReader.File))
+	for i, file := range zipReader.File {
+		indexes[file] = i
+	}
+
+	report := &VerifyReport{TrailingBytes: zipReader.size - eocd.end}
+	duplicates := duplicateNames(zipReader.File)
+
+	for _, file := range verifyFiles {
+		entry := &EntryReport{Name: file.Name}
+
+		if count, ok := duplicates[file.Name]; ok {
+			entry.Problems = append(entry.Problems, &CollisionError{Archive: u.Path, Names: [][]string{slices.Repeat([]string{file.Name}, count)}})
+		}
+
+		if i, ok := indexes[file]; ok && i < len(problems) {
+			entry.Problems = append(entry.Problems, problems[i]...)
+		}
+
+		if err := u.verifyContents(file); err != nil {
+			entry.Problems = append(entry.Problems, err)
+		}
+
+		report.Entries = append(report.Entries, entry)
+	}
+
+	if !report.OK() {
+		return report, ErrVerificationFailed
+	}
+
+	return report, nil
+}
+
+// verifyLayout compares each local header with its central directory header
+// and checks that no two entries overlap. It returns the problems found for
+// each entry, indexed by central directory position.
+func (u *Unzippy) verifyLayout(r io.ReaderAt, eocd *endOfCentralDirectory, headers []*rawHeader) [][]error {
+	problems := make([][]error, len(headers))
+	spans := make([]entrySpan, 0, len(headers))
+
+	for i, central := range headers {
+		local, err := readLocalHeader(r, central.Offset)
+		if err != nil {
+			problems[i] = append(problems[i], fmt.Errorf("failed to read local header of '%s': %w", central.Name, err))
+			continue
+		}
+
+		problems[i] = append(problems[i], compareHeaders(local, central)...)
+
+		end := local.DataOffset + int64(central.CompressedSize64)
+		end += dataDescriptorSize(r, central, end)
+		spans = append(spans, entrySpan{index: i, start: central.Offset, end: end})
+	}
+
+	sort.Slice(spans, func(a, b int) bool {
+		return spans[a].start < spans[b].start
+	})
+
+	cdStart := eocd.baseOffset + int64(eocd.cdOffset)
+	for i, span := range spans {
+		if i+1 < len(spans) && spans[i+1].start < span.end {
+			next := spans[i+1]
+			problems[span.index] = append(problems[span.index], fmt.Errorf("'%s' and '%s': %w", headers[span.index].Name, headers[next.index].Name, ErrOverlappingEntries))
+			problems[next.index] = append(problems[next.index], fmt.Errorf("'%s' and '%s': %w", headers[next.index].Name, headers[span.index].Name, ErrOverlappingEntries))
+		}
+
+		if span.end > cdStart {
+			problems[span.index] = append(problems[span.index], fmt.Errorf("'%s' and central directory: %w", headers[span.index].Name, ErrOverlappingEntries))
+		}
+	}
+
+	return problems
+}
+
+// compareHeaders returns the differences between a local header and its
+// central directory header.
+func compareHeaders(local, central *rawHeader) []error {
+	var problems []error
+
+	mismatch := func(field string, localValue, centralValue any) {
+		problems = append(problems, fmt.Errorf("'%s': %s is %v in local header and %v in central directory: %w", central.Name, field, localValue, centralValue, ErrHeaderMismatch))
+	}
+
+	if local.Name != central.Name {
+		mismatch("name", local.Name, central.Name)
+	}
+
+	if local.Method != central.Method {
+		mismatch("method", local.Method, central.Method)
+	}
+
+	if local.Flags&0x1 != central.Flags&0x1 {
+		mismatch("encryption flag", local.Flags&0x1, central.Flags&0x1)
+	}
+
+	// With a data descriptor the local header does not hold the CRC and sizes
+	if local.Flags&0x8 == 0 {
+		if local.CRC32 != central.CRC32 {
+			mismatch("CRC-32", fmt.Sprintf("%08x", local.CRC32), fmt.Sprintf("%08x", central.CRC32))
+		}
+
+		if local.CompressedSize64 != central.CompressedSize64 {
+			mismatch("compressed size", local.CompressedSize64, central.CompressedSize64)
+		}
+
+		if local.UncompressedSize64 != central.UncompressedSize64 {
+			mismatch("uncompressed size", local.UncompressedSize64, central.UncompressedSize64)
+		}
+	}
+
+	return problems
+}
+
+// verifyContents decompresses an entry, discarding the output, and checks its
+// checksum and size.
+func (u *Unzippy) verifyContents(file *zip.File) error {
+	if file.FileInfo().IsDir() {
+		return nil
+	}
+
+	zippedFile, err := u.openFile(file)
+	if err != nil {
+		return err
+	}
+	defer zippedFile.Close()
+
+	written, err := copyWithChecksum(io.Discard, zippedFile, u.Path, file)
+	if err != nil {
+		return err
+	}
+
+	if written != int64(file.UncompressedSize64) {
+		return &SizeError{
+			EntryError: EntryError{Op: "copy", Archive: u.Path, Entry: file.Name},
+			Expected:   int64(file.UncompressedSize64),
+			Actual:     written,
+		}
+	}
+
+	return nil
+}
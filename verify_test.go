@@ -0,0 +1,163 @@
+package zippy
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/engmtcdrm/go-zippy/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// centralHeaderOffsets returns the offsets of the central directory headers of
+// an archive.
+func centralHeaderOffsets(t *testing.T, data []byte) []int {
+	t.Helper()
+
+	var offsets []int
+	for i := 0; i+4 <= len(data); i++ {
+		if binary.LittleEndian.Uint32(data[i:]) == centralHeaderSignature {
+			offsets = append(offsets, i)
+		}
+	}
+
+	return offsets
+}
+
+// Tests for [Unzippy.Verify] function.
+func Test_Unzippy_Verify(t *testing.T) {
+	initZip := func(t *testing.T, files, subdirs int) (string, []byte) {
+		zipFilePath := filepath.Join(t.TempDir(), testZipFileName)
+
+		_, err := testutils.CreateZipFile(zipFilePath, files, subdirs)
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(zipFilePath)
+		assert.NoError(t, err)
+
+		return zipFilePath, data
+	}
+
+	t.Run("intact archive", func(t *testing.T) {
+		zipFilePath, _ := initZip(t, 3, 2)
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		assert.NoError(t, err)
+		assert.True(t, report.OK())
+		assert.Len(t, report.Entries, 11)
+		assert.Empty(t, report.Failed())
+	})
+
+	t.Run("filtered entries", func(t *testing.T) {
+		zipFilePath, _ := initZip(t, 3, 0)
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify("test0-*")
+		assert.NoError(t, err)
+		assert.Len(t, report.Entries, 1)
+	})
+
+	t.Run("corrupted data does not stop verification", func(t *testing.T) {
+		zipFilePath, data := initZip(t, 3, 0)
+
+		zipReader, err := zip.OpenReader(zipFilePath)
+		assert.NoError(t, err)
+		offset, err := zipReader.File[0].DataOffset()
+		assert.NoError(t, err)
+		zipReader.Close()
+
+		data[offset] ^= 0xff
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		assert.ErrorIs(t, err, ErrVerificationFailed)
+		assert.Len(t, report.Entries, 3)
+		assert.Len(t, report.Failed(), 1)
+		assert.False(t, report.Entries[0].OK())
+	})
+
+	t.Run("trailing garbage", func(t *testing.T) {
+		zipFilePath, data := initZip(t, 1, 0)
+
+		data = append(data, []byte("garbage")...)
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		assert.ErrorIs(t, err, ErrVerificationFailed)
+		assert.Equal(t, int64(7), report.TrailingBytes)
+		assert.Empty(t, report.Failed())
+	})
+
+	t.Run("local header mismatch", func(t *testing.T) {
+		zipFilePath, data := initZip(t, 1, 0)
+
+		// Change the method in the first local header
+		binary.LittleEndian.PutUint16(data[8:], 12)
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		assert.ErrorIs(t, err, ErrVerificationFailed)
+		assert.ErrorIs(t, report.Entries[0].Problems[0], ErrHeaderMismatch)
+	})
+
+	t.Run("overlapping entries", func(t *testing.T) {
+		zipFilePath, data := initZip(t, 2, 0)
+
+		// Point the second central directory header at the first local header
+		offsets := centralHeaderOffsets(t, data)
+		assert.Len(t, offsets, 2)
+		binary.LittleEndian.PutUint32(data[offsets[1]+42:], 0)
+		assert.NoError(t, os.WriteFile(zipFilePath, data, 0644))
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		assert.ErrorIs(t, err, ErrVerificationFailed)
+		assert.Len(t, report.Failed(), 2)
+
+		found := false
+		for _, problem := range report.Entries[1].Problems {
+			if errors.Is(problem, ErrOverlappingEntries) {
+				found = true
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("zip does not exist", func(t *testing.T) {
+		u, err := NewUnzippy(filepath.Join(t.TempDir(), testZipFileName), nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		assert.Error(t, err)
+		assert.Nil(t, report)
+	})
+
+	t.Run("bad glob pattern", func(t *testing.T) {
+		zipFilePath, _ := initZip(t, 1, 0)
+
+		u, err := NewUnzippy(zipFilePath, nil)
+		assert.NoError(t, err)
+
+		_, err = u.Verify("[")
+		assert.Error(t, err)
+	})
+}
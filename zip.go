@@ -35,12 +35,24 @@ type ZippyInterface interface {
 }
 
 type Zippy struct {
-	Path          string // The path, including the file name, to the zip archive.
-	Junk          bool   // Specifies whether to junk the path when archiving.
-	tempFile      string // Temp file name when working with zip archives.
+	Path          string         // The path, including the file name, to the zip archive.
+	Junk          bool           // Specifies whether to junk the path when archiving.
+	Encryption    *Encryption    // Encryption to apply to entries written to the archive.
+	Symlinks      SymlinkPolicy  // Specifies how symbolic links are archived.
+	Exclude       []string       // Glob patterns of entry names to leave out when archiving. A matching directory leaves out its contents.
+	ForceZip64    bool           // Write ZIP64 records for every entry in the central directory, even when the archive does not need them. Local headers only hold them for entries that need them.
+	Stub          string         // Path to an executable prepended to the archive to make it self-extracting. Data prepended to an existing archive is not kept without one.
+	FailUnmatched bool           // Fail Delete and Copy with an [UnmatchedError] when a pattern matches no entry, before anything is written.
+	DirEntries    DirEntryPolicy // How directory entries are written by Delete and Copy.
+	IgnoreCase    bool           // Match the patterns of Delete and Copy, Exclude and the encryption patterns with entry names case-insensitively, like zip -ic.
+	DryRun        bool           // Set Plan to what Add, Update, Freshen, Sync, Delete and Copy would do instead of writing anything.
+	Plan          *Plan          // Plan of the last operation run with DryRun.
+	tempFile      string         // Temp file name when working with zip archives.
 	existingFiles map[string]*zip.File
 	zWriter       *zip.Writer
 	zReadCloser   *zip.ReadCloser
+	sync          *syncState // State of the running Sync, if any.
+	plan          *Plan      // Plan being recorded by a dry run, if any.
 }
 
 func NewZippy(path string) *Zippy {
@@ -61,6 +73,27 @@ func (z *Zippy) createTempZipWithFiles(dest string, files ...string) (tempZipPat
 	}
 	defer z.zReadCloser.Close()
 
+	files = toZipPaths(files...)
+
+	unmatched, err := unmatchedPatterns(zipFileNames(z.zReadCloser.File), files, z.IgnoreCase)
+	if err != nil {
+		return "", err
+	}
+
+	if z.FailUnmatched && len(unmatched) > 0 {
+		return "", &UnmatchedError{Archive: z.Path, Patterns: unmatched}
+	}
+
+	if z.plan != nil {
+		z.plan.Unmatched = unmatched
+
+		if len(files) > 0 {
+			return "", z.copyZipFilesKeep(z.zReadCloser.File, files)
+		}
+
+		return "", z.copyZipFilesAll(z.zReadCloser.File)
+	}
+
 	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
 		return "", err
 	}
@@ -72,29 +105,41 @@ func (z *Zippy) createTempZipWithFiles(dest string, files ...string) (tempZipPat
 	}
 	defer tempZipFile.Close()
 
-	// Copy entire zip file if no files are provided to copy
-	if files == nil {
+	// Copy entire zip file if no files are provided to copy and no entries
+	// need to be encrypted
+	if len(files) == 0 && z.Encryption == nil && !z.ForceZip64 && z.Stub == "" && z.DirEntries == DirEntriesKeep {
 		return z.copyEntireZip(tempZipFile.Name())
 	}
 
 	z.zWriter = zip.NewWriter(tempZipFile)
 	defer z.zWriter.Close()
 
-	files = toZipPaths(files...)
+	if err := z.zWriter.SetComment(z.zReadCloser.Comment); err != nil {
+		return "", err
+	}
+
+	if len(files) == 0 {
+		if err := z.copyZipFilesAll(z.zReadCloser.File); err != nil {
+			return "", err
+		}
+
+		return tempZipFile.Name(), z.closeWriter(tempZipFile)
+	}
 
 	// Copy existing files to the new zip archive, excluding the ones to delete
 	if err := z.copyZipFilesKeep(z.zReadCloser.File, files); err != nil {
 		return "", err
 	}
 
-	return tempZipFile.Name(), err
+	return tempZipFile.Name(), z.closeWriter(tempZipFile)
 }
 
 // Copy files from current zip to a temporary zip file removing any provided files listed
 //
 // files are the files or directories to delete. Glob patterns are supported.
 //
-// returns the path to the temporary zip file as well as any errors
+// returns the path to the temporary zip file as well as any errors. The path
+// is empty when no file matches, as there is nothing to delete.
 func (z *Zippy) createTempZipWithoutFiles(files ...string) (tempZipPath string, err error) {
 	z.zReadCloser, err = zip.OpenReader(z.Path)
 	if err != nil {
@@ -102,6 +147,29 @@ func (z *Zippy) createTempZipWithoutFiles(files ...string) (tempZipPath string,
 	}
 	defer z.zReadCloser.Close()
 
+	files = toZipPaths(files...)
+
+	unmatched, err := unmatchedPatterns(zipFileNames(z.zReadCloser.File), files, z.IgnoreCase)
+	if err != nil {
+		return "", err
+	}
+
+	if z.FailUnmatched && len(unmatched) > 0 {
+		return "", &UnmatchedError{Archive: z.Path, Patterns: unmatched}
+	}
+
+	if z.plan != nil {
+		z.plan.Unmatched = unmatched
+	}
+
+	if len(unmatched) == len(files) {
+		return "", nil
+	}
+
+	if z.plan != nil {
+		return "", z.copyZipFilesRemove(z.zReadCloser.File, files)
+	}
+
 	// Create a temporary zip file in the same directory as Zippy.Path
 	tempZipFile, err := os.CreateTemp(filepath.Dir(z.Path), z.tempFile)
 	if err != nil {
@@ -112,14 +180,134 @@ func (z *Zippy) createTempZipWithoutFiles(files ...string) (tempZipPath string,
 	z.zWriter = zip.NewWriter(tempZipFile)
 	defer z.zWriter.Close()
 
-	files = toZipPaths(files...)
+	if err := z.zWriter.SetComment(z.zReadCloser.Comment); err != nil {
+		return "", err
+	}
 
 	// Copy existing files to the new zip archive, excluding the ones to delete
 	if err := z.copyZipFilesRemove(z.zReadCloser.File, files); err != nil {
 		return "", err
 	}
 
-	return tempZipFile.Name(), err
+	return tempZipFile.Name(), z.closeWriter(tempZipFile)
+}
+
+// closeWriter closes the zip writer and finishes the archive it wrote to file,
+// which may be shorter than an archive it replaced in place.
+func (z *Zippy) closeWriter(file *os.File) error {
+	if err := z.zWriter.Close(); err != nil {
+		return err
+	}
+
+	end, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if err := file.Truncate(end); err != nil {
+		return err
+	}
+
+	return z.finish(file)
+}
+
+// finish applies the options that rewrite a complete zip archive in file: the
+// stub is prepended and ZIP64 records are forced.
+func (z *Zippy) finish(file *os.File) error {
+	if z.Stub != "" {
+		return prependStub(file, z.Stub, z.ForceZip64)
+	}
+
+	if z.ForceZip64 {
+		return forceZip64(file)
+	}
+
+	return nil
+}
+
+// writeArchive writes a new zip archive at dest through a temporary file in
+// the same directory, so dest is only replaced once the archive is complete.
+//
+// pattern is the temporary file name pattern. sources are closed before dest
+// is replaced, as open files cannot be replaced on Windows. finish, if not nil,
+// is called with the complete archive, like [Zippy.finish].
+func writeArchive(dest, pattern string, sources []io.Closer, finish func(file *os.File) error, fn func(zWriter *zip.Writer) error) error {
+	return writeTemp(dest, pattern, sources, func(file *os.File) error {
+		zWriter := zip.NewWriter(file)
+
+		if err := fn(zWriter); err != nil {
+			return err
+		}
+
+		if err := zWriter.Close(); err != nil {
+			return err
+		}
+
+		if finish != nil {
+			return finish(file)
+		}
+
+		return nil
+	})
+}
+
+// writeTemp writes a new file at dest through a temporary file in the same
+// directory, so dest is only replaced once fn has written all of it.
+//
+// pattern is the temporary file name pattern. sources are closed before dest
+// is replaced, as open files cannot be replaced on Windows.
+func writeTemp(dest, pattern string, sources []io.Closer, fn func(file *os.File) error) (err error) {
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(dest), pattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tempFile.Name())
+		}
+	}()
+	defer tempFile.Close()
+
+	if err := fn(tempFile); err != nil {
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		source.Close()
+	}
+
+	if err := os.Rename(tempFile.Name(), dest); err != nil {
+		return fmt.Errorf("failed to rename temporary file: %w", err)
+	}
+
+	return nil
+}
+
+// rewrite writes a new copy of the zip archive through a temporary file, then
+// replaces the archive with it. fn writes the entries to keep to the new
+// archive, which starts with the comment of the existing one.
+func (z *Zippy) rewrite(fn func(zReader *zip.ReadCloser, zWriter *zip.Writer) error) error {
+	zReader, err := zip.OpenReader(z.Path)
+	if err != nil {
+		return err
+	}
+	defer zReader.Close()
+
+	return writeArchive(z.Path, z.tempFile, []io.Closer{zReader}, z.finish, func(zWriter *zip.Writer) error {
+		if err := zWriter.SetComment(zReader.Comment); err != nil {
+			return err
+		}
+
+		return fn(zReader, zWriter)
+	})
 }
 
 // copyEntireZip creates a copy of the entire zip file
@@ -165,106 +353,44 @@ func (z *Zippy) copyEntireZip(tempZipPath string) (string, error) {
 //
 // patterns are the patterns to match files to remove.
 func (z *Zippy) copyZipFilesRemove(files []*zip.File, patterns []string) error {
-	// First identify which files should be removed
-	filesToRemove := make(map[string]bool)
-	for _, pattern := range patterns {
-		for _, file := range files {
-			match, err := filepath.Match(pattern, file.Name)
-			if err != nil {
-				return err
-			}
-
-			if match {
-				filesToRemove[file.Name] = true
-			}
+	// First identify which files should be kept
+	filesToKeep := make(map[string]bool)
+	for _, file := range files {
+		match, err := nameMatches(file.Name, z.IgnoreCase, patterns...)
+		if err != nil {
+			return err
 		}
-	}
 
-	// Create a map to track directories and their contents
-	dirContents := make(map[string][]string)
-
-	// Group files by their parent directories
-	for _, file := range files {
-		if !filesToRemove[file.Name] {
-			// Use strings.Split/Join to get parent directory with forward slashes
-			parts := strings.Split(file.Name, "/")
-			if len(parts) > 1 {
-				dir := strings.Join(parts[:len(parts)-1], "/")
-				dirContents[dir] = append(dirContents[dir], file.Name)
-			}
+		if !match {
+			filesToKeep[file.Name] = true
 		}
 	}
 
-	// Identify empty directories that should be removed
-	emptyDirs := make(map[string]bool)
-
-	// Mark directories as empty if all their contents are marked for removal
-	// This is a recursive process that works bottom-up
-	changed := true
-	for changed {
-		changed = false
-		for dir, contents := range dirContents {
-			// Skip if already marked as empty
-			if emptyDirs[dir] {
-				continue
-			}
-
-			isEmpty := true
-			for _, content := range contents {
-				// If content is not marked for removal and is not an empty dir, dir is not empty
-				if !filesToRemove[content] && !emptyDirs[content] {
-					isEmpty = false
-					break
-				}
-			}
+	// Copy files that should not be removed, raw as they were
+	return z.writeKept(files, filesToKeep, true, z.keepFile)
+}
 
-			if isEmpty {
-				emptyDirs[dir] = true
-				changed = true
-
-				// Mark this directory for removal in its parent directory's context
-				// Use strings.Split/Join to get parent directory with forward slashes
-				parts := strings.Split(dir, "/")
-				if len(parts) > 1 {
-					parentDir := strings.Join(parts[:len(parts)-1], "/")
-					// Add this dir to its parent's contents list if not already there
-					found := false
-					for _, content := range dirContents[parentDir] {
-						if content == dir || content == dir+"/" {
-							found = true
-							break
-						}
-					}
-
-					if !found {
-						dirContents[parentDir] = append(dirContents[parentDir], dir)
-					}
-				}
-			}
-		}
+// keepFile copies an entry to the archive being written raw as it was, without
+// encrypting it.
+func (z *Zippy) keepFile(file *zip.File) error {
+	if z.plan != nil {
+		z.plan.add(PlanKeep, file.Name, "", false)
+		return nil
 	}
 
-	// Copy files that should not be removed
-	for _, file := range files {
-		// Skip if file is marked for removal
-		if filesToRemove[file.Name] {
-			continue
-		}
-
-		// Skip if file is a directory that is marked as empty
-		if file.FileInfo().IsDir() {
-			dirName := strings.TrimSuffix(file.Name, "/")
-			if emptyDirs[dirName] {
-				continue
-			}
-		}
+	return copyRaw(z.zWriter, file)
+}
 
-		if err := z.zWriter.Copy(file); err != nil {
-			return err
-		}
+// Copies all files to another zip archive.
+//
+// files are the files to copy.
+func (z *Zippy) copyZipFilesAll(files []*zip.File) error {
+	filesToCopy := make(map[string]bool, len(files))
+	for _, file := range files {
+		filesToCopy[file.Name] = true
 	}
 
-	return nil
+	return z.writeKept(files, filesToCopy, false, z.copyFile)
 }
 
 // Keeps only the files that match the given patterns and copies them to another zip archive.
@@ -275,25 +401,20 @@ func (z *Zippy) copyZipFilesRemove(files []*zip.File, patterns []string) error {
 func (z *Zippy) copyZipFilesKeep(files []*zip.File, patterns []string) error {
 	// Map to track directories that need to be included
 	dirsToInclude := make(map[string]bool)
-	filesToCopy := make(map[string]*zip.File)
+	filesToCopy := make(map[string]bool)
 
 	// First pass: identify files to keep and their parent directories
 	for _, file := range files {
-		shouldKeep := false
-		for _, fileToKeep := range patterns {
-			match, err := filepath.Match(fileToKeep, file.Name)
-			if err != nil {
-				return err
-			}
-
-			if match {
-				shouldKeep = true
-				break
-			}
+		shouldKeep, err := nameMatches(file.Name, z.IgnoreCase, patterns...)
+		if err != nil {
+			return err
 		}
 
 		if shouldKeep {
-			filesToCopy[file.Name] = file
+			// Directories are only included through the files they hold
+			if !file.FileInfo().IsDir() {
+				filesToCopy[file.Name] = true
+			}
 
 			// Mark all parent directories for inclusion using ZIP paths
 			// Use strings.Split/Join instead of filepath.Dir to maintain forward slashes
@@ -307,29 +428,19 @@ func (z *Zippy) copyZipFilesKeep(files []*zip.File, patterns []string) error {
 		}
 	}
 
-	// Second pass: copy all directories first
+	// Second pass: include the directories of the files to keep
 	for _, file := range files {
 		if file.FileInfo().IsDir() {
 			// If this is a directory that needs to be included
 			dirName := strings.TrimSuffix(file.Name, "/")
 			if dirsToInclude[dirName] {
-				if err := z.zWriter.Copy(file); err != nil {
-					return err
-				}
-			}
-		}
-	}
-
-	// Third pass: copy all files
-	for _, file := range filesToCopy {
-		if !file.FileInfo().IsDir() {
-			if err := z.zWriter.Copy(file); err != nil {
-				return err
+				filesToCopy[file.Name] = true
 			}
 		}
 	}
 
-	return nil
+	// Third pass: copy all files, in the order of the archive
+	return z.writeKept(files, filesToCopy, false, z.copyFile)
 }
 
 // Adds a file or directory to a zip archive.
@@ -338,11 +449,16 @@ func (z *Zippy) copyZipFilesKeep(files []*zip.File, patterns []string) error {
 func (z *Zippy) zipFile(path string) error {
 	path = filepath.Clean(path)
 
-	info, err := os.Stat(path)
+	info, err := z.stat(path)
 	if err != nil {
 		return err
 	}
 
+	isLink := info.Mode()&os.ModeSymlink != 0
+	if isLink && z.Symlinks == SymlinkSkip {
+		return nil
+	}
+
 	header, err := zip.FileInfoHeader(info)
 	if err != nil {
 		return err
@@ -360,17 +476,50 @@ func (z *Zippy) zipFile(path string) error {
 		header.Method = zip.Deflate
 	}
 
-	// search z.existingFiles for matching header.Name
-	// if found, skip
-	if _, ok := z.existingFiles[header.Name]; ok {
+	// Junked names are excluded by their path, so excluded directories still
+	// leave out their contents
+	if skip, err := excluded(toZipPath(path), z.Exclude, z.IgnoreCase); skip || err != nil {
+		return err
+	}
+
+	if z.sync != nil {
+		if done, err := z.syncEntry(path, header.Name, info); done || err != nil {
+			return err
+		}
+	} else if _, ok := z.existingFiles[header.Name]; ok {
+		// search z.existingFiles for matching header.Name
+		// if found, skip
+		return nil
+	}
+
+	if z.plan != nil {
+		action := PlanAdd
+		if _, ok := z.existingFiles[header.Name]; ok {
+			action = PlanReplace
+		}
+
+		z.plan.add(action, header.Name, path, false)
 		return nil
 	}
 
-	writer, err := z.zWriter.CreateHeader(header)
+	attrs, err := fileAttrs(path, z.Symlinks == SymlinkFollow)
+	if err != nil {
+		return err
+	}
+
+	setUnixExtra(header, attrs)
+
+	if isLink {
+		return z.zipSymlink(path, header)
+	}
+
+	writer, err := z.createEntry(header)
 	if err != nil {
 		return err
 	}
 
+	setCentralExtra(header)
+
 	// Open is done before checking if file is a directory to check permissions on the file
 	file, err := os.Open(path)
 	if err != nil {
@@ -387,7 +536,11 @@ func (z *Zippy) zipFile(path string) error {
 		return err
 	}
 
-	err = validateCopy(path, written, int64(header.UncompressedSize64))
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	err = validateCopy(z.Path, header.Name, path, written, int64(header.UncompressedSize64))
 
 	return err
 }
@@ -396,40 +549,14 @@ func (z *Zippy) zipFile(path string) error {
 //
 // files are the files or directories to add. Glob patterns are supported.
 func (z *Zippy) zipFiles(files ...string) error {
-	for _, file := range files {
-		fileMatches, err := filepath.Glob(file)
-		if err != nil {
-			return fmt.Errorf("failed to glob pattern '%s': %v", file, err)
-		}
-
-		// If no matches found, treat file as a literal path
-		if len(fileMatches) == 0 {
-			fileMatches = append(fileMatches, file)
-		}
-
-		for _, fileMatch := range fileMatches {
-			fileMatch = filepath.Clean(fileMatch)
-
-			fInfo, err := os.Stat(fileMatch)
-			if err != nil {
-				return err
-			}
-
-			if fInfo.IsDir() {
-				err = filepath.WalkDir(fileMatch, func(path string, entry os.DirEntry, walkErr error) error {
-					if walkErr != nil {
-						return walkErr
-					}
-
-					return z.zipFile(path)
-				})
-			} else {
-				err = z.zipFile(fileMatch)
-			}
+	paths, err := expandGlobs(files...)
+	if err != nil {
+		return err
+	}
 
-			if err != nil {
-				return err
-			}
+	for _, path := range paths {
+		if err := z.walk(path, nil); err != nil {
+			return err
 		}
 	}
 
@@ -440,6 +567,12 @@ func (z *Zippy) zipFiles(files ...string) error {
 //
 // files are the files or directories to archive. Glob patterns are supported.
 func (z *Zippy) Add(files ...string) (err error) {
+	if z.DryRun {
+		return z.dryRun(z.Path, func() error {
+			return z.planAdd(files...)
+		})
+	}
+
 	if err := os.MkdirAll(filepath.Dir(z.Path), os.ModePerm); err != nil {
 		return err
 	}
@@ -474,6 +607,10 @@ func (z *Zippy) Add(files ...string) (err error) {
 
 	// Copy existing files to the new zip archive if zip file exists
 	if err == nil && z.zReadCloser != nil {
+		if err := z.zWriter.SetComment(z.zReadCloser.Comment); err != nil {
+			return err
+		}
+
 		z.existingFiles = make(map[string]*zip.File)
 
 		for _, f := range z.zReadCloser.File {
@@ -481,7 +618,7 @@ func (z *Zippy) Add(files ...string) (err error) {
 		}
 
 		for _, f := range z.zReadCloser.File {
-			if err := z.zWriter.Copy(f); err != nil {
+			if err := copyRaw(z.zWriter, f); err != nil {
 				return err
 			}
 		}
@@ -491,27 +628,38 @@ func (z *Zippy) Add(files ...string) (err error) {
 		return err
 	}
 
-	return err
+	return z.closeWriter(zipFile)
 }
 
-// Deletes files or directories from an existing zip archive.
+// Deletes files or directories from an existing zip archive. The archive is
+// not rewritten when no file matches.
 //
 // files are the files or directories to delete. Glob patterns are supported.
 func (z *Zippy) Delete(files ...string) (err error) {
+	if z.DryRun {
+		return z.dryRun(z.Path, func() error {
+			_, err := z.createTempZipWithoutFiles(files...)
+			return err
+		})
+	}
+
 	tempZipPath, err := z.createTempZipWithoutFiles(files...)
 	if err != nil {
 		// If the temp zip file was made, but we had an error happen after the fact
 		// lets clean it up if it exists
-		_, err = os.Stat(tempZipPath)
-		if err == nil {
-			if err := os.Remove(tempZipPath); err != nil {
-				return err
+		if tempZipPath != "" {
+			if _, statErr := os.Stat(tempZipPath); statErr == nil {
+				os.Remove(tempZipPath)
 			}
 		}
 
 		return err
 	}
 
+	if tempZipPath == "" {
+		return nil
+	}
+
 	// Rename the temporary zip file to the original path
 	if err := os.Rename(tempZipPath, z.Path); err != nil {
 		return fmt.Errorf("failed to rename temporary zip file: %w", err)
@@ -520,20 +668,19 @@ func (z *Zippy) Delete(files ...string) (err error) {
 	return err
 }
 
-// Updates files in a zip archive.
-//
-// files are the files or directories to update.  Glob patterns are supported.
-func (z *Zippy) Update(files ...string) (err error) {
-	// TODO: Implementation of Update method
-	return err
-}
-
 // Copies files from existing zip archive to a new zip archive.
 //
 // dest is the new zip archive path.
 //
 // files are the files to copy.  Glob patterns are supported. If no files are provided, all files will be copied.
 func (z *Zippy) Copy(dest string, files ...string) (err error) {
+	if z.DryRun {
+		return z.dryRun(dest, func() error {
+			_, err := z.createTempZipWithFiles(dest, files...)
+			return err
+		})
+	}
+
 	tempZipPath, err := z.createTempZipWithFiles(dest, files...)
 	if err != nil {
 		return err
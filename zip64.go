@@ -0,0 +1,194 @@
+package zippy
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// zipVersion45 is the version needed to extract entries using ZIP64 records.
+const zipVersion45 = 45
+
+// copyRaw copies an entry from an existing archive without decompressing it.
+//
+// The ZIP64 extra field of the entry is dropped, as it holds the sizes and
+// offset of the entry in the archive it is copied from. The writer adds a new
+// one when the entry needs it in the new archive.
+func copyRaw(zWriter *zip.Writer, file *zip.File) error {
+	reader, err := file.OpenRaw()
+	if err != nil {
+		return err
+	}
+
+	header := file.FileHeader
+	header.Extra = stripExtra(header.Extra, zip64ExtraID)
+
+	writer, err := zWriter.CreateRaw(&header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, reader)
+	return err
+}
+
+// forceZip64 rewrites the central directory of the zip archive in file so
+// every entry and the end of central directory use ZIP64 records, even when
+// the archive does not need them. The local headers are left unchanged, as
+// readers take the sizes and offsets from the central directory and rewriting
+// the headers would move every entry.
+func forceZip64(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	eocd, err := readEOCD(file, info.Size())
+	if err != nil {
+		return err
+	}
+
+	headers, err := readCentralDirectory(file, eocd)
+	if err != nil {
+		return err
+	}
+
+	var buf []byte
+	for _, h := range headers {
+		buf = appendCentralHeader(buf, h, uint64(h.Offset-eocd.baseOffset), 0, true)
+	}
+
+	end := &endOfCentralDirectory{
+		diskEntries: uint64(len(headers)),
+		entries:     uint64(len(headers)),
+		cdSize:      uint64(len(buf)),
+		cdOffset:    eocd.cdOffset,
+		comment:     eocd.comment,
+	}
+	buf = appendEndRecords(buf, end, eocd.cdOffset+uint64(len(buf)), true)
+
+	start := eocd.baseOffset + int64(eocd.cdOffset)
+	if err := file.Truncate(start); err != nil {
+		return err
+	}
+
+	_, err = file.WriteAt(buf, start)
+	return err
+}
+
+// appendCentralHeader appends a central directory header for h to buf, with
+// the local header at offset on disk. The sizes and offset are stored in a
+// ZIP64 extra field when they do not fit, or always when zip64 is set.
+func appendCentralHeader(buf []byte, h *rawHeader, offset uint64, disk uint32, zip64 bool) []byte {
+	le := binary.LittleEndian
+
+	var zip64Data []byte
+	saturate := func(value uint64) uint32 {
+		if zip64 || value >= uint32max {
+			zip64Data = le.AppendUint64(zip64Data, value)
+			return 0xffffffff
+		}
+
+		return uint32(value)
+	}
+
+	uncompressedSize := saturate(h.UncompressedSize64)
+	compressedSize := saturate(h.CompressedSize64)
+	headerOffset := saturate(offset)
+
+	diskStart := uint16(disk)
+	if disk >= 0xffff {
+		zip64Data = le.AppendUint32(zip64Data, disk)
+		diskStart = 0xffff
+	}
+
+	extra := stripExtra(h.Extra, zip64ExtraID)
+	readerVersion := h.ReaderVersion
+	if zip64Data != nil {
+		extra = appendExtra(extra, zip64ExtraID, zip64Data)
+		readerVersion = max(readerVersion, zipVersion45)
+	}
+
+	buf = le.AppendUint32(buf, centralHeaderSignature)
+	buf = le.AppendUint16(buf, h.CreatorVersion)
+	buf = le.AppendUint16(buf, readerVersion)
+	buf = le.AppendUint16(buf, h.Flags)
+	buf = le.AppendUint16(buf, h.Method)
+	buf = le.AppendUint16(buf, h.ModifiedTime)
+	buf = le.AppendUint16(buf, h.ModifiedDate)
+	buf = le.AppendUint32(buf, h.CRC32)
+	buf = le.AppendUint32(buf, compressedSize)
+	buf = le.AppendUint32(buf, uncompressedSize)
+	buf = le.AppendUint16(buf, uint16(len(h.Name)))
+	buf = le.AppendUint16(buf, uint16(len(extra)))
+	buf = le.AppendUint16(buf, uint16(len(h.Comment)))
+	buf = le.AppendUint16(buf, diskStart)
+	buf = le.AppendUint16(buf, h.InternalAttrs)
+	buf = le.AppendUint32(buf, h.ExternalAttrs)
+	buf = le.AppendUint32(buf, headerOffset)
+	buf = append(buf, h.Name...)
+	buf = append(buf, extra...)
+
+	return append(buf, h.Comment...)
+}
+
+// appendEndRecords appends the end of central directory record described by
+// eocd to buf, preceded by the ZIP64 end of central directory record and
+// locator when its values do not fit, or always when zip64 is set. offset is
+// where the records start on the disk holding them.
+func appendEndRecords(buf []byte, eocd *endOfCentralDirectory, offset uint64, zip64 bool) []byte {
+	le := binary.LittleEndian
+
+	zip64 = zip64 || eocd.disk >= 0xffff || eocd.cdDisk >= 0xffff || eocd.entries >= 0xffff ||
+		eocd.cdSize >= uint32max || eocd.cdOffset >= uint32max
+
+	if zip64 {
+		// ZIP64 end of central directory record
+		buf = le.AppendUint32(buf, eocd64Signature)
+		buf = le.AppendUint64(buf, eocd64Len-12) // Size of the remaining record
+		buf = le.AppendUint16(buf, zipVersion45) // Version made by
+		buf = le.AppendUint16(buf, zipVersion45) // Version needed to extract
+		buf = le.AppendUint32(buf, eocd.disk)
+		buf = le.AppendUint32(buf, eocd.cdDisk)
+		buf = le.AppendUint64(buf, eocd.diskEntries)
+		buf = le.AppendUint64(buf, eocd.entries)
+		buf = le.AppendUint64(buf, eocd.cdSize)
+		buf = le.AppendUint64(buf, eocd.cdOffset)
+
+		// ZIP64 end of central directory locator
+		buf = le.AppendUint32(buf, eocd64LocatorSignature)
+		buf = le.AppendUint32(buf, eocd.disk)
+		buf = le.AppendUint64(buf, offset)
+		buf = le.AppendUint32(buf, eocd.disk+1) // Total number of disks
+	}
+
+	// The end of central directory record defers every count, size and
+	// offset to the ZIP64 record when there is one
+	saturate16 := func(value uint64) uint16 {
+		if zip64 {
+			return 0xffff
+		}
+
+		return uint16(value)
+	}
+
+	saturate32 := func(value uint64) uint32 {
+		if zip64 {
+			return 0xffffffff
+		}
+
+		return uint32(value)
+	}
+
+	buf = le.AppendUint32(buf, eocdSignature)
+	buf = le.AppendUint16(buf, uint16(min(eocd.disk, 0xffff)))
+	buf = le.AppendUint16(buf, uint16(min(eocd.cdDisk, 0xffff)))
+	buf = le.AppendUint16(buf, saturate16(eocd.diskEntries))
+	buf = le.AppendUint16(buf, saturate16(eocd.entries))
+	buf = le.AppendUint32(buf, saturate32(eocd.cdSize))
+	buf = le.AppendUint32(buf, saturate32(eocd.cdOffset))
+	buf = le.AppendUint16(buf, uint16(len(eocd.comment)))
+
+	return append(buf, eocd.comment...)
+}
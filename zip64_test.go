@@ -0,0 +1,385 @@
+package zippy
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// largeSize is the size of the entries that need ZIP64 records.
+const largeSize = 1<<32 + 1<<20
+
+// zeroBlock is a block of zeros read by zeroReader and skipped by sparseWriter.
+var zeroBlock = make([]byte, 1<<20)
+
+// zeroReader is an endless stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// sparseWriter writes to a file, leaving holes for blocks of zeros so large
+// archives of zeros take little disk space.
+type sparseWriter struct {
+	file *os.File
+}
+
+func (w *sparseWriter) Write(p []byte) (int, error) {
+	for written := 0; written < len(p); {
+		block := p[written:min(len(p), written+len(zeroBlock))]
+		if bytes.Equal(block, zeroBlock[:len(block)]) {
+			if _, err := w.file.Seek(int64(len(block)), io.SeekCurrent); err != nil {
+				return written, err
+			}
+		} else if _, err := w.file.Write(block); err != nil {
+			return written, err
+		}
+
+		written += len(block)
+	}
+
+	return len(p), nil
+}
+
+// createLargeZip writes a sparse zip archive with a stored entry of largeSize
+// zeros, followed by a small entry whose local header is past 4 GiB.
+func createLargeZip(t *testing.T, path string) {
+	t.Helper()
+
+	file, err := os.Create(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer file.Close()
+
+	hash := crc32.NewIEEE()
+	_, err = io.CopyBuffer(hash, io.LimitReader(zeroReader{}, largeSize), zeroBlock)
+	assert.NoError(t, err)
+
+	zWriter := zip.NewWriter(&sparseWriter{file: file})
+
+	writer, err := zWriter.CreateRaw(&zip.FileHeader{
+		Name:               "big.bin",
+		Method:             zip.Store,
+		CRC32:              hash.Sum32(),
+		CompressedSize64:   largeSize,
+		UncompressedSize64: largeSize,
+	})
+	assert.NoError(t, err)
+
+	_, err = io.CopyBuffer(writer, io.LimitReader(zeroReader{}, largeSize), make([]byte, len(zeroBlock)))
+	assert.NoError(t, err)
+
+	writer, err = zWriter.Create("small.txt")
+	assert.NoError(t, err)
+
+	_, err = writer.Write([]byte("hello"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, zWriter.Close())
+}
+
+// centralHeaders returns the central directory of a zip archive, and whether
+// it has ZIP64 end of central directory records.
+func centralHeaders(t *testing.T, path string) ([]*rawHeader, bool) {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if !assert.NoError(t, err) {
+		return nil, false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	assert.NoError(t, err)
+
+	eocd, err := readEOCD(file, info.Size())
+	if !assert.NoError(t, err) {
+		return nil, false
+	}
+
+	headers, err := readCentralDirectory(file, eocd)
+	assert.NoError(t, err)
+
+	// The ZIP64 records may be present even when the end of central
+	// directory record does not defer to them
+	_, zip64 := readEOCD64(file, eocd)
+
+	return headers, zip64
+}
+
+// assertZip64 checks whether every entry of a zip archive, and the archive
+// itself, use ZIP64 records, and that the archive verifies.
+func assertZip64(t *testing.T, path string, zip64 bool) {
+	t.Helper()
+
+	headers, hasEOCD64 := centralHeaders(t, path)
+	assert.Equal(t, zip64, hasEOCD64, "ZIP64 end of central directory")
+
+	for _, h := range headers {
+		_, ok := findExtra(h.Extra, zip64ExtraID)
+		assert.Equal(t, zip64, ok, "ZIP64 extra field of '%s'", h.Name)
+	}
+
+	u, err := NewUnzippy(path, nil)
+	assert.NoError(t, err)
+
+	report, err := u.Verify()
+	if assert.NoError(t, err) {
+		assert.True(t, report.OK(), "%v", report.Failed())
+	}
+}
+
+// Tests for [Zippy.ForceZip64] option.
+func Test_Zippy_ForceZip64(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	assert.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "sub"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(sourceDir, "a.txt"), []byte("alpha"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(sourceDir, "sub", "b.txt"), []byte("bravo"), 0644))
+
+	zipPath := filepath.Join(tempDir, "forced.zip")
+	z := NewZippy(zipPath)
+	z.ForceZip64 = true
+
+	t.Run("add", func(t *testing.T) {
+		assert.NoError(t, z.Add(sourceDir))
+		assertZip64(t, zipPath, true)
+
+		dest := filepath.Join(tempDir, "extracted")
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(dest)
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dest, toZipPath(sourceDir), "sub", "b.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "bravo", string(data))
+	})
+
+	t.Run("verify", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		report, err := u.Verify()
+		assert.NoError(t, err)
+		if assert.NotNil(t, report) {
+			assert.Empty(t, report.Failed())
+		}
+	})
+
+	t.Run("comment", func(t *testing.T) {
+		assert.NoError(t, z.SetComment("forced"))
+		assertZip64(t, zipPath, true)
+
+		comment, err := z.Comment()
+		assert.NoError(t, err)
+		assert.Equal(t, "forced", comment)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		dest := filepath.Join(tempDir, "copy.zip")
+		assert.NoError(t, z.Copy(dest))
+		assertZip64(t, dest, true)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, z.Delete(toZipPath(filepath.Join(sourceDir, "a.txt"))))
+		assertZip64(t, zipPath, true)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		dest := filepath.Join(tempDir, "merged.zip")
+		assert.NoError(t, MergeWithOptions(dest, &MergeOptions{ForceZip64: true}, zipPath))
+		assertZip64(t, dest, true)
+	})
+
+	t.Run("dropped when not forced", func(t *testing.T) {
+		plain := NewZippy(zipPath)
+		assert.NoError(t, plain.Add(filepath.Join(sourceDir, "a.txt")))
+		assertZip64(t, zipPath, false)
+
+		zReader, err := zip.OpenReader(zipPath)
+		if assert.NoError(t, err) {
+			defer zReader.Close()
+			assert.Equal(t, "forced", zReader.Comment)
+		}
+	})
+}
+
+// Tests for ZIP64 archives with more than 65,535 entries.
+func Test_Zippy_zip64_entries(t *testing.T) {
+	const entries = 70000
+
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "many.zip")
+
+	file, err := os.Create(zipPath)
+	assert.NoError(t, err)
+
+	zWriter := zip.NewWriter(file)
+	for i := range entries {
+		_, err := zWriter.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("f%05d.txt", i), Method: zip.Store})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zWriter.Close())
+	assert.NoError(t, file.Close())
+
+	countEntries := func(path string) int {
+		zReader, err := zip.OpenReader(path)
+		if !assert.NoError(t, err) {
+			return 0
+		}
+		defer zReader.Close()
+
+		return len(zReader.File)
+	}
+
+	extraPath := filepath.Join(tempDir, "extra.txt")
+	assert.NoError(t, os.WriteFile(extraPath, []byte("extra"), 0644))
+
+	z := NewZippy(zipPath)
+	z.Junk = true
+
+	t.Run("add", func(t *testing.T) {
+		assert.NoError(t, z.Add(extraPath))
+		assert.Equal(t, entries+1, countEntries(zipPath))
+
+		_, zip64 := centralHeaders(t, zipPath)
+		assert.True(t, zip64)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, z.Delete("f0000?.txt"))
+		assert.Equal(t, entries-9, countEntries(zipPath))
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		dest := filepath.Join(tempDir, "copy.zip")
+		assert.NoError(t, z.Copy(dest, "f0001?.txt"))
+		assert.Equal(t, 10, countEntries(dest))
+
+		_, zip64 := centralHeaders(t, dest)
+		assert.False(t, zip64)
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		dest := filepath.Join(tempDir, "extracted")
+		extracted, err := u.ExtractFilesTo(dest, "f6999?.txt", "extra.txt")
+		assert.NoError(t, err)
+		assert.Len(t, extracted, 11)
+		assert.FileExists(t, filepath.Join(dest, "f69999.txt"))
+	})
+}
+
+// Tests for ZIP64 entries larger than 4 GiB and at offsets past 4 GiB.
+func Test_Zippy_zip64_large(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large archive test in short mode")
+	}
+
+	tempDir := t.TempDir()
+
+	t.Run("sparse file", func(t *testing.T) {
+		sparsePath := filepath.Join(tempDir, "sparse.bin")
+		file, err := os.Create(sparsePath)
+		assert.NoError(t, err)
+		assert.NoError(t, file.Truncate(largeSize))
+		assert.NoError(t, file.Close())
+
+		zipPath := filepath.Join(tempDir, "sparse.zip")
+		z := NewZippy(zipPath)
+		z.Junk = true
+		assert.NoError(t, z.Add(sparsePath))
+		assertZip64(t, zipPath, true)
+
+		// Entries copied raw keep their ZIP64 sizes
+		smallPath := filepath.Join(tempDir, "small.txt")
+		assert.NoError(t, os.WriteFile(smallPath, []byte("hello"), 0644))
+		assert.NoError(t, z.Add(smallPath))
+		assert.NoError(t, z.Delete("small.txt"))
+
+		zReader, err := zip.OpenReader(zipPath)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer zReader.Close()
+
+		if assert.Len(t, zReader.File, 1) {
+			entry := zReader.File[0]
+			assert.Equal(t, uint64(largeSize), entry.UncompressedSize64)
+
+			reader, err := entry.Open()
+			assert.NoError(t, err)
+			defer reader.Close()
+
+			written, err := copyWithChecksum(io.Discard, reader, zipPath, entry)
+			assert.NoError(t, err)
+			assert.NoError(t, validateCopy(zipPath, entry.Name, sparsePath, written, int64(entry.UncompressedSize64)))
+		}
+	})
+
+	t.Run("entry past 4 GiB", func(t *testing.T) {
+		zipPath := filepath.Join(tempDir, "large.zip")
+		createLargeZip(t, zipPath)
+
+		headers, _ := centralHeaders(t, zipPath)
+		if assert.Len(t, headers, 2) {
+			assert.Greater(t, headers[1].Offset, int64(uint32max))
+		}
+
+		u, err := NewUnzippy(zipPath, nil)
+		assert.NoError(t, err)
+
+		dest := filepath.Join(tempDir, "extracted")
+		_, err = u.ExtractFilesTo(dest, "small.txt")
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(dest, "small.txt"))
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+
+		report, err := u.Verify()
+		if assert.NoError(t, err) {
+			assert.True(t, report.OK(), "%v", report.Failed())
+		}
+
+		// Copied entries move before 4 GiB and must not keep the offset of
+		// their source
+		copyPath := filepath.Join(tempDir, "copy.zip")
+		z := NewZippy(zipPath)
+		assert.NoError(t, z.Copy(copyPath, "small.txt"))
+		assertZip64(t, copyPath, false)
+
+		assert.NoError(t, z.Delete("big.bin"))
+		assertZip64(t, zipPath, false)
+
+		zReader, err := zip.OpenReader(zipPath)
+		if assert.NoError(t, err) {
+			defer zReader.Close()
+
+			if assert.Len(t, zReader.File, 1) {
+				reader, err := zReader.File[0].Open()
+				assert.NoError(t, err)
+				defer reader.Close()
+
+				data, err := io.ReadAll(reader)
+				assert.NoError(t, err)
+				assert.Equal(t, "hello", string(data))
+			}
+		}
+	})
+}
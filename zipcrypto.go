@@ -0,0 +1,92 @@
+package zippy
+
+import (
+	"archive/zip"
+	"hash/crc32"
+	"io"
+)
+
+// zipCryptoHeaderLen is the length of the encryption header that precedes the
+// data of a ZipCrypto entry.
+const zipCryptoHeaderLen = 12
+
+// zipCryptoKeys holds the state of the traditional PKWARE stream cipher.
+type zipCryptoKeys [3]uint32
+
+func newZipCryptoKeys(password string) *zipCryptoKeys {
+	keys := &zipCryptoKeys{0x12345678, 0x23456789, 0x34567890}
+	for i := 0; i < len(password); i++ {
+		keys.update(password[i])
+	}
+
+	return keys
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+func (k *zipCryptoKeys) update(b byte) {
+	k[0] = crc32Update(k[0], b)
+	k[1] = (k[1]+k[0]&0xff)*134775813 + 1
+	k[2] = crc32Update(k[2], byte(k[1]>>24))
+}
+
+func (k *zipCryptoKeys) streamByte() byte {
+	t := k[2] | 2
+	return byte((t * (t ^ 1)) >> 8)
+}
+
+func (k *zipCryptoKeys) decrypt(buf []byte) {
+	for i, b := range buf {
+		buf[i] = b ^ k.streamByte()
+		k.update(buf[i])
+	}
+}
+
+// zipCryptoReader decrypts the data of a ZipCrypto entry.
+type zipCryptoReader struct {
+	r    io.Reader
+	keys *zipCryptoKeys
+}
+
+func (r *zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.keys.decrypt(p[:n])
+	return n, err
+}
+
+// openZipCrypto opens a ZipCrypto entry of the archive at path archive for
+// reading.
+func openZipCrypto(archive string, file *zip.File, password string) (io.ReadCloser, error) {
+	if file.CompressedSize64 < zipCryptoHeaderLen {
+		return nil, &EntryError{Op: "open", Archive: archive, Entry: file.Name, Err: zip.ErrFormat}
+	}
+
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := newZipCryptoKeys(password)
+
+	header := make([]byte, zipCryptoHeaderLen)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, &EntryError{Op: "open", Archive: archive, Entry: file.Name, Err: err}
+	}
+	keys.decrypt(header)
+
+	// The last header byte is the high byte of the CRC, or of the modification
+	// time when the sizes and CRC are stored in a data descriptor.
+	check := byte(file.CRC32 >> 24)
+	if file.Flags&0x8 != 0 {
+		check = byte(file.ModifiedTime >> 8)
+	}
+
+	if header[zipCryptoHeaderLen-1] != check {
+		return nil, &PasswordError{EntryError{Op: "decrypt", Archive: archive, Entry: file.Name, Err: ErrBadPassword}}
+	}
+
+	data := io.LimitReader(raw, int64(file.CompressedSize64)-zipCryptoHeaderLen)
+	return decompress(&zipCryptoReader{r: data, keys: keys}, file.Method, archive, file)
+}
@@ -0,0 +1,144 @@
+package zippy
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// encrypt encrypts buf in place, the inverse of decrypt. Only tests write
+// ZipCrypto entries.
+func (k *zipCryptoKeys) encrypt(buf []byte) {
+	for i, b := range buf {
+		buf[i] = b ^ k.streamByte()
+		k.update(b)
+	}
+}
+
+// createZipCryptoTestFile creates a zip archive with a single ZipCrypto
+// encrypted entry.
+func createZipCryptoTestFile(t *testing.T, zipFilePath, name string, content []byte, method uint16, password string) {
+	t.Helper()
+
+	data := content
+	if method == zip.Deflate {
+		var buf bytes.Buffer
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		assert.NoError(t, err)
+		_, err = fw.Write(content)
+		assert.NoError(t, err)
+		assert.NoError(t, fw.Close())
+		data = buf.Bytes()
+	}
+
+	crc := crc32.ChecksumIEEE(content)
+
+	header := make([]byte, zipCryptoHeaderLen)
+	_, err := rand.Read(header)
+	assert.NoError(t, err)
+	header[zipCryptoHeaderLen-1] = byte(crc >> 24)
+
+	encrypted := append(header, data...)
+	newZipCryptoKeys(password).encrypt(encrypted)
+
+	createRawZip(t, zipFilePath, &zip.FileHeader{
+		Name:               name,
+		Method:             method,
+		Flags:              0x1,
+		CRC32:              crc,
+		UncompressedSize64: uint64(len(content)),
+	}, string(encrypted))
+}
+
+// Tests for [zipCryptoKeys] encrypt and decrypt.
+func Test_zipCryptoKeys(t *testing.T) {
+	plain := []byte("the quick brown fox")
+
+	buf := bytes.Clone(plain)
+	newZipCryptoKeys("secret").encrypt(buf)
+	assert.NotEqual(t, plain, buf)
+
+	newZipCryptoKeys("secret").decrypt(buf)
+	assert.Equal(t, plain, buf)
+}
+
+// Tests for [Unzippy.ExtractTo] with ZipCrypto entries.
+func Test_Unzippy_ExtractTo_ZipCrypto(t *testing.T) {
+	content := bytes.Repeat([]byte("zippy zipcrypto "), 10)
+
+	for _, method := range []uint16{zip.Store, zip.Deflate} {
+		t.Run("valid password", func(t *testing.T) {
+			tempDir := t.TempDir()
+			zipFilePath := filepath.Join(tempDir, testZipFileName)
+			createZipCryptoTestFile(t, zipFilePath, "secret.txt", content, method, "secret")
+
+			u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Password: "secret"})
+			assert.NoError(t, err)
+
+			files, err := u.ExtractTo(filepath.Join(tempDir, "output"))
+			assert.NoError(t, err)
+			assert.Len(t, files, 1)
+			assert.Equal(t, ZipCrypto, EntryEncryption(files[0]))
+
+			extracted, err := os.ReadFile(filepath.Join(tempDir, "output", "secret.txt"))
+			assert.NoError(t, err)
+			assert.Equal(t, content, extracted)
+		})
+
+		t.Run("bad password", func(t *testing.T) {
+			tempDir := t.TempDir()
+			zipFilePath := filepath.Join(tempDir, testZipFileName)
+			createZipCryptoTestFile(t, zipFilePath, "secret.txt", content, method, "secret")
+
+			// Find a wrong password that does not pass the one byte header check
+			password := "wrong"
+			for i := 0; ; i++ {
+				header := make([]byte, zipCryptoHeaderLen)
+				zipReader, err := zip.OpenReader(zipFilePath)
+				assert.NoError(t, err)
+				raw, err := zipReader.File[0].OpenRaw()
+				assert.NoError(t, err)
+				_, err = raw.Read(header)
+				assert.NoError(t, err)
+				zipReader.Close()
+
+				newZipCryptoKeys(password).decrypt(header)
+				if header[zipCryptoHeaderLen-1] != byte(crc32.ChecksumIEEE(content)>>24) {
+					break
+				}
+				password = "wrong" + string(rune('a'+i))
+			}
+
+			u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Password: password})
+			assert.NoError(t, err)
+
+			_, err = u.ExtractTo(filepath.Join(tempDir, "output"))
+			assert.ErrorIs(t, err, ErrBadPassword)
+		})
+	}
+
+	t.Run("truncated header", func(t *testing.T) {
+		tempDir := t.TempDir()
+		zipFilePath := filepath.Join(tempDir, testZipFileName)
+		createRawZip(t, zipFilePath, &zip.FileHeader{Name: "secret.txt", Method: zip.Store, Flags: 0x1}, "short")
+
+		u, err := NewUnzippy(zipFilePath, &UnzippyOptions{Password: "secret"})
+		assert.NoError(t, err)
+
+		_, err = u.ExtractTo(filepath.Join(tempDir, "output"))
+		assert.ErrorIs(t, err, zip.ErrFormat)
+
+		var entryErr *EntryError
+		if assert.ErrorAs(t, err, &entryErr) {
+			assert.Equal(t, zipFilePath, entryErr.Archive)
+			assert.Equal(t, "secret.txt", entryErr.Entry)
+		}
+	})
+}